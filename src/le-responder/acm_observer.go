@@ -2,11 +2,13 @@ package main
 
 import (
 	"bytes"
+	"crypto/ed25519"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/pem"
 	"errors"
 	log "github.com/sirupsen/logrus"
+	"strings"
 	"sync"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -153,10 +155,28 @@ func (a *acmObs) Import(cert *credhubCert) error {
 		return nil // ignore, we don't want to store
 	}
 
+	if strings.TrimSpace(cert.Certificate) == "" {
+		return nil // not issued yet
+	}
+	if cert.PrivateKey == "" {
+		log.Printf("Skipping ACM import for: %s as it was issued for a CSR, so we don't have the key", hostFromPath(cert.path))
+		return nil
+	}
+
+	// ACM only accepts RSA and ECDSA keys
+	pkey, err := parsePrivateKey(cert.PrivateKey)
+	if err != nil {
+		return err
+	}
+	if _, ok := pkey.(ed25519.PrivateKey); ok {
+		log.Printf("Skipping ACM import for: %s as ACM does not support ed25519 keys", hostFromPath(cert.path))
+		return nil
+	}
+
 	a.awsMutex.Lock()
 	defer a.awsMutex.Unlock()
 
-	err := a.initAWSSessionAndCaches()
+	err = a.initAWSSessionAndCaches()
 	if err != nil {
 		return err
 	}
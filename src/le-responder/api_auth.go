@@ -0,0 +1,276 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/govau/cf-common/uaa"
+)
+
+const (
+	// apiTokensPath is where locally issued API tokens are kept in storage
+	apiTokensPath = "/api-tokens"
+
+	// apiTokenPrefix distinguishes our tokens from UAA JWTs
+	apiTokenPrefix = "lrt."
+)
+
+// apiAuthConf configures which non-human callers may use the API
+type apiAuthConf struct {
+	// UAA clients that may call the API with client_credentials tokens
+	AllowedClients []string `yaml:"allowed_clients"`
+
+	// If set, client tokens must have at least one of these scopes
+	AllowedScopes []string `yaml:"allowed_scopes"`
+
+	// If set, tokens can be issued from the admin UI
+	LocalTokens bool `yaml:"local_tokens"`
+}
+
+// apiToken is a locally issued token. Only a hash of the secret is stored.
+type apiToken struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Hash      string    `json:"hash"`
+	Created   time.Time `json:"created"`
+	CreatedBy string    `json:"created_by"`
+	Role      string    `json:"role"` // if empty, roles are looked up for "token:<name>"
+}
+
+type apiTokenList struct {
+	Tokens []*apiToken `json:"tokens"`
+}
+
+// apiTokenStore manages locally issued API tokens
+type apiTokenStore struct {
+	storage certStorage
+	lock    sync.Mutex
+}
+
+func hashAPITokenSecret(secret string) string {
+	h := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(h[:])
+}
+
+func (ts *apiTokenStore) load() (*apiTokenList, error) {
+	var rv apiTokenList
+	err := ts.storage.LoadData(apiTokensPath, &rv)
+	if err != nil && err != errCertNotFound {
+		return nil, err
+	}
+	return &rv, nil
+}
+
+// List returns all tokens, newest first
+func (ts *apiTokenStore) List() ([]*apiToken, error) {
+	tl, err := ts.load()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(tl.Tokens, func(i, j int) bool {
+		return tl.Tokens[i].Created.After(tl.Tokens[j].Created)
+	})
+	return tl.Tokens, nil
+}
+
+// Create issues a new token, and returns it. The token can't be retrieved again.
+func (ts *apiTokenStore) Create(name, createdBy string, rl role) (string, error) {
+	if name == "" {
+		return "", errors.New("empty token name")
+	}
+	if rl == roleNone {
+		return "", errors.New("token must have a role")
+	}
+
+	id := make([]byte, 8)
+	secret := make([]byte, 32)
+	for _, b := range [][]byte{id, secret} {
+		_, err := rand.Read(b)
+		if err != nil {
+			return "", err
+		}
+	}
+	idHex, secretHex := hex.EncodeToString(id), hex.EncodeToString(secret)
+
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+
+	tl, err := ts.load()
+	if err != nil {
+		return "", err
+	}
+	tl.Tokens = append(tl.Tokens, &apiToken{
+		ID:        idHex,
+		Name:      name,
+		Hash:      hashAPITokenSecret(secretHex),
+		Created:   time.Now().UTC(),
+		CreatedBy: createdBy,
+		Role:      rl.String(),
+	})
+	err = ts.storage.SaveData(apiTokensPath, tl)
+	if err != nil {
+		return "", err
+	}
+
+	return apiTokenPrefix + idHex + "." + secretHex, nil
+}
+
+// Revoke removes a token, after which it can no longer be used
+func (ts *apiTokenStore) Revoke(id string) error {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+
+	tl, err := ts.load()
+	if err != nil {
+		return err
+	}
+	for i, t := range tl.Tokens {
+		if t.ID == id {
+			tl.Tokens = append(tl.Tokens[:i], tl.Tokens[i+1:]...)
+			return ts.storage.SaveData(apiTokensPath, tl)
+		}
+	}
+	return errors.New("token not found")
+}
+
+// Validate returns the token if it is valid
+func (ts *apiTokenStore) Validate(token string) (*apiToken, error) {
+	bits := strings.Split(strings.TrimPrefix(token, apiTokenPrefix), ".")
+	if len(bits) != 2 {
+		return nil, errors.New("malformed token")
+	}
+
+	tl, err := ts.load()
+	if err != nil {
+		return nil, err
+	}
+	hash := hashAPITokenSecret(bits[1])
+	for _, t := range tl.Tokens {
+		if t.ID == bits[0] && subtle.ConstantTimeCompare([]byte(t.Hash), []byte(hash)) == 1 {
+			return t, nil
+		}
+	}
+	return nil, errors.New("unknown token")
+}
+
+// errAPIForbidden is returned by authenticateBearer when a valid token is not allowed to use the API
+var errAPIForbidden = errors.New("not allowed to use this API")
+
+// authenticateBearer checks the bearer token for an API request, returning the caller and their role.
+// Callers other than users are identified by a prefix on the returned EmailAddress, e.g. "client:concourse" or "token:pipeline".
+func (as *adminServer) authenticateBearer(uaaClient *uaa.Client, r *http.Request) (*uaa.LoggedInUser, role, error) {
+	liu, t, err := as.authenticateBearerToken(uaaClient, r)
+	if err != nil {
+		return nil, roleNone, err
+	}
+
+	if t != nil && t.Role != "" {
+		rl, err := parseRole(t.Role)
+		if err != nil {
+			return nil, roleNone, err
+		}
+		return liu, rl, nil
+	}
+
+	rl := as.roles().roleFor(liu.EmailAddress, tokenScopes(liu.AccessToken))
+	if rl == roleNone {
+		return nil, roleNone, errAPIForbidden
+	}
+	return liu, rl, nil
+}
+
+// authenticateBearerToken validates the token, also returning it if it is one of ours
+func (as *adminServer) authenticateBearerToken(uaaClient *uaa.Client, r *http.Request) (*uaa.LoggedInUser, *apiToken, error) {
+	bits := strings.Split(r.Header.Get("Authorization"), " ")
+	if len(bits) != 2 || strings.ToLower(bits[0]) != "bearer" {
+		return nil, nil, errors.New("bearer token required")
+	}
+	at := bits[1]
+
+	// Locally issued token
+	if strings.HasPrefix(at, apiTokenPrefix) {
+		if !as.API.LocalTokens {
+			return nil, nil, errors.New("local tokens not enabled")
+		}
+		t, err := as.apiTokens.Validate(at)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &uaa.LoggedInUser{
+			EmailAddress: "token:" + t.Name,
+			AccessToken:  at,
+		}, t, nil
+	}
+
+	// Otherwise a UAA token, and we need to know which client it's for before we can validate it
+	var unverified jwt.MapClaims
+	_, _, err := (&jwt.Parser{}).ParseUnverified(at, &unverified)
+	if err != nil {
+		return nil, nil, err
+	}
+	cid, _ := unverified["client_id"].(string)
+
+	// User tokens, for a client we recognise
+	if cid == as.UAA.ClientID+"-api" {
+		claims, err := uaaClient.ValidateAccessToken(at, cid)
+		if err != nil {
+			return nil, nil, err
+		}
+		email, _ := claims["email"].(string)
+		if email == "" {
+			return nil, nil, errors.New("email empty")
+		}
+		if allowed := as.allowedUsers(); !stringInList(email, allowed) && len(allowed) != 0 {
+			return nil, nil, errAPIForbidden
+		}
+		return &uaa.LoggedInUser{
+			EmailAddress: email,
+			AccessToken:  at,
+		}, nil, nil
+	}
+
+	// Client credentials tokens
+	if cid == "" || !stringInList(cid, as.API.AllowedClients) {
+		return nil, nil, errAPIForbidden
+	}
+	claims, err := uaaClient.ValidateAccessToken(at, cid)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(as.API.AllowedScopes) != 0 {
+		found := false
+		scopes, _ := claims["scope"].([]interface{})
+		for _, s := range scopes {
+			ss, _ := s.(string)
+			if stringInList(ss, as.API.AllowedScopes) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, nil, errAPIForbidden
+		}
+	}
+	return &uaa.LoggedInUser{
+		EmailAddress: "client:" + cid,
+		AccessToken:  at,
+	}, nil, nil
+}
+
+func stringInList(s string, l []string) bool {
+	for _, x := range l {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
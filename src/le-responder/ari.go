@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme"
+)
+
+// ACME Renewal Information (ARI, RFC 9773) lets a CA suggest when a cert should be renewed, e.g. earlier than
+// usual if it is about to be revoked. The acme client we use doesn't support it, nor the "replaces" field on new
+// orders that goes with it, so both are implemented here.
+
+// renewalInfoSource is implemented by sources that can ask the CA when a cert it issued should be renewed
+type renewalInfoSource interface {
+	RenewalWindow(ctx context.Context, pc *x509.Certificate) (*renewalWindow, error)
+}
+
+// renewalWindow is the period in which the CA would like a cert to be renewed
+type renewalWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// renewAt picks a time within the window to renew pc, as recommended so that renewals are spread out.
+// It is derived from the serial, so that each scan picks the same time.
+func (rw *renewalWindow) renewAt(pc *x509.Certificate) time.Time {
+	n := new(big.Int).Mod(pc.SerialNumber, big.NewInt(1000)).Int64()
+	if n < 0 {
+		n = -n
+	}
+	return rw.Start.Add(time.Duration(float64(rw.End.Sub(rw.Start)) * float64(n) / 1000))
+}
+
+// ariCertID identifies a cert to the CA, for fetching renewal information and the replaces field of new orders
+func ariCertID(pc *x509.Certificate) (string, error) {
+	if len(pc.AuthorityKeyId) == 0 {
+		return "", errors.New("cert has no authority key identifier")
+	}
+	// The serial is DER encoded, which needs a leading zero if the high bit is set
+	serial := pc.SerialNumber.Bytes()
+	if len(serial) == 0 || serial[0]&0x80 != 0 {
+		serial = append([]byte{0}, serial...)
+	}
+	return base64.RawURLEncoding.EncodeToString(pc.AuthorityKeyId) + "." + base64.RawURLEncoding.EncodeToString(serial), nil
+}
+
+func withReplaces(ctx context.Context, certID string) context.Context {
+	return context.WithValue(ctx, keyReplaces, certID)
+}
+
+func replacesFrom(ctx context.Context) string {
+	rv, _ := ctx.Value(keyReplaces).(string)
+	return rv
+}
+
+// acmeDirectory holds the directory fields we need that the acme client doesn't expose
+type acmeDirectory struct {
+	NewNonce    string `json:"newNonce"`
+	NewOrder    string `json:"newOrder"`
+	RenewalInfo string `json:"renewalInfo"` // empty if the CA doesn't support ARI
+}
+
+func (acs *acmeCertSource) httpClient() *http.Client {
+	if acs.acmeClient.HTTPClient != nil {
+		return acs.acmeClient.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// directory fetches the directory the first time it's needed
+func (acs *acmeCertSource) directory(ctx context.Context) (*acmeDirectory, error) {
+	acs.dirLock.Lock()
+	defer acs.dirLock.Unlock()
+
+	if acs.dir != nil {
+		return acs.dir, nil
+	}
+
+	var rv acmeDirectory
+	err := acs.getJSON(ctx, acs.URL, &rv)
+	if err != nil {
+		return nil, err
+	}
+	acs.dir = &rv
+	return acs.dir, nil
+}
+
+func (acs *acmeCertSource) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	res, err := acs.httpClient().Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return acmeResponseError(res)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// acmeResponseError returns the problem in the response body, in the same form as the acme client does
+func acmeResponseError(res *http.Response) error {
+	var p struct {
+		Type     string `json:"type"`
+		Detail   string `json:"detail"`
+		Instance string `json:"instance"`
+	}
+	b, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1<<20))
+	json.Unmarshal(b, &p)
+	if p.Detail == "" {
+		p.Detail = string(b)
+	}
+	return &acme.Error{
+		StatusCode:  res.StatusCode,
+		ProblemType: p.Type,
+		Detail:      p.Detail,
+		Instance:    p.Instance,
+		Header:      res.Header,
+	}
+}
+
+// RenewalWindow asks the CA when pc should be renewed
+func (acs *acmeCertSource) RenewalWindow(ctx context.Context, pc *x509.Certificate) (*renewalWindow, error) {
+	dir, err := acs.directory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if dir.RenewalInfo == "" {
+		return nil, errors.New("CA does not support renewal information")
+	}
+	certID, err := ariCertID(pc)
+	if err != nil {
+		return nil, err
+	}
+
+	var ri struct {
+		SuggestedWindow renewalWindow `json:"suggestedWindow"`
+	}
+	err = acs.getJSON(ctx, strings.TrimSuffix(dir.RenewalInfo, "/")+"/"+certID, &ri)
+	if err != nil {
+		return nil, err
+	}
+	if ri.SuggestedWindow.Start.IsZero() || ri.SuggestedWindow.End.Before(ri.SuggestedWindow.Start) {
+		return nil, errors.New("invalid renewal window returned by CA")
+	}
+	return &ri.SuggestedWindow, nil
+}
+
+// accountURL returns the URL of our account, which signed requests are made with
+func (acs *acmeCertSource) accountURL(ctx context.Context) (string, error) {
+	acs.registerLock.Lock()
+	defer acs.registerLock.Unlock()
+
+	if acs.acmeAccountURL != "" {
+		return acs.acmeAccountURL, nil
+	}
+	a, err := acs.acmeClient.GetReg(ctx, "")
+	if err != nil {
+		return "", err
+	}
+	acs.acmeAccountURL = a.URI
+	return a.URI, nil
+}
+
+// authorizeOrderReplacing creates an order as the acme client's AuthorizeOrder does, but telling the CA which cert it replaces
+func (acs *acmeCertSource) authorizeOrderReplacing(ctx context.Context, hostnames []string, replaces string) (*acme.Order, error) {
+	dir, err := acs.directory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if dir.RenewalInfo == "" {
+		return nil, errors.New("CA does not support renewal information")
+	}
+	kid, err := acs.accountURL(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	type identifier struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}
+	req := struct {
+		Identifiers []identifier `json:"identifiers"`
+		Replaces    string       `json:"replaces"`
+	}{Replaces: replaces}
+	for _, hn := range hostnames {
+		req.Identifiers = append(req.Identifiers, identifier{Type: "dns", Value: hn})
+	}
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := acs.postJWS(ctx, dir, kid, dir.NewOrder, payload)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusCreated {
+		return nil, acmeResponseError(res)
+	}
+
+	var o struct {
+		Status         string   `json:"status"`
+		Authorizations []string `json:"authorizations"`
+		Finalize       string   `json:"finalize"`
+		Certificate    string   `json:"certificate"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&o)
+	if err != nil {
+		return nil, err
+	}
+	return &acme.Order{
+		URI:         res.Header.Get("Location"),
+		Status:      o.Status,
+		AuthzURLs:   o.Authorizations,
+		FinalizeURL: o.Finalize,
+		CertURL:     o.Certificate,
+	}, nil
+}
+
+// postJWS signs payload with our account key and posts it, retrying once if the CA rejects the nonce
+func (acs *acmeCertSource) postJWS(ctx context.Context, dir *acmeDirectory, kid, url string, payload []byte) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		nonce, err := acs.fetchNonce(ctx, dir)
+		if err != nil {
+			return nil, err
+		}
+		body, err := jwsEncode(acs.acmeClient.Key, kid, nonce, url, payload)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/jose+json")
+		res, err := acs.httpClient().Do(req.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode != http.StatusBadRequest || attempt != 0 {
+			return res, nil
+		}
+		err = acmeResponseError(res)
+		res.Body.Close()
+		if ae, ok := err.(*acme.Error); !ok || ae.ProblemType != "urn:ietf:params:acme:error:badNonce" {
+			return nil, err
+		}
+	}
+}
+
+func (acs *acmeCertSource) fetchNonce(ctx context.Context, dir *acmeDirectory) (string, error) {
+	req, err := http.NewRequest(http.MethodHead, dir.NewNonce, nil)
+	if err != nil {
+		return "", err
+	}
+	res, err := acs.httpClient().Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	res.Body.Close()
+	nonce := res.Header.Get("Replay-Nonce")
+	if nonce == "" {
+		return "", errors.New("no nonce returned by CA")
+	}
+	return nonce, nil
+}
+
+// jwsEncode returns the flattened JWS of payload, as used for ACME requests made with an existing account
+func jwsEncode(key crypto.Signer, kid, nonce, url string, payload []byte) ([]byte, error) {
+	var alg string
+	var hash crypto.Hash
+	var sigSize int // for ECDSA, the size of each of r and s
+	switch pub := key.Public().(type) {
+	case *rsa.PublicKey:
+		alg, hash = "RS256", crypto.SHA256
+	case *ecdsa.PublicKey:
+		switch pub.Curve.Params().BitSize {
+		case 256:
+			alg, hash = "ES256", crypto.SHA256
+		case 384:
+			alg, hash = "ES384", crypto.SHA384
+		case 521:
+			alg, hash = "ES512", crypto.SHA512
+		default:
+			return nil, errors.New("unsupported ECDSA curve for account key")
+		}
+		sigSize = (pub.Curve.Params().BitSize + 7) / 8
+	default:
+		return nil, fmt.Errorf("unsupported account key type %T", pub)
+	}
+
+	protected, err := json.Marshal(map[string]string{
+		"alg":   alg,
+		"kid":   kid,
+		"nonce": nonce,
+		"url":   url,
+	})
+	if err != nil {
+		return nil, err
+	}
+	p64 := base64.RawURLEncoding.EncodeToString(protected)
+	pl64 := base64.RawURLEncoding.EncodeToString(payload)
+
+	h := hash.New()
+	h.Write([]byte(p64 + "." + pl64))
+	sig, err := key.Sign(rand.Reader, h.Sum(nil), hash)
+	if err != nil {
+		return nil, err
+	}
+
+	// JWS wants ECDSA signatures as r and s concatenated, rather than ASN.1
+	if sigSize != 0 {
+		var rs struct{ R, S *big.Int }
+		_, err = asn1.Unmarshal(sig, &rs)
+		if err != nil {
+			return nil, err
+		}
+		sig = make([]byte, 2*sigSize)
+		r, s := rs.R.Bytes(), rs.S.Bytes()
+		copy(sig[sigSize-len(r):sigSize], r)
+		copy(sig[2*sigSize-len(s):], s)
+	}
+
+	return json.Marshal(struct {
+		Protected string `json:"protected"`
+		Payload   string `json:"payload"`
+		Signature string `json:"signature"`
+	}{p64, pl64, base64.RawURLEncoding.EncodeToString(sig)})
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// auditPathPrefix is where audit entries are kept in storage, one data item per entry under a path for each
+	// day, so that entries are never rewritten and instances don't overwrite each other's.
+	// Older versions kept each day in a single item at the day's path, which is still read.
+	auditPathPrefix = "/audit/"
+
+	// auditActorDaemon is the actor recorded for actions taken by the periodic scan
+	auditActorDaemon = "daemon"
+
+	defaultAuditRetentionDays = 90
+)
+
+// auditEntry records a single action taken on a cert
+type auditEntry struct {
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Actor    string    `json:"actor"` // email address, "client:<id>", "token:<name>" or "daemon"
+	Action   string    `json:"action"`
+	Hostname string    `json:"hostname"`
+	Source   string    `json:"source"`
+	Outcome  string    `json:"outcome"` // "success" or "failure"
+	Error    string    `json:"error,omitempty"`
+}
+
+// auditDay is how a day of entries was kept by older versions
+type auditDay struct {
+	Entries []*auditEntry `json:"entries"`
+}
+
+// auditLog keeps a record of actions in storage, so that it survives restarts
+type auditLog struct {
+	RetentionDays int `yaml:"retention_days"`
+
+	storage    certStorage
+	lock       sync.Mutex
+	lastPruned string
+}
+
+func (al *auditLog) Init(storage certStorage) error {
+	if al.RetentionDays == 0 {
+		al.RetentionDays = defaultAuditRetentionDays
+	}
+	al.storage = storage
+	return nil
+}
+
+// auditPath returns the path of the day holding t, under which its entries are kept
+func auditPath(t time.Time) string {
+	return auditPathPrefix + t.UTC().Format("2006-01-02")
+}
+
+// auditEntryPath returns a new path for an entry finished at t, unique across instances
+func auditEntryPath(t time.Time) (string, error) {
+	id := make([]byte, 4)
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", err
+	}
+	return auditPath(t) + "/" + strconv.FormatInt(t.UnixNano(), 10) + "-" + hex.EncodeToString(id), nil
+}
+
+// Start returns a new entry, which should be passed to Finish once the action is done.
+// Source can be filled in later if not known yet.
+func (al *auditLog) Start(actor, action, hostname, source string) *auditEntry {
+	return &auditEntry{
+		Started:  time.Now().UTC(),
+		Actor:    actor,
+		Action:   action,
+		Hostname: hostname,
+		Source:   source,
+	}
+}
+
+// Finish sets the outcome of the entry and saves it. Failure to save is logged, but does not fail the action.
+func (al *auditLog) Finish(ae *auditEntry, err error) {
+	ae.Finished = time.Now().UTC()
+	ae.Outcome = "success"
+	if err != nil {
+		ae.Outcome = "failure"
+		ae.Error = err.Error()
+	}
+
+	log.WithFields(log.Fields{
+		"actor":    ae.Actor,
+		"action":   ae.Action,
+		"hostname": ae.Hostname,
+		"source":   ae.Source,
+		"outcome":  ae.Outcome,
+		"error":    ae.Error,
+	}).Info("audit")
+
+	err = al.save(ae)
+	if err != nil {
+		metricErrors.WithLabelValues("audit").Inc()
+		log.Println("error saving audit entry:", err)
+	}
+}
+
+// loadDay returns the entries for the day at path
+func (al *auditLog) loadDay(path string) ([]*auditEntry, error) {
+	var legacy auditDay
+	err := al.storage.LoadData(path, &legacy)
+	if err != nil && err != errCertNotFound {
+		return nil, err
+	}
+	rv := legacy.Entries
+
+	paths, err := al.storage.ListData(path + "/")
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range paths {
+		var ae auditEntry
+		err = al.storage.LoadData(p, &ae)
+		if err != nil {
+			if err == errCertNotFound {
+				continue // pruned since we listed
+			}
+			return nil, err
+		}
+		rv = append(rv, &ae)
+	}
+	return rv, nil
+}
+
+// deleteDay removes the entries for the day at path
+func (al *auditLog) deleteDay(path string) error {
+	paths, err := al.storage.ListData(path + "/")
+	if err != nil {
+		return err
+	}
+	for _, p := range append(paths, path) {
+		err = al.storage.DeleteData(p)
+		if err != nil && err != errCertNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+func (al *auditLog) save(ae *auditEntry) error {
+	path, err := auditEntryPath(ae.Finished)
+	if err != nil {
+		return err
+	}
+	err = al.storage.SaveData(path, ae)
+	if err != nil {
+		return err
+	}
+
+	// Once a day, remove the days that have fallen out of the retention period.
+	// We look back a week in case we weren't running when they did.
+	al.lock.Lock()
+	day := auditPath(ae.Finished)
+	prune := al.lastPruned != day
+	al.lastPruned = day
+	al.lock.Unlock()
+	if prune {
+		for i := 0; i < 7; i++ {
+			err = al.deleteDay(auditPath(ae.Finished.AddDate(0, 0, -(al.RetentionDays + i))))
+			if err != nil {
+				log.Println("error removing old audit entries, will try again tomorrow:", err)
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// Query returns entries from the last days days, newest first. If hostname or actor are set, only entries matching them are returned.
+func (al *auditLog) Query(days int, hostname, actor string) ([]*auditEntry, error) {
+	if days <= 0 || days > al.RetentionDays {
+		return nil, fmt.Errorf("days must be between 1 and %d", al.RetentionDays)
+	}
+
+	var rv []*auditEntry
+	now := time.Now()
+	for i := 0; i < days; i++ {
+		entries, err := al.loadDay(auditPath(now.AddDate(0, 0, -i)))
+		if err != nil {
+			return nil, err
+		}
+		for _, ae := range entries {
+			if hostname != "" && ae.Hostname != hostname {
+				continue
+			}
+			if actor != "" && ae.Actor != actor {
+				continue
+			}
+			rv = append(rv, ae)
+		}
+	}
+
+	sort.SliceStable(rv, func(i, j int) bool {
+		return rv[i].Started.After(rv[j].Started)
+	})
+
+	return rv, nil
+}
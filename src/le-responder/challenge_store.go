@@ -0,0 +1,217 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// challengeStorePathPrefix is where challenge responses are kept in storage, one data item per key
+	challengeStorePathPrefix = "/challenges/"
+
+	// challengeTTL is how long a response is served for if it isn't cleared, e.g. if the instance
+	// that set it stops mid order. Orders are abandoned well before this.
+	challengeTTL = 1 * time.Hour
+
+	defaultChallengeCacheSeconds = 5
+)
+
+// challengeStore holds the responses served for challenges, e.g. http-01 responses by path
+type challengeStore interface {
+	Set(k string, v []byte, ttl time.Duration) error
+	// Get returns errCertNotFound if there is no response for k, or it has expired
+	Get(k string) ([]byte, error)
+	Delete(k string) error
+	// Sweep deletes responses that have expired, which are left behind if an instance stops mid order
+	Sweep() error
+}
+
+type challengeStoreConf struct {
+	Type string `yaml:"type"` // "memory" (the default) or "storage"
+
+	// CacheSeconds is how long responses read from storage are kept in memory, defaults to defaultChallengeCacheSeconds
+	CacheSeconds int `yaml:"cache_seconds"`
+}
+
+// Init validates the config and returns the store selected
+func (cc *challengeStoreConf) Init(storage certStorage) (challengeStore, error) {
+	switch cc.Type {
+	case "", "memory":
+		return &memoryChallengeStore{
+			entries: make(map[string]*storedChallenge),
+		}, nil
+	case "storage":
+		if cc.CacheSeconds == 0 {
+			cc.CacheSeconds = defaultChallengeCacheSeconds
+		}
+		if cc.CacheSeconds < 0 {
+			return nil, errors.New("challenge store cache_seconds must not be negative")
+		}
+		return &storageChallengeStore{
+			storage:  storage,
+			cacheTTL: time.Duration(cc.CacheSeconds) * time.Second,
+			cache:    make(map[string]*cachedChallenge),
+		}, nil
+	default:
+		return nil, errors.New("unknown challenge store type")
+	}
+}
+
+type storedChallenge struct {
+	Value   []byte    `json:"value"`
+	Expires time.Time `json:"expires"`
+}
+
+// memoryChallengeStore only serves responses set by this instance
+type memoryChallengeStore struct {
+	lock    sync.RWMutex
+	entries map[string]*storedChallenge
+}
+
+func (mcs *memoryChallengeStore) Set(k string, v []byte, ttl time.Duration) error {
+	mcs.lock.Lock()
+	defer mcs.lock.Unlock()
+
+	mcs.entries[k] = &storedChallenge{
+		Value:   v,
+		Expires: time.Now().Add(ttl),
+	}
+	return nil
+}
+
+func (mcs *memoryChallengeStore) Get(k string) ([]byte, error) {
+	mcs.lock.RLock()
+	sc, ok := mcs.entries[k]
+	mcs.lock.RUnlock()
+	if !ok || time.Now().After(sc.Expires) {
+		return nil, errCertNotFound
+	}
+	return sc.Value, nil
+}
+
+func (mcs *memoryChallengeStore) Delete(k string) error {
+	mcs.lock.Lock()
+	delete(mcs.entries, k)
+	mcs.lock.Unlock()
+	return nil
+}
+
+func (mcs *memoryChallengeStore) Sweep() error {
+	now := time.Now()
+	mcs.lock.Lock()
+	for k, sc := range mcs.entries {
+		if now.After(sc.Expires) {
+			delete(mcs.entries, k)
+		}
+	}
+	mcs.lock.Unlock()
+	return nil
+}
+
+// storageChallengeStore keeps responses in storage, so that any instance behind a load balancer can serve them.
+// Responses found are cached briefly, as the CA may validate from several places at once. Misses aren't
+// cached, as the response may be being written by another instance.
+type storageChallengeStore struct {
+	storage  certStorage
+	cacheTTL time.Duration
+
+	lock  sync.Mutex
+	cache map[string]*cachedChallenge
+}
+
+type cachedChallenge struct {
+	value    []byte
+	cachedAt time.Time
+}
+
+// challengeStorePath hashes k, as it is a URL path and may not be valid in storage
+func challengeStorePath(k string) string {
+	h := sha256.Sum256([]byte(k))
+	return challengeStorePathPrefix + hex.EncodeToString(h[:])
+}
+
+func (scs *storageChallengeStore) Set(k string, v []byte, ttl time.Duration) error {
+	return scs.storage.SaveData(challengeStorePath(k), &storedChallenge{
+		Value:   v,
+		Expires: time.Now().UTC().Add(ttl),
+	})
+}
+
+func (scs *storageChallengeStore) Get(k string) ([]byte, error) {
+	now := time.Now()
+
+	scs.lock.Lock()
+	cc, ok := scs.cache[k]
+	if ok && now.Sub(cc.cachedAt) < scs.cacheTTL {
+		scs.lock.Unlock()
+		return cc.value, nil
+	}
+	// Drop anything stale while we're here, so that the cache doesn't grow
+	for ck, cc := range scs.cache {
+		if now.Sub(cc.cachedAt) >= scs.cacheTTL {
+			delete(scs.cache, ck)
+		}
+	}
+	scs.lock.Unlock()
+
+	var sc storedChallenge
+	err := scs.storage.LoadData(challengeStorePath(k), &sc)
+	if err != nil {
+		return nil, err
+	}
+	if now.After(sc.Expires) {
+		return nil, errCertNotFound
+	}
+
+	scs.lock.Lock()
+	scs.cache[k] = &cachedChallenge{
+		value:    sc.Value,
+		cachedAt: now,
+	}
+	scs.lock.Unlock()
+
+	return sc.Value, nil
+}
+
+func (scs *storageChallengeStore) Delete(k string) error {
+	scs.lock.Lock()
+	delete(scs.cache, k)
+	scs.lock.Unlock()
+
+	err := scs.storage.DeleteData(challengeStorePath(k))
+	if err == errCertNotFound {
+		return nil
+	}
+	return err
+}
+
+// Sweep lists everything under challengeStorePathPrefix, as any instance may have set it. If an expired
+// entry is set again between being loaded and deleted here the new one is lost, and that order fails.
+func (scs *storageChallengeStore) Sweep() error {
+	paths, err := scs.storage.ListData(challengeStorePathPrefix)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, p := range paths {
+		var sc storedChallenge
+		err = scs.storage.LoadData(p, &sc)
+		if err != nil {
+			if err == errCertNotFound {
+				continue // cleared since we listed
+			}
+			return err
+		}
+		if !now.After(sc.Expires) {
+			continue
+		}
+		err = scs.storage.DeleteData(p)
+		if err != nil && err != errCertNotFound {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"crypto"
+	"crypto/tls"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/acme"
+)
+
+// newTestFileStore returns directory storage in a temporary directory, removed when the test ends
+func newTestFileStore(t *testing.T) *fileStore {
+	fs := &fileStore{
+		Path:          t.TempDir(),
+		EncryptionKey: "test-encryption-key",
+	}
+	err := fs.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fs
+}
+
+func TestChallengeStoreSweep(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		conf challengeStoreConf
+	}{
+		{name: "memory", conf: challengeStoreConf{Type: "memory"}},
+		{name: "storage", conf: challengeStoreConf{Type: "storage"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			storage := newTestFileStore(t)
+			cs, err := tc.conf.Init(storage)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = cs.Set("/expired", []byte("a"), -time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = cs.Set("/current", []byte("b"), time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = cs.Sweep()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = cs.Get("/expired")
+			if err != errCertNotFound {
+				t.Fatalf("expected expired entry to be gone, got %v", err)
+			}
+			v, err := cs.Get("/current")
+			if err != nil || string(v) != "b" {
+				t.Fatalf("expected current entry to be kept, got %q %v", v, err)
+			}
+
+			paths, err := storage.ListData(challengeStorePathPrefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := 0
+			if tc.conf.Type == "storage" {
+				want = 1
+			}
+			if len(paths) != want {
+				t.Fatalf("expected %d entries left in storage, got %v", want, paths)
+			}
+		})
+	}
+}
+
+// TestChallengeCertShared checks that a tls-alpn-01 cert set by one instance is served by another
+func TestChallengeCertShared(t *testing.T) {
+	storage := newTestFileStore(t)
+	var instances [2]*serverResponder
+	for i := range instances {
+		instances[i] = &serverResponder{
+			TLSALPNPort:    8443,
+			ChallengeStore: challengeStoreConf{Type: "storage"},
+		}
+		err := instances[i].Init("", nil, storage)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	accountKey, err := generateKey(keyTypeECDSAP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := (&acme.Client{Key: accountKey}).TLSALPN01ChallengeCert("token", "www.example.gov.au")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = instances[0].SetChallengeCert("WWW.example.gov.au", cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hello := &tls.ClientHelloInfo{
+		ServerName:      "www.example.gov.au",
+		SupportedProtos: []string{acme.ALPNProto},
+	}
+	served, err := instances[1].getChallengeCert(hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(served.Certificate) != 1 || string(served.Certificate[0]) != string(cert.Certificate[0]) {
+		t.Fatal("expected the same cert to be served")
+	}
+	want, err := marshalPrivateKey(cert.PrivateKey.(crypto.Signer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := marshalPrivateKey(served.PrivateKey.(crypto.Signer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Fatal("expected the same key to be served")
+	}
+
+	// The other instance may serve it from its cache for a few seconds more
+	instances[0].ClearChallengeCert("www.example.gov.au")
+	_, err = instances[0].getChallengeCert(hello)
+	if err == nil {
+		t.Fatal("expected no cert once cleared")
+	}
+}
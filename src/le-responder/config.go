@@ -1,12 +1,19 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/getsentry/raven-go"
 	errors2 "github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"io/ioutil"
 	"net/url"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/govau/cf-common/credhub"
 	yaml "gopkg.in/yaml.v2"
@@ -18,6 +25,20 @@ type sourceMap map[string]struct {
 	PrivateKey string `yaml:"private_key"`
 	URL        string `yaml:"url"`
 	Email      string `yaml:"email"`
+
+	KeyType          string `yaml:"key_type"`           // default key type for certs, defaults to rsa2048
+	SecondaryKeyType string `yaml:"secondary_key_type"` // if set, certs get a second cert with this key type, e.g. for dual RSA/ECDSA
+
+	RenewalPolicy    string  `yaml:"renewal_policy"`    // default renewal policy for certs, defaults to days_before
+	LifetimeFraction float64 `yaml:"lifetime_fraction"` // for the lifetime policy, how much of it must pass before renewing, defaults to 2/3
+
+	DNS        *dnsConf `yaml:"dns"`        // optional, for acme sources to automatically satisfy dns-01 challenges
+	Challenges []string `yaml:"challenges"` // optional, for acme sources the challenge types to use in order of preference
+	CA         *caConf  `yaml:"ca"`         // optional for ca sources, which otherwise generate a CA
+
+	Concurrency int `yaml:"concurrency"` // how many certs the periodic scan renews at once, defaults to defaultSourceConcurrency
+
+	RevokeOnDelete bool `yaml:"revoke_on_delete"` // if set, certs are revoked before they are deleted
 }
 
 type config struct {
@@ -26,7 +47,9 @@ type config struct {
 	Daemon daemonConf `yaml:"daemon"`
 
 	Data struct {
-		CredHub credhub.Client `yaml:"credhub"`
+		CredHub   credhub.Client `yaml:"credhub"`
+		Directory fileStore      `yaml:"directory"` // if path is set, used instead of CredHub
+		Vault     vaultStore     `yaml:"vault"`     // if address is set, used instead of CredHub
 	} `yaml:"data"`
 
 	Servers struct {
@@ -36,10 +59,13 @@ type config struct {
 
 	Output outputObserver `yaml:"output"`
 
+	Audit auditLog `yaml:"audit"`
+
 	SentryDSN string `yaml:"sentry_dsn"`
 }
 
-func newConf(configPath string) (*config, error) {
+// loadConf parses the config file, without initialising anything
+func loadConf(configPath string) (*config, error) {
 	if configPath == "" {
 		return nil, errors.New("must specify a config path")
 	}
@@ -54,6 +80,55 @@ func newConf(configPath string) (*config, error) {
 		return nil, err
 	}
 
+	return &c, nil
+}
+
+// defaultStorageName returns the storage backend we should use, in order of preference
+func (c *config) defaultStorageName() string {
+	switch {
+	case c.Data.Directory.Path != "":
+		return "directory"
+	case c.Data.Vault.Address != "":
+		return "vault"
+	default:
+		return "credhub"
+	}
+}
+
+// storage initialises and returns the named storage backend
+func (c *config) storage(name string) (certStorage, error) {
+	switch name {
+	case "directory":
+		err := c.Data.Directory.Init()
+		if err != nil {
+			return nil, err
+		}
+		return &c.Data.Directory, nil
+	case "vault":
+		err := c.Data.Vault.Init()
+		if err != nil {
+			return nil, err
+		}
+		return &c.Data.Vault, nil
+	case "credhub":
+		err := c.Data.CredHub.Init()
+		if err != nil {
+			return nil, err
+		}
+		return &certStore{
+			CredHub: &c.Data.CredHub,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown storage: %s", name)
+	}
+}
+
+func newConf(configPath string) (*config, error) {
+	c, err := loadConf(configPath)
+	if err != nil {
+		return nil, err
+	}
+
 	// Parse hostname here, as it's used in a number of places
 	u, err := url.Parse(c.Servers.Admin.ExternalURL)
 	if err != nil {
@@ -64,49 +139,132 @@ func newConf(configPath string) (*config, error) {
 		return nil, errors.New("admin external url must be specified")
 	}
 
-	err = c.Data.CredHub.Init()
+	ccs, err := c.storage(c.defaultStorageName())
 	if err != nil {
 		return nil, err
 	}
 
-	ccs := &certStore{
-		CredHub: &c.Data.CredHub,
+	err = c.Audit.Init(ccs)
+	if err != nil {
+		return nil, err
 	}
 
-	err = c.Output.Init(&c.Daemon)
+	err = c.Output.Init(&c.Daemon, &c.Daemon)
 	if err != nil {
 		return nil, err
 	}
 
-	err = c.Daemon.Init(hn, c.Sources, ccs, []certObserver{
+	err = c.Daemon.Init(hn, c.Sources, ccs, &c.Audit, []certObserver{
 		&c.Servers.Admin,
+	}, []certObserver{
 		&c.Output,
 	}, &c.Servers.ACME)
 	if err != nil {
 		return nil, err
 	}
 
-	err = c.Servers.ACME.Init(c.Servers.Admin.ExternalURL)
+	err = c.Servers.ACME.Init(c.Servers.Admin.ExternalURL, &c.Daemon, ccs)
 	if err != nil {
 		return nil, err
 	}
 
-	err = c.Servers.Admin.Init(ccs, &c.Daemon, hn)
+	err = c.Servers.Admin.Init(ccs, &c.Daemon, &c.Audit, hn)
 	if err != nil {
 		return nil, err
 	}
 
-	return &c, nil
+	return c, nil
 }
 
-func (c *config) RunForever() {
+// responderShutdownTimeout is how long the ACME responder is given to finish requests, once everything else has stopped
+const responderShutdownTimeout = 5 * time.Second
+
+// RunForever runs until SIGTERM or SIGINT, when it shuts down gracefully. SIGHUP reloads configPath.
+func (c *config) RunForever(configPath string) {
 	if c.SentryDSN != "" {
 		err := raven.SetDSN(c.SentryDSN)
 		if err != nil {
 			log.Fatalf("%+v", errors2.Wrap(err, "Can't set up sentry"))
 		}
 	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+
 	go c.Servers.Admin.RunForever()
 	go c.Servers.ACME.RunForever()
-	c.Daemon.RunForever()
+	go c.Daemon.RunForever()
+
+	for sig := range signals {
+		if sig == syscall.SIGHUP {
+			log.Println("reloading config...")
+			err := c.reload(configPath)
+			if err != nil {
+				metricErrors.WithLabelValues("reload").Inc()
+				log.Println("error reloading config, keeping the old one:", err)
+				continue
+			}
+			log.Println("config reloaded")
+			continue
+		}
+
+		log.Printf("received %s, shutting down...\n", sig)
+		signal.Stop(signals)
+		c.shutdown()
+		log.Println("shut down")
+		return
+	}
+}
+
+// reload rebuilds the sources, output, and the admin UI's allowed users, roles and teams from configPath. Everything else,
+// such as storage and listeners, is kept and needs a restart to change.
+func (c *config) reload(configPath string) error {
+	nc, err := loadConf(configPath)
+	if err != nil {
+		return err
+	}
+
+	// The daemon's settings, such as days_before, aren't reloaded, so sources are checked against them
+	srcs, err := c.Daemon.buildSources(nc.Sources)
+	if err != nil {
+		return err
+	}
+
+	err = nc.Output.Init(&c.Daemon, &c.Daemon)
+	if err != nil {
+		return err
+	}
+
+	c.Daemon.Reload(srcs, []certObserver{
+		&nc.Output,
+	})
+	c.Servers.Admin.ReloadAccess(nc.Servers.Admin.AllowedUsers, nc.Servers.Admin.Roles, nc.Servers.Admin.Ownership)
+
+	return nil
+}
+
+// shutdown stops taking requests and work, and waits for work in progress. The ACME responder
+// is stopped last, as orders in progress need it to answer their challenges.
+func (c *config) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(c.Daemon.ShutdownSeconds))
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		err := c.Servers.Admin.Shutdown(ctx)
+		if err != nil {
+			log.Println("error shutting down admin server:", err)
+		}
+	}()
+	c.Daemon.Shutdown(ctx)
+	wg.Wait()
+
+	rctx, rcancel := context.WithTimeout(context.Background(), responderShutdownTimeout)
+	defer rcancel()
+	err := c.Servers.ACME.Shutdown(rctx)
+	if err != nil {
+		log.Println("error shutting down acme responder:", err)
+	}
 }
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// csrSigner stands in for the private key of a cert issued for an uploaded CSR, as we never see that key.
+// Sources that support CSRs send the CSR as is, and anything that tries to sign with it gets an error.
+type csrSigner struct {
+	csr *x509.CertificateRequest
+}
+
+func (cs *csrSigner) Public() crypto.PublicKey {
+	return cs.csr.PublicKey
+}
+
+func (cs *csrSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return nil, errors.New("the private key for this cert is held elsewhere, only its CSR can be used")
+}
+
+// parseCSRPEM returns the CSR in s, having checked its signature
+func parseCSRPEM(s string) (*x509.CertificateRequest, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, errors.New("no CSR found in pem")
+	}
+	if block.Type != "CERTIFICATE REQUEST" && block.Type != "NEW CERTIFICATE REQUEST" {
+		return nil, errors.New("invalid CSR found in pem")
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	err = csr.CheckSignature()
+	if err != nil {
+		return nil, err
+	}
+	return csr, nil
+}
+
+// validateCSR checks that the CSR in s can be used for a cert managed under hostname, and returns
+// the other names it includes, which become the cert's SANs
+func validateCSR(s, hostname string) (*x509.CertificateRequest, []string, error) {
+	csr, err := parseCSRPEM(s)
+	if err != nil {
+		return nil, nil, err
+	}
+	if keyTypeOf(csr.PublicKey) == "" {
+		return nil, nil, errors.New("unsupported key type in CSR")
+	}
+	if len(csr.IPAddresses) != 0 || len(csr.EmailAddresses) != 0 || len(csr.URIs) != 0 {
+		return nil, nil, errors.New("CSR may only include DNS names")
+	}
+
+	names := parseHostnames(strings.Join(append([]string{csr.Subject.CommonName}, csr.DNSNames...), " "))
+	found := false
+	var sans []string
+	for _, hn := range names {
+		if hn == hostname {
+			found = true
+		} else {
+			sans = append(sans, hn)
+		}
+	}
+	if !found {
+		return nil, nil, fmt.Errorf("CSR does not include %s", hostname)
+	}
+
+	// de-duplicated as they will be when issued
+	return csr, certHostnames(hostname, sans)[1:], nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// testCSR returns a PEM CSR for key, with the template filled in by f
+func testCSR(t *testing.T, key crypto.Signer, f func(tmpl *x509.CertificateRequest)) string {
+	tmpl := &x509.CertificateRequest{}
+	f(tmpl)
+	der, err := x509.CreateCertificateRequest(rand.Reader, tmpl, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der}))
+}
+
+func TestValidateCSR(t *testing.T) {
+	key, err := generateKey(keyTypeECDSAP256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p224Key, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := testCSR(t, key, func(tmpl *x509.CertificateRequest) {
+		tmpl.Subject = pkix.Name{CommonName: "www.example.gov.au"}
+	})
+	block, _ := pem.Decode([]byte(valid))
+	block.Bytes[len(block.Bytes)-1] ^= 0xff
+	badSignature := string(pem.EncodeToMemory(block))
+
+	for _, tc := range []struct {
+		name     string
+		csr      string
+		wantSANs []string
+		wantErr  string
+	}{
+		{name: "common name only", csr: valid},
+		{
+			name: "sans",
+			csr: testCSR(t, key, func(tmpl *x509.CertificateRequest) {
+				tmpl.Subject = pkix.Name{CommonName: "WWW.example.gov.au."}
+				tmpl.DNSNames = []string{"www.example.gov.au", "example.gov.au", "Example.gov.au", "*.example.gov.au"}
+			}),
+			wantSANs: []string{"example.gov.au", "*.example.gov.au"},
+		},
+		{
+			name: "hostname only in sans",
+			csr: testCSR(t, key, func(tmpl *x509.CertificateRequest) {
+				tmpl.Subject = pkix.Name{CommonName: "example.gov.au"}
+				tmpl.DNSNames = []string{"www.example.gov.au"}
+			}),
+			wantSANs: []string{"example.gov.au"},
+		},
+		{
+			name: "hostname missing",
+			csr: testCSR(t, key, func(tmpl *x509.CertificateRequest) {
+				tmpl.Subject = pkix.Name{CommonName: "example.gov.au"}
+			}),
+			wantErr: "does not include www.example.gov.au",
+		},
+		{
+			name: "ip address",
+			csr: testCSR(t, key, func(tmpl *x509.CertificateRequest) {
+				tmpl.Subject = pkix.Name{CommonName: "www.example.gov.au"}
+				tmpl.IPAddresses = []net.IP{net.ParseIP("192.0.2.1")}
+			}),
+			wantErr: "only include DNS names",
+		},
+		{
+			name: "email address",
+			csr: testCSR(t, key, func(tmpl *x509.CertificateRequest) {
+				tmpl.Subject = pkix.Name{CommonName: "www.example.gov.au"}
+				tmpl.EmailAddresses = []string{"web@example.gov.au"}
+			}),
+			wantErr: "only include DNS names",
+		},
+		{
+			name: "uri",
+			csr: testCSR(t, key, func(tmpl *x509.CertificateRequest) {
+				tmpl.Subject = pkix.Name{CommonName: "www.example.gov.au"}
+				tmpl.URIs = []*url.URL{{Scheme: "spiffe", Host: "example.gov.au"}}
+			}),
+			wantErr: "only include DNS names",
+		},
+		{
+			name: "unsupported key",
+			csr: testCSR(t, p224Key, func(tmpl *x509.CertificateRequest) {
+				tmpl.Subject = pkix.Name{CommonName: "www.example.gov.au"}
+			}),
+			wantErr: "unsupported key type",
+		},
+		{name: "bad signature", csr: badSignature, wantErr: "verification failure"},
+		{name: "not pem", csr: "www.example.gov.au", wantErr: "no CSR found"},
+		{name: "not a csr", csr: "-----BEGIN CERTIFICATE-----\nAAAA\n-----END CERTIFICATE-----\n", wantErr: "invalid CSR"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			csr, sans, err := validateCSR(tc.csr, "www.example.gov.au")
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(sans, ",") != strings.Join(tc.wantSANs, ",") {
+				t.Fatalf("expected sans %v, got %v", tc.wantSANs, sans)
+			}
+			if keyTypeOf(csr.PublicKey) != keyTypeECDSAP256 {
+				t.Fatal("expected the CSR's key to be returned")
+			}
+		})
+	}
+}
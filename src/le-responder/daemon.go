@@ -2,43 +2,96 @@ package main
 
 import (
 	"context"
-	"crypto/rand"
-	"crypto/rsa"
+	"crypto"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	log "github.com/sirupsen/logrus"
 	"sort"
+	"sync"
 	"time"
-
-	"github.com/govau/cf-common/credhub"
 )
 
 type certSource interface {
-	// AutoFetchCert will try to fetch a cert now for the hostname and given context (you should set this to timeout)
-	AutoFetchCert(ctx context.Context, pkey *rsa.PrivateKey, hostname string) ([][]byte, error)
+	// AutoFetchCert will try to fetch a cert now for the hostnames and given context (you should set this to timeout)
+	// The first hostname is the one the cert is managed under, and is used as the common name.
+	AutoFetchCert(ctx context.Context, pkey crypto.Signer, hostnames []string) ([][]byte, error)
 
 	// ManualStartChallenge will return instructions on how to proceed. We'll persist it for you
-	ManualStartChallenge(ctx context.Context, hostname string) (*acmeChallenge, error)
+	ManualStartChallenge(ctx context.Context, hostnames []string) (*acmeChallenge, error)
 
 	// CompleteChallenge and issue cert
-	CompleteChallenge(ctx context.Context, pkey *rsa.PrivateKey, hostname string, chal *acmeChallenge) ([][]byte, error)
+	CompleteChallenge(ctx context.Context, pkey crypto.Signer, hostnames []string, chal *acmeChallenge) ([][]byte, error)
 
 	SupportsManual() bool
+
+	// SupportsCSR returns true if the source can issue certs for a CSR, passed as a *csrSigner in place of the private key
+	SupportsCSR() bool
+
+	// SupportsKeyType returns an error if this source can't issue certs for the key type
+	SupportsKeyType(kt string) error
+
+	// Revoke revokes an issued cert with an RFC 5280 reason code. key is the cert's private key if it should
+	// be used to sign the request, else nil.
+	Revoke(ctx context.Context, der []byte, key crypto.Signer, reason int) error
+
+	// SupportsRevoke returns false if Revoke always fails
+	SupportsRevoke() bool
+}
+
+// sourceDefaults are settings that apply to any type of source, which may be overridden per cert
+type sourceDefaults struct {
+	KeyType          string
+	SecondaryKeyType string
+	RenewalPolicy    string
+	LifetimeFraction float64
+	RevokeOnDelete   bool
 }
 
+const defaultSourceConcurrency = 4
+
 type shouldShipOracle interface {
 	ShipToProxy(hostname string) bool
 }
 
 type certRenewer interface {
-	RenewCertNow(hostname, cs string) error
+	// Actions are recorded in the audit log against actor
+	RenewCertNow(hostname, cs, actor string) error
 	CanDelete(hostname string) bool
 	Sources() []string
 	SourceCanManual(string) bool
-	StartManualChallenge(hostname string) error
-	CompleteChallenge(hostname string) error
+	SourceSupportsKeyType(cs, kt string) error
+	SourceSupportsRenewalPolicy(cs, policy string) error
+	SourceSupportsCSR(cs string) bool
+	StartManualChallenge(hostname, actor string) error
+	CompleteChallenge(hostname, actor string) error
+	RestoreVersion(hostname, version, actor string) error
+	SourceCanRevoke(cs string) bool
+	SourceRevokesOnDelete(cs string) bool
+
+	// LockHost waits until nothing else in this instance is changing the cert for hostname, e.g. renewing it,
+	// and returns a func to release the lock. Callers must hold it from loading the cert until saving changes.
+	LockHost(hostname string) func()
+
+	// RevokeCert revokes the current certs for hostname. The caller must hold the host lock.
+	RevokeCert(hostname, reason, actor string) error
+
+	// ImportCert saves a cert issued elsewhere, which the caller has checked and audited, and sends it to observers.
+	// The caller must hold the host lock.
+	ImportCert(hostname string, chc *credhubCert) error
+
+	// RenewalState returns how renewals have gone for the cert, or nil if it has never been issued by us
+	RenewalState(hostname string) *renewalState
+
+	// QueueJob runs RenewCertNow (jobRenew), CompleteChallenge (jobComplete) or the key compromise workflow (jobCompromise)
+	// in the background, returning the job. If we aren't the leader, it is handed over to the leader to run.
+	QueueJob(kind, hostname, cs, actor string) (*job, error)
+	Job(id string) *job
+	Jobs() []*job
+
+	// Leader returns the ID of the leader as last seen, or "" if not known, and whether it is us
+	Leader() (string, bool)
 }
 
 type daemonConf struct {
@@ -47,32 +100,143 @@ type daemonConf struct {
 	Bootstrap  struct {
 		Source string `yaml:"source"`
 	} `yaml:"bootstrap"`
+	JobQueue jobQueue `yaml:"jobs"`
+
+	// LeaderElection, if enabled, picks one of several instances to renew certs and update observers
+	LeaderElection leaderElection `yaml:"leader_election"`
+
+	// Failed automatic renewals are retried with exponential backoff between these
+	RetryMinSeconds int `yaml:"retry_min_seconds"`
+	RetryMaxSeconds int `yaml:"retry_max_seconds"`
+
+	// ShutdownSeconds is how long work in progress is given to finish on SIGTERM before it is cancelled
+	ShutdownSeconds int `yaml:"shutdown_seconds"`
 
 	fixedHosts []string
 	ourHN      string
 	storage    certStorage
+	responder  responder
+	audit      *auditLog
+
+	reloadLock sync.RWMutex // srcs and observers are replaced when the config is reloaded
+	srcs       *daemonSources
+	observers  []certObserver // outputs, only updated by the leader
+
+	localObservers []certObserver // updated by every instance, e.g. the admin UI's own cert
 
-	certFactories map[string]certSource
-	sources       []string
-	observers     []certObserver
+	hostLocks   keyedMutex // held while deciding to issue and issuing a cert, so that the scan and jobs don't both do so at once
+	renewals    *renewalTracker
+	metricsLock sync.Mutex // so that the scan and observer updates don't interleave resetting per-cert metrics
+
+	// observersLock is held while updating observers, as the observer loop, shutdown and key compromise
+	// jobs may all do so, and observers don't expect to be called concurrently
+	observersLock sync.Mutex
 
 	updateRequests chan bool
+
+	// workCtx is the parent of everything that talks to CAs, and is cancelled if work is still in progress
+	// when we have waited as long as we can to shut down
+	workCtx    context.Context
+	cancelWork context.CancelFunc
+
+	stopping      chan struct{} // closed when we start shutting down
+	scanDone      chan struct{} // closed once the periodic scan loop has stopped
+	observersDone chan struct{} // closed once the observer loop has stopped
+	flushOnStop   bool          // set by the observer loop if it stopped with an update pending
+}
+
+const defaultShutdownSeconds = 30
+
+// shutdownCancelWait is how long we wait for work to return once cancelled, before giving up on it
+const shutdownCancelWait = 10 * time.Second
+
+// daemonSources are built from the sources config, and replaced as a whole when it is reloaded
+type daemonSources struct {
+	certFactories  map[string]certSource
+	sourceDefaults map[string]*sourceDefaults
+	concurrency    map[string]int // by source
+	sources        []string
+}
+
+// current returns the sources as last loaded. Work in progress keeps using the ones it started with.
+func (dc *daemonConf) current() *daemonSources {
+	dc.reloadLock.RLock()
+	defer dc.reloadLock.RUnlock()
+	return dc.srcs
+}
+
+func (dc *daemonConf) currentObservers() []certObserver {
+	dc.reloadLock.RLock()
+	defer dc.reloadLock.RUnlock()
+	return dc.observers
+}
+
+// Reload replaces the sources and outputs, and asks for observers to be updated, as the outputs may be new
+func (dc *daemonConf) Reload(srcs *daemonSources, observers []certObserver) {
+	dc.reloadLock.Lock()
+	dc.srcs = srcs
+	dc.observers = observers
+	dc.reloadLock.Unlock()
+
+	dc.requestUpdate()
 }
 
 func (dc *daemonConf) Sources() []string {
-	return dc.sources
+	return dc.current().sources
 }
 
 func (dc *daemonConf) SourceCanManual(cs string) bool {
-	cf, ok := dc.certFactories[cs]
+	cf, ok := dc.current().certFactories[cs]
 	if !ok {
 		return false
 	}
 	return cf.SupportsManual()
 }
 
-func (dc *daemonConf) Init(ourHostname string, sm sourceMap, storage certStorage, observers []certObserver, responder responder) error {
+func (dc *daemonConf) SourceSupportsKeyType(cs, kt string) error {
+	cf, ok := dc.current().certFactories[cs]
+	if !ok {
+		return fmt.Errorf("no cert source found for: %s", cs)
+	}
+	return cf.SupportsKeyType(kt)
+}
+
+func (dc *daemonConf) SourceSupportsCSR(cs string) bool {
+	cf, ok := dc.current().certFactories[cs]
+	if !ok {
+		return false
+	}
+	return cf.SupportsCSR()
+}
+
+func (dc *daemonConf) SourceSupportsRenewalPolicy(cs, policy string) error {
+	cf, ok := dc.current().certFactories[cs]
+	if !ok {
+		return errors.New("no such source")
+	}
+	return dc.sourceSupportsRenewalPolicy(cf, policy)
+}
+
+func (dc *daemonConf) sourceSupportsRenewalPolicy(cf certSource, policy string) error {
+	err := validateRenewalPolicy(policy)
+	if err != nil {
+		return err
+	}
+	if policy == renewalPolicyARI {
+		if _, ok := cf.(renewalInfoSource); !ok {
+			return errors.New("the ari renewal policy is only supported by acme sources")
+		}
+	}
+	return nil
+}
+
+// Init sets up the daemon. Every instance updates localObservers, but only the leader updates observers.
+func (dc *daemonConf) Init(ourHostname string, sm sourceMap, storage certStorage, audit *auditLog, localObservers, observers []certObserver, responder responder) error {
 	dc.updateRequests = make(chan bool, 1000)
+	dc.workCtx, dc.cancelWork = context.WithCancel(context.Background())
+	dc.stopping = make(chan struct{})
+	dc.scanDone = make(chan struct{})
+	dc.observersDone = make(chan struct{})
 
 	if dc.Period == 0 {
 		return errors.New("period must be specified and non-zero. should be in seconds")
@@ -87,52 +251,211 @@ func (dc *daemonConf) Init(ourHostname string, sm sourceMap, storage certStorage
 		ourHostname,
 	}
 
-	dc.certFactories = make(map[string]certSource)
-	dc.sources = nil
+	dc.storage = storage
+	dc.audit = audit
+	dc.responder = responder
+
+	srcs, err := dc.buildSources(sm)
+	if err != nil {
+		return err
+	}
+	dc.srcs = srcs
+
+	if dc.RetryMinSeconds == 0 {
+		dc.RetryMinSeconds = defaultRetryMinSeconds
+	}
+	if dc.RetryMaxSeconds == 0 {
+		dc.RetryMaxSeconds = defaultRetryMaxSeconds
+	}
+	if dc.RetryMinSeconds < 0 || dc.RetryMaxSeconds < dc.RetryMinSeconds {
+		return errors.New("retry_min_seconds must be positive, and no more than retry_max_seconds")
+	}
+	if dc.ShutdownSeconds == 0 {
+		dc.ShutdownSeconds = defaultShutdownSeconds
+	}
+	if dc.ShutdownSeconds < 0 {
+		return errors.New("shutdown_seconds must not be negative")
+	}
+	dc.renewals = &renewalTracker{
+		storage:  storage,
+		leader:   &dc.LeaderElection,
+		minDelay: time.Second * time.Duration(dc.RetryMinSeconds),
+		maxDelay: time.Second * time.Duration(dc.RetryMaxSeconds),
+	}
+
+	err = dc.JobQueue.Init(dc.workCtx, dc.runJob)
+	if err != nil {
+		return err
+	}
+
+	err = dc.LeaderElection.Init(storage)
+	if err != nil {
+		return err
+	}
+
+	dc.localObservers = localObservers
+	dc.observers = observers
+
+	return nil
+}
+
+// buildSources initialises the configured sources, returning an error if any are invalid
+func (dc *daemonConf) buildSources(sm sourceMap) (*daemonSources, error) {
+	rv := &daemonSources{
+		certFactories:  make(map[string]certSource),
+		sourceDefaults: make(map[string]*sourceDefaults),
+		concurrency:    make(map[string]int),
+	}
 	for name, val := range sm {
+		if name == importedSourceName {
+			return nil, fmt.Errorf("source name %s is reserved for imported certs", name)
+		}
 		switch val.Type {
 		case "self-signed":
-			dc.certFactories[name] = &selfSignedSource{}
+			rv.certFactories[name] = &selfSignedSource{}
+		case "ca":
+			v := &caSource{
+				name:    name,
+				storage: dc.storage,
+			}
+			if val.CA != nil {
+				v.caConf = *val.CA
+			}
+			err := v.Init()
+			if err != nil {
+				return nil, fmt.Errorf("source %s: %s", name, err)
+			}
+			if (val.RenewalPolicy == "" || val.RenewalPolicy == renewalPolicyDaysBefore) && v.ValidityDays <= dc.DaysBefore {
+				return nil, fmt.Errorf("source %s: validity_days must be more than days_before, or use the lifetime renewal policy", name)
+			}
+			rv.certFactories[name] = v
 		case "acme":
 			v := &acmeCertSource{
+				name:            name,
 				EmailContact:    val.Email,
 				URL:             val.URL,
 				PrivateKey:      val.PrivateKey,
-				responderServer: responder,
+				Challenges:      val.Challenges,
+				responderServer: dc.responder,
+			}
+			if val.DNS != nil {
+				dp, err := val.DNS.Init()
+				if err != nil {
+					return nil, err
+				}
+				v.dnsProvider = dp
 			}
 			err := v.Init()
 			if err != nil {
-				return err
+				return nil, fmt.Errorf("source %s: %s", name, err)
 			}
-			dc.certFactories[name] = v
+			rv.certFactories[name] = v
 
 		default:
-			return errors.New("unknown cert source type")
+			return nil, errors.New("unknown cert source type")
 		}
 
-		dc.sources = append(dc.sources, name)
+		for _, kt := range []string{val.KeyType, val.SecondaryKeyType} {
+			if kt == "" {
+				continue
+			}
+			err := rv.certFactories[name].SupportsKeyType(kt)
+			if err != nil {
+				return nil, fmt.Errorf("source %s: %s", name, err)
+			}
+		}
+		if val.RenewalPolicy != "" {
+			err := dc.sourceSupportsRenewalPolicy(rv.certFactories[name], val.RenewalPolicy)
+			if err != nil {
+				return nil, fmt.Errorf("source %s: %s", name, err)
+			}
+		}
+		if val.LifetimeFraction == 0 {
+			val.LifetimeFraction = defaultLifetimeFraction
+		}
+		if val.LifetimeFraction <= 0 || val.LifetimeFraction >= 1 {
+			return nil, fmt.Errorf("source %s: lifetime fraction must be between 0 and 1", name)
+		}
+		rv.sourceDefaults[name] = &sourceDefaults{
+			KeyType:          val.KeyType,
+			SecondaryKeyType: val.SecondaryKeyType,
+			RenewalPolicy:    val.RenewalPolicy,
+			LifetimeFraction: val.LifetimeFraction,
+			RevokeOnDelete:   val.RevokeOnDelete,
+		}
+		if val.RevokeOnDelete && !rv.certFactories[name].SupportsRevoke() {
+			return nil, fmt.Errorf("source %s: revoke_on_delete is set, but its certs cannot be revoked", name)
+		}
+
+		switch {
+		case val.Concurrency == 0:
+			rv.concurrency[name] = defaultSourceConcurrency
+		case val.Concurrency < 0:
+			return nil, fmt.Errorf("source %s: concurrency must not be negative", name)
+		default:
+			rv.concurrency[name] = val.Concurrency
+		}
+
+		rv.sources = append(rv.sources, name)
 	}
 
-	if len(dc.certFactories) == 0 {
-		return errors.New("must specify at least one cert source")
+	if len(rv.certFactories) == 0 {
+		return nil, errors.New("must specify at least one cert source")
 	}
 
-	dc.storage = storage
+	// Not listed in sources, so that certs can't be created with it or moved to it
+	rv.certFactories[importedSourceName] = &importedSource{}
 
-	sort.StringSlice(dc.sources).Sort()
+	sort.StringSlice(rv.sources).Sort()
 
-	dc.observers = observers
+	return rv, nil
+}
 
-	return nil
+// TrustBundle returns the CA certs of any sources that sign with their own CA
+func (dc *daemonConf) TrustBundle() (string, error) {
+	var rv string
+	srcs := dc.current()
+	for _, name := range srcs.sources {
+		ccs, ok := srcs.certFactories[name].(caCertSource)
+		if ok {
+			ca, err := ccs.CACertificate()
+			if err != nil {
+				return "", err
+			}
+			rv += ca
+		}
+	}
+	return rv, nil
 }
 
-func (dc *daemonConf) updateObservers() error {
+// CRL returns the current DER CRL for the source, or errCertNotFound if it doesn't publish one
+func (dc *daemonConf) CRL(cs string) ([]byte, error) {
+	cs2, ok := dc.current().certFactories[cs].(crlSource)
+	if !ok {
+		return nil, errCertNotFound
+	}
+	return cs2.CRL()
+}
+
+// updateObservers sends the current certs to the local observers, and if outputs is set, which it
+// should only be on the leader, to the outputs too
+func (dc *daemonConf) updateObservers(outputs bool) error {
+	dc.observersLock.Lock()
+	defer dc.observersLock.Unlock()
+
 	certs, err := dc.storage.FetchCerts()
 	if err != nil {
 		return err
 	}
+
+	observers := dc.localObservers
+	if outputs {
+		dc.updateCertMetrics(certs)
+		observers = append(append([]certObserver{}, observers...), dc.currentObservers()...)
+	}
+
 	var retErr error
-	for _, ob := range dc.observers {
+	for _, ob := range observers {
 		err = ob.CertsAreUpdated(certs)
 		if err != nil {
 			log.Println("erroring updating cert observer, will continue to next but still return failed:", err)
@@ -142,11 +465,101 @@ func (dc *daemonConf) updateObservers() error {
 	return retErr
 }
 
+// requestUpdate asks for observers to be updated soon. Only the leader updates the outputs, so other instances
+// hand that over, and just update their local observers.
+func (dc *daemonConf) requestUpdate() {
+	dc.updateRequests <- true
+	if dc.LeaderElection.IsLeader() {
+		return
+	}
+	err := dc.LeaderElection.handOver(func(lp *leaderPending) {
+		lp.Update = true
+	})
+	if err != nil {
+		metricErrors.WithLabelValues("leader_handover").Inc()
+		log.Println("error asking the leader to update observers, it will on its next scan:", err)
+	}
+}
+
+// takeHandedOver runs work left by other instances while we are the leader. When we become
+// the leader, observers are updated, in case the last leader stopped before it could.
+func (dc *daemonConf) takeHandedOver() {
+	wasLeader := false
+	for {
+		isLeader := dc.LeaderElection.IsLeader()
+		if isLeader && !wasLeader {
+			dc.updateRequests <- true
+		}
+		wasLeader = isLeader
+
+		if isLeader {
+			lp, err := dc.LeaderElection.takePending()
+			if err != nil {
+				metricErrors.WithLabelValues("leader_handover").Inc()
+				log.Println("error fetching work handed over to the leader, will try again soon:", err)
+			} else {
+				if lp.Update {
+					dc.updateRequests <- true
+				}
+				for _, j := range lp.Jobs {
+					log.Printf("queueing %s job %s for %s, handed over by another instance\n", j.Kind, j.ID, j.Hostname)
+					_, err = dc.JobQueue.EnqueueHandedOver(j)
+					if err != nil {
+						log.Printf("error queueing %s job %s for %s: %s\n", j.Kind, j.ID, j.Hostname, err)
+					}
+				}
+			}
+		}
+
+		if !dc.sleep(leaderPendingInterval) {
+			return
+		}
+	}
+}
+
+// isStopping returns true once we have started shutting down
+func (dc *daemonConf) isStopping() bool {
+	select {
+	case <-dc.stopping:
+		return true
+	default:
+		return false
+	}
+}
+
+// sleep waits for d, returning false if we start shutting down first
+func (dc *daemonConf) sleep(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-dc.stopping:
+		return false
+	}
+}
+
+func (dc *daemonConf) Leader() (string, bool) {
+	return dc.LeaderElection.Leader(), dc.LeaderElection.IsLeader()
+}
+
+// RunForever runs the periodic scan and observer updates, until Shutdown is called
 func (dc *daemonConf) RunForever() {
+	go dc.LeaderElection.RunForever(dc.stopping)
+	go dc.takeHandedOver()
+
 	// Periodic scan loop, this will ping the update request queue
 	go func() {
+		defer close(dc.scanDone)
 		bootstrapped := false
 		for {
+			if !dc.LeaderElection.IsLeader() {
+				if !dc.sleep(leaderPendingInterval) {
+					return
+				}
+				continue
+			}
+
 			nextSleepSeconds := time.Duration(dc.Period)
 
 			log.Println("starting periodic scan...")
@@ -159,22 +572,43 @@ func (dc *daemonConf) RunForever() {
 				metricHealth.WithLabelValues("fetching_certs").Set(1) // unhealthy
 				metricErrors.WithLabelValues("fetching_certs").Inc()
 				log.Println("error in periodic scan, ignoring:", err)
-				if credhub.IsCommsRelatedError(err) && !bootstrapped {
+				if isCommsRelatedError(err) && !bootstrapped {
 					log.Println("looks like a comms related issue, we'll reduce our sleep time")
 					nextSleepSeconds = 15
 				}
 			}
 
+			// Come back sooner if a failed renewal is due to be retried
+			nextRetry := dc.renewals.NextRetry()
+			if !nextRetry.IsZero() {
+				untilRetry := time.Duration(time.Until(nextRetry).Seconds())
+				if untilRetry < 60 {
+					untilRetry = 60
+				}
+				if untilRetry < nextSleepSeconds {
+					nextSleepSeconds = untilRetry
+				}
+			}
+
 			log.Printf("sleeping for %d...\n", nextSleepSeconds)
-			time.Sleep(time.Second * nextSleepSeconds)
+			if !dc.sleep(time.Second * nextSleepSeconds) {
+				return
+			}
 		}
 	}()
 
 	// Write out config loop
 	t := time.NewTimer(time.Second * 5)
+	pending := true // on startup, in case the last leader stopped before it could
 	for {
 		select {
+		case <-dc.stopping:
+			// Shutdown sends any pending update, once work in progress is done
+			dc.flushOnStop = pending
+			close(dc.observersDone)
+			return
 		case <-dc.updateRequests:
+			pending = true
 			// Reset our timer to fire after a reasonable period in case new certs also come through
 			// first Stop() and drain it, per the docs
 			if !t.Stop() {
@@ -187,8 +621,18 @@ func (dc *daemonConf) RunForever() {
 			// we don't have to stop it, because we fired to begin with we know it is drained
 			t.Reset(time.Hour * 24 * 365)
 
+			// A failure below asks again
+			pending = false
+
+			isLeader := dc.LeaderElection.IsLeader()
+			if !isLeader {
+				// The leader renews certs, including ours, so check for changes every period
+				log.Println("not the leader, leaving outputs to it and only updating our own cert")
+				t.Reset(time.Second * time.Duration(dc.Period))
+			}
+
 			log.Println("updating observers...")
-			err := dc.updateObservers()
+			err := dc.updateObservers(isLeader)
 			if err == nil {
 				metricHealth.WithLabelValues("updating_aws").Set(0) // healthy
 				log.Println("updating observers completed successfully.")
@@ -202,14 +646,64 @@ func (dc *daemonConf) RunForever() {
 	}
 }
 
+// Shutdown stops new work from starting, and waits for work in progress to finish until ctx is done, after
+// which it is cancelled. Any pending update is then sent to observers, and the leader lease given up.
+func (dc *daemonConf) Shutdown(ctx context.Context) {
+	close(dc.stopping)
+	dc.JobQueue.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		dc.JobQueue.Wait()
+		<-dc.scanDone
+		<-dc.observersDone
+		close(done)
+	}()
+
+	finished := true
+	select {
+	case <-done:
+	case <-ctx.Done():
+		log.Println("work still in progress, cancelling it...")
+		dc.cancelWork()
+		select {
+		case <-done:
+		case <-time.After(shutdownCancelWait):
+			log.Println("gave up waiting for cancelled work to finish")
+			finished = false
+		}
+	}
+
+	// Jobs that finished while we waited may also have asked for an update
+	if finished && (dc.flushOnStop || len(dc.updateRequests) != 0) && dc.LeaderElection.IsLeader() {
+		log.Println("updating observers before exiting...")
+		err := dc.updateObservers(true)
+		if err != nil {
+			metricErrors.WithLabelValues("updating_aws").Inc()
+			log.Println("error updating observers, the next leader will try again:", err)
+		}
+	}
+
+	dc.LeaderElection.Resign()
+	dc.cancelWork()
+}
+
 func (dc *daemonConf) renewCertIfNeeded(hostname string) error {
+	if dc.isStopping() {
+		return errJobsStopped
+	}
+
+	// Held while deciding too, so that we see the result of any renewal or revocation in progress,
+	// rather than renewing again once it's done
+	defer dc.hostLocks.Lock(hostname)()
+
 	path := pathFromHost(hostname)
 
 	needNew := false
 
 	chc, err := dc.storage.LoadPath(path)
 	if err != nil {
-		if credhub.IsNotFoundError(err) {
+		if err == errCertNotFound {
 			needNew = true
 			chc = nil
 		} else {
@@ -225,6 +719,11 @@ func (dc *daemonConf) renewCertIfNeeded(hostname string) error {
 	if chc != nil {
 		sourceToUse = chc.Source
 
+		// Kept as is until an admin changes the source to one that can renew it
+		if sourceToUse == importedSourceName {
+			return nil
+		}
+
 		if chc.Challenge != nil {
 			return errors.New("challenge not empty, we will not try to auto renew, please use console to do manually")
 		}
@@ -246,7 +745,26 @@ func (dc *daemonConf) renewCertIfNeeded(hostname string) error {
 			return errors.New("cert already expired, we won't try to auto-renew. do so manually via console")
 		}
 
-		if pc.NotAfter.Before(time.Now().Add(24 * time.Hour * time.Duration(dc.DaysBefore))) {
+		if dc.renewalDue(hostname, sourceToUse, chc, pc) {
+			needNew = true
+		}
+
+		// Revoked certs are replaced straight away
+		if chc.Revoked != nil {
+			needNew = true
+		}
+
+		// If names have been added since it was issued, then get a new one that covers all
+		if !certCoversHostnames(pc, certHostnames(hostname, chc.SANs)) {
+			needNew = true
+		}
+
+		// Likewise if the key types wanted have been changed
+		kt, skt := dc.keyTypesFor(sourceToUse, chc)
+		if keyTypeOf(pc.PublicKey) != kt {
+			needNew = true
+		}
+		if skt != "" && (chc.Secondary == nil || chc.Secondary.KeyType != skt) {
 			needNew = true
 		}
 	}
@@ -255,7 +773,15 @@ func (dc *daemonConf) renewCertIfNeeded(hostname string) error {
 		return nil
 	}
 
-	err = dc.RenewCertNow(hostname, sourceToUse)
+	ok, rs, err := dc.renewals.ShouldAttempt(hostname)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("renewal has failed %d times, will retry after %s: %s", rs.ConsecutiveFailures, rs.NextAttempt.Format(time.RFC3339), rs.LastError)
+	}
+
+	err = dc.renewCertLocked(dc.workCtx, hostname, sourceToUse, auditActorDaemon)
 	if err != nil {
 		return err
 	}
@@ -263,6 +789,55 @@ func (dc *daemonConf) renewCertIfNeeded(hostname string) error {
 	return nil
 }
 
+// updateCertMetrics replaces the per-cert gauges with values for certs, so that deleted certs are dropped
+func (dc *daemonConf) updateCertMetrics(certs []*credhubCert) {
+	dc.metricsLock.Lock()
+	defer dc.metricsLock.Unlock()
+
+	resetCertMetrics()
+
+	now := time.Now()
+	for _, chc := range certs {
+		hostname := hostFromPath(chc.path)
+
+		rp := dc.renewalPolicyFor(chc.Source, chc)
+		if chc.Source == importedSourceName {
+			rp = nil // never renewed
+		} else if rp.Policy == renewalPolicyDaysBefore {
+			metricCertDaysBefore.WithLabelValues(hostname, chc.Source).Set(float64(rp.DaysBefore))
+		}
+
+		challengePending := 0.0
+		if chc.Challenge != nil {
+			challengePending = 1
+		}
+		metricCertChallengePending.WithLabelValues(hostname, chc.Source).Set(challengePending)
+
+		// Not issued yet, e.g. waiting for a manual challenge
+		pc, err := parseCertificatePEM(chc.Certificate)
+		if err == nil {
+			metricCertNotAfter.WithLabelValues(hostname, chc.Source).Set(float64(pc.NotAfter.Unix()))
+			metricCertExpiry.WithLabelValues(hostname, chc.Source).Set(pc.NotAfter.Sub(now).Seconds())
+			if rp != nil && rp.Policy != renewalPolicyDaysBefore {
+				metricCertDaysBefore.WithLabelValues(hostname, chc.Source).Set(pc.NotAfter.Sub(rp.renewAt(pc)).Hours() / 24)
+			}
+		}
+
+		lastAttempt, lastSuccess := 0.0, 0.0
+		rs := dc.renewals.Get(hostname)
+		if rs != nil {
+			if !rs.LastAttempt.IsZero() {
+				lastAttempt = float64(rs.LastAttempt.Unix())
+			}
+			if !rs.LastSuccess.IsZero() {
+				lastSuccess = float64(rs.LastSuccess.Unix())
+			}
+		}
+		metricCertLastAttempt.WithLabelValues(hostname, chc.Source).Set(lastAttempt)
+		metricCertLastSuccess.WithLabelValues(hostname, chc.Source).Set(lastSuccess)
+	}
+}
+
 func (dc *daemonConf) CanDelete(hostname string) bool {
 	return !dc.isFixedHost(hostname)
 }
@@ -280,8 +855,13 @@ func (dc *daemonConf) isFixedHost(hostname string) bool {
 	return false
 }
 
-func (dc *daemonConf) StartManualChallenge(hostname string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+func (dc *daemonConf) StartManualChallenge(hostname, actor string) (err error) {
+	ae := dc.audit.Start(actor, "manual", hostname, "")
+	defer func() { dc.audit.Finish(ae, err) }()
+
+	defer dc.hostLocks.Lock(hostname)()
+
+	ctx, cancel := context.WithTimeout(dc.workCtx, 1*time.Minute)
 	defer cancel()
 
 	path := pathFromHost(hostname)
@@ -289,13 +869,14 @@ func (dc *daemonConf) StartManualChallenge(hostname string) error {
 	if err != nil {
 		return err
 	}
+	ae.Source = curCert.Source
 
-	cf, ok := dc.certFactories[curCert.Source]
+	cf, ok := dc.current().certFactories[curCert.Source]
 	if !ok {
 		return fmt.Errorf("no cert source found for: %s", curCert.Source)
 	}
 
-	chal, err := cf.ManualStartChallenge(ctx, hostname)
+	chal, err := cf.ManualStartChallenge(ctx, certHostnames(hostname, curCert.SANs))
 	if err != nil {
 		return err
 	}
@@ -310,40 +891,176 @@ func (dc *daemonConf) StartManualChallenge(hostname string) error {
 	return nil
 }
 
-func (dc *daemonConf) CompleteChallenge(hostname string) error {
+func (dc *daemonConf) CompleteChallenge(hostname, actor string) error {
+	return dc.completeChallenge(dc.workCtx, hostname, actor)
+}
+
+func (dc *daemonConf) completeChallenge(ctx context.Context, hostname, actor string) (err error) {
+	ae := dc.audit.Start(actor, "complete", hostname, "")
+	defer func() {
+		dc.audit.Finish(ae, err)
+		dc.renewals.Record(hostname, err)
+	}()
+
+	defer dc.hostLocks.Lock(hostname)()
+
 	chd, err := dc.storage.LoadPath(pathFromHost(hostname))
 	if err != nil {
 		return err
 	}
+	ae.Source = chd.Source
 
 	if chd.Challenge == nil {
 		return errors.New("challenge not set")
 	}
 
-	return dc.getCertAndSave(hostname, chd.Source, func(ctx context.Context, cf certSource, pkey *rsa.PrivateKey) ([][]byte, error) {
-		return cf.CompleteChallenge(ctx, pkey, hostname, chd.Challenge)
+	return dc.getCertAndSave(ctx, hostname, chd.Source, chd, func(ctx context.Context, cf certSource, pkey crypto.Signer) ([][]byte, error) {
+		return cf.CompleteChallenge(ctx, pkey, certHostnames(hostname, chd.SANs), chd.Challenge)
 	})
 }
 
-func (dc *daemonConf) getCertAndSave(hostname, cs string, issuer func(context.Context, certSource, *rsa.PrivateKey) ([][]byte, error)) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
-	defer cancel()
+// RestoreVersion makes a previous version of a cert current again, and sends it to observers.
+// Versions whose certs have since been revoked can't be restored.
+func (dc *daemonConf) RestoreVersion(hostname, version, actor string) (err error) {
+	ae := dc.audit.Start(actor, "restore", hostname, "")
+	defer func() { dc.audit.Finish(ae, err) }()
+
+	defer dc.hostLocks.Lock(hostname)()
 
-	pkey, err := rsa.GenerateKey(rand.Reader, 2048)
+	path := pathFromHost(hostname)
+	chc, err := dc.storage.LoadVersion(path, version)
 	if err != nil {
 		return err
 	}
+	ae.Source = chc.Source
 
-	cf, ok := dc.certFactories[cs]
-	if !ok {
-		return fmt.Errorf("no cert source found for: %s", cs)
+	if chc.Certificate == "" {
+		return errors.New("no cert was issued in this version")
 	}
 
-	der, err := issuer(ctx, cf, pkey)
+	err = dc.checkNotRevoked(path, chc)
+	if err != nil {
+		return err
+	}
+
+	err = dc.storage.SavePath(path, chc)
+	if err != nil {
+		return err
+	}
+
+	dc.requestUpdate()
+
+	return nil
+}
+
+// LockHost takes the host lock, which is also held while issuing a cert
+func (dc *daemonConf) LockHost(hostname string) func() {
+	return dc.hostLocks.Lock(hostname)
+}
+
+// ImportCert saves a cert issued elsewhere, and sends it to observers. The caller must hold the host lock.
+func (dc *daemonConf) ImportCert(hostname string, chc *credhubCert) error {
+	err := dc.storage.SavePath(pathFromHost(hostname), chc)
 	if err != nil {
 		return err
 	}
 
+	dc.requestUpdate()
+
+	return nil
+}
+
+// renewalPolicyFor returns the renewal policy for a cert. chc may be nil, for the source default.
+func (dc *daemonConf) renewalPolicyFor(cs string, chc *credhubCert) *renewalPolicy {
+	rv := &renewalPolicy{
+		Policy:           renewalPolicyDaysBefore,
+		DaysBefore:       dc.DaysBefore,
+		LifetimeFraction: defaultLifetimeFraction,
+	}
+	sd, ok := dc.current().sourceDefaults[cs]
+	if ok {
+		if sd.RenewalPolicy != "" {
+			rv.Policy = sd.RenewalPolicy
+		}
+		rv.LifetimeFraction = sd.LifetimeFraction
+	}
+	if chc != nil && chc.RenewalPolicy != "" {
+		rv.Policy = chc.RenewalPolicy
+	}
+	return rv
+}
+
+// renewalDue returns true if pc, the current cert for hostname, should be renewed now
+func (dc *daemonConf) renewalDue(hostname, cs string, chc *credhubCert, pc *x509.Certificate) bool {
+	rp := dc.renewalPolicyFor(cs, chc)
+	if rp.Policy == renewalPolicyARI {
+		ris, ok := dc.current().certFactories[cs].(renewalInfoSource)
+		if ok {
+			ctx, cancel := context.WithTimeout(dc.workCtx, 30*time.Second)
+			defer cancel()
+
+			rw, err := ris.RenewalWindow(ctx, pc)
+			if err == nil {
+				return !time.Now().Before(rw.renewAt(pc))
+			}
+			log.Printf("error fetching renewal information for %s, falling back to lifetime: %s\n", hostname, err)
+		}
+	}
+	return !time.Now().Before(rp.renewAt(pc))
+}
+
+// replacesFor returns the ARI cert ID of the cert in certPEM, if it is to be sent as being replaced by a new order
+func (dc *daemonConf) replacesFor(cs string, chc *credhubCert, certPEM string) string {
+	if chc == nil || chc.Source != cs || certPEM == "" {
+		return ""
+	}
+	if dc.renewalPolicyFor(cs, chc).Policy != renewalPolicyARI {
+		return ""
+	}
+	pc, err := parseCertificatePEM(certPEM)
+	if err != nil {
+		return ""
+	}
+	rv, err := ariCertID(pc)
+	if err != nil {
+		return ""
+	}
+	return rv
+}
+
+// keyTypesFor returns the primary and secondary (possibly empty) key types to use for a cert
+func (dc *daemonConf) keyTypesFor(cs string, chc *credhubCert) (string, string) {
+	kt, skt := defaultKeyType, ""
+	sd, ok := dc.current().sourceDefaults[cs]
+	if ok {
+		if sd.KeyType != "" {
+			kt = sd.KeyType
+		}
+		skt = sd.SecondaryKeyType
+	}
+	if chc != nil {
+		if chc.KeyType != "" {
+			kt = chc.KeyType
+		}
+		if chc.SecondaryKeyType != "" {
+			skt = chc.SecondaryKeyType
+		}
+		// The key for a CSR was generated elsewhere, and there can only be one
+		if chc.CSR != "" {
+			csr, err := parseCSRPEM(chc.CSR)
+			if err == nil {
+				kt = keyTypeOf(csr.PublicKey)
+			}
+			skt = ""
+		}
+	}
+	if skt == kt {
+		skt = ""
+	}
+	return kt, skt
+}
+
+func newCertKeyPair(kt string, der [][]byte, pkey crypto.Signer) (*certKeyPair, error) {
 	roots := ""
 	for _, r := range der[1:] {
 		roots += string(pem.EncodeToMemory(&pem.Block{
@@ -352,23 +1069,135 @@ func (dc *daemonConf) getCertAndSave(hostname, cs string, issuer func(context.Co
 		}))
 	}
 
-	certType := "admin"
-	if dc.CanDelete(hostname) {
-		certType = "user"
+	// We don't have the private key for a CSR
+	var pk string
+	if _, ok := pkey.(*csrSigner); !ok {
+		var err error
+		pk, err = marshalPrivateKey(pkey)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	err = dc.storage.SavePath(pathFromHost(hostname), &credhubCert{
-		Source: cs,
-		CA:     roots,
-		Type:   certType,
+	return &certKeyPair{
+		KeyType: kt,
+		CA:      roots,
 		Certificate: string(pem.EncodeToMemory(&pem.Block{
 			Bytes: der[0],
 			Type:  "CERTIFICATE",
 		})),
-		PrivateKey: string(pem.EncodeToMemory(&pem.Block{
-			Bytes: x509.MarshalPKCS1PrivateKey(pkey),
-			Type:  "RSA PRIVATE KEY",
-		})),
+		PrivateKey: pk,
+	}, nil
+}
+
+// getCertAndSave issues a new cert using issuer, carrying over settings from existing (which may be nil).
+// The caller must hold the host lock.
+func (dc *daemonConf) getCertAndSave(ctx context.Context, hostname, cs string, existing *credhubCert, issuer func(context.Context, certSource, crypto.Signer) ([][]byte, error)) error {
+	ctx, cancel := context.WithTimeout(ctx, 1*time.Minute)
+	defer cancel()
+
+	stored := existing != nil
+	if existing == nil {
+		existing = &credhubCert{}
+	}
+	kt, skt := dc.keyTypesFor(cs, existing)
+
+	cf, ok := dc.current().certFactories[cs]
+	if !ok {
+		return fmt.Errorf("no cert source found for: %s", cs)
+	}
+
+	var pkey crypto.Signer
+	if existing.CSR != "" {
+		if !cf.SupportsCSR() {
+			return fmt.Errorf("source %s cannot issue certs for a CSR", cs)
+		}
+		csr, err := parseCSRPEM(existing.CSR)
+		if err != nil {
+			return err
+		}
+		pkey = &csrSigner{csr: csr}
+	} else {
+		reportStep(ctx, "generating %s key", kt)
+		var err error
+		pkey, err = generateKey(kt)
+		if err != nil {
+			return err
+		}
+	}
+
+	der, err := issuer(ctx, cf, pkey)
+	if err != nil {
+		return err
+	}
+
+	primary, err := newCertKeyPair(kt, der, pkey)
+	if err != nil {
+		return err
+	}
+
+	var secondary *certKeyPair
+	if skt != "" {
+		// Names have just been authorized, so this order should need no further challenges
+		ctx, cancel := context.WithTimeout(ctx, 1*time.Minute)
+		defer cancel()
+
+		reportStep(ctx, "issuing secondary %s cert", skt)
+		skey, err := generateKey(skt)
+		if err != nil {
+			return err
+		}
+
+		var replaces string
+		if existing.Secondary != nil {
+			replaces = dc.replacesFor(cs, existing, existing.Secondary.Certificate)
+		}
+		der, err := cf.AutoFetchCert(withReplaces(ctx, replaces), skey, certHostnames(hostname, existing.SANs))
+		if err != nil {
+			return err
+		}
+
+		secondary, err = newCertKeyPair(skt, der, skey)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Settings may have been changed while we were issuing, by another instance that doesn't share our
+	// host lock, so keep the latest. The next scan renews again if the new cert doesn't suit them.
+	source := cs
+	latest, err := dc.storage.LoadPath(pathFromHost(hostname))
+	switch err {
+	case nil:
+		existing, source = latest, latest.Source
+	case errCertNotFound:
+		if stored {
+			return errors.New("cert was deleted while a new one was being issued, so not saving it")
+		}
+	default:
+		return err
+	}
+
+	reportStep(ctx, "saving")
+	certType := "admin"
+	if dc.CanDelete(hostname) {
+		certType = "user"
+	}
+
+	err = dc.storage.SavePath(pathFromHost(hostname), &credhubCert{
+		Source:      source,
+		CA:          primary.CA,
+		Type:        certType,
+		Certificate: primary.Certificate,
+		PrivateKey:  primary.PrivateKey,
+		SANs:        existing.SANs,
+		Team:        existing.Team,
+		CSR:         existing.CSR,
+
+		KeyType:          existing.KeyType,
+		SecondaryKeyType: existing.SecondaryKeyType,
+		Secondary:        secondary,
+		RenewalPolicy:    existing.RenewalPolicy,
 	})
 	if err != nil {
 		return err
@@ -376,15 +1205,98 @@ func (dc *daemonConf) getCertAndSave(hostname, cs string, issuer func(context.Co
 	metricIssued.WithLabelValues(cs).Inc()
 
 	// yo, we got a cert
-	dc.updateRequests <- true
+	dc.requestUpdate()
 
 	return nil
 }
 
-func (dc *daemonConf) RenewCertNow(hostname, cs string) error {
-	return dc.getCertAndSave(hostname, cs, func(ctx context.Context, cf certSource, pkey *rsa.PrivateKey) ([][]byte, error) {
-		return cf.AutoFetchCert(ctx, pkey, hostname)
+func (dc *daemonConf) RenewCertNow(hostname, cs, actor string) error {
+	return dc.renewCertNow(dc.workCtx, hostname, cs, actor)
+}
+
+func (dc *daemonConf) renewCertNow(ctx context.Context, hostname, cs, actor string) error {
+	reportStep(ctx, "waiting for other renewals of this host")
+	defer dc.hostLocks.Lock(hostname)()
+
+	return dc.renewCertLocked(ctx, hostname, cs, actor)
+}
+
+// renewCertLocked issues a new cert for hostname from source cs. The caller must hold the host lock.
+func (dc *daemonConf) renewCertLocked(ctx context.Context, hostname, cs, actor string) (err error) {
+	ae := dc.audit.Start(actor, "renew", hostname, cs)
+	defer func() {
+		dc.audit.Finish(ae, err)
+		dc.renewals.Record(hostname, err)
+	}()
+
+	// Carry over any names and settings from the existing cert, if there is one
+	chc, err := dc.storage.LoadPath(pathFromHost(hostname))
+	if err != nil {
+		if err != errCertNotFound {
+			return err
+		}
+		chc = nil
+	}
+
+	var sans []string
+	var replaces string
+	if chc != nil {
+		sans = chc.SANs
+		replaces = dc.replacesFor(cs, chc, chc.Certificate)
+	}
+
+	return dc.getCertAndSave(ctx, hostname, cs, chc, func(ctx context.Context, cf certSource, pkey crypto.Signer) ([][]byte, error) {
+		return cf.AutoFetchCert(withReplaces(ctx, replaces), pkey, certHostnames(hostname, sans))
+	})
+}
+
+func (dc *daemonConf) RenewalState(hostname string) *renewalState {
+	return dc.renewals.Get(hostname)
+}
+
+func (dc *daemonConf) QueueJob(kind, hostname, cs, actor string) (*job, error) {
+	if dc.LeaderElection.IsLeader() {
+		return dc.JobQueue.Enqueue(kind, hostname, cs, actor)
+	}
+
+	j, err := newJob(kind, hostname, cs, actor)
+	if err != nil {
+		return nil, err
+	}
+	err = dc.LeaderElection.handOver(func(lp *leaderPending) {
+		for _, pj := range lp.Jobs {
+			if pj.Hostname == hostname && pj.Kind == kind {
+				j = pj
+				return
+			}
+		}
+		lp.Jobs = append(lp.Jobs, j)
 	})
+	if err != nil {
+		return nil, err
+	}
+	return dc.JobQueue.AddHandedOver(j, dc.LeaderElection.Leader()), nil
+}
+
+func (dc *daemonConf) Job(id string) *job {
+	return dc.JobQueue.Get(id)
+}
+
+func (dc *daemonConf) Jobs() []*job {
+	return dc.JobQueue.List()
+}
+
+func (dc *daemonConf) runJob(ctx context.Context, j *job) error {
+	switch j.Kind {
+	case jobRenew:
+		return dc.renewCertNow(ctx, j.Hostname, j.Source, j.Actor)
+	case jobComplete:
+		return dc.completeChallenge(ctx, j.Hostname, j.Actor)
+	case jobCompromise:
+		return dc.keyCompromise(ctx, j.Hostname, j.Actor)
+	default:
+		return fmt.Errorf("unknown job kind: %s", j.Kind)
+	}
 }
 
 func (dc *daemonConf) periodicScan() error {
@@ -399,8 +1311,19 @@ func (dc *daemonConf) periodicScan() error {
 	if err != nil {
 		return err
 	}
+	dc.updateCertMetrics(certsToDealWith)
+
+	// Forget renewal state for certs that have been deleted
+	var hostnames []string
+	for _, cert := range certsToDealWith {
+		hostnames = append(hostnames, hostFromPath(cert.path))
+	}
+	dc.renewals.Forget(append(hostnames, dc.fixedHosts...))
+
+	// Only the leader orders certs, but responses may have been left behind by any instance
+	dc.responder.SweepChallenges()
 
-	// Now ignore it, and handle our fixed hosts
+	// Now ignore it, and handle our fixed hosts, one at a time as the rest may need them
 	for _, fh := range dc.fixedHosts {
 		err := dc.renewCertIfNeeded(fh)
 		if err != nil {
@@ -409,17 +1332,49 @@ func (dc *daemonConf) periodicScan() error {
 		}
 	}
 
-	// And now handle the rest.
+	// And now handle the rest, with a pool of workers for each source, so that a slow CA doesn't hold up the others.
+	bySource := make(map[string][]string)
 	for _, cert := range certsToDealWith {
 		hn := hostFromPath(cert.path)
 		if !dc.isFixedHost(hn) { // we just did these above
-			err = dc.renewCertIfNeeded(hn)
-			if err != nil {
-				log.Println("error, continuing with others:", err)
-				retErr = err
-			}
+			bySource[cert.Source] = append(bySource[cert.Source], hn)
+		}
+	}
+
+	var wg sync.WaitGroup
+	var errLock sync.Mutex
+	for cs, hostnames := range bySource {
+		workers := dc.current().concurrency[cs]
+		if workers == 0 {
+			workers = 1 // source no longer configured, so renewal will fail, but still report each
+		}
+		if workers > len(hostnames) {
+			workers = len(hostnames)
+		}
+
+		todo := make(chan string, len(hostnames))
+		for _, hn := range hostnames {
+			todo <- hn
+		}
+		close(todo)
+
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for hn := range todo {
+					err := dc.renewCertIfNeeded(hn)
+					if err != nil {
+						log.Printf("error renewing %s, continuing with others: %s\n", hn, err)
+						errLock.Lock()
+						retErr = err
+						errLock.Unlock()
+					}
+				}
+			}()
 		}
 	}
+	wg.Wait()
 
 	return retErr
 }
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// blockingSource issues certs from another source, but each waits until release is closed
+type blockingSource struct {
+	certSource
+	started chan struct{}
+	release chan struct{}
+	issued  int32
+}
+
+func (bs *blockingSource) AutoFetchCert(ctx context.Context, pkey crypto.Signer, hostnames []string) ([][]byte, error) {
+	atomic.AddInt32(&bs.issued, 1)
+	select {
+	case bs.started <- struct{}{}:
+	default:
+	}
+	<-bs.release
+	return bs.certSource.AutoFetchCert(ctx, pkey, hostnames)
+}
+
+// newTestDaemon returns a daemon with a single self-signed source, "self", whose issuing blocks until released
+func newTestDaemon(t *testing.T) (*daemonConf, *blockingSource) {
+	var sm sourceMap
+	err := yaml.Unmarshal([]byte("self: {type: self-signed, key_type: ecdsa-p256}"), &sm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storage := newTestFileStore(t)
+	audit := &auditLog{}
+	err = audit.Init(storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dc := &daemonConf{Period: 3600, DaysBefore: 30}
+	dc.Bootstrap.Source = "self"
+	err = dc.Init("admin.example.gov.au", sm, storage, audit, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(dc.JobQueue.Stop)
+
+	bs := &blockingSource{
+		certSource: dc.srcs.certFactories["self"],
+		started:    make(chan struct{}, 1),
+		release:    make(chan struct{}),
+	}
+	dc.srcs.certFactories["self"] = bs
+	return dc, bs
+}
+
+// TestScanWaitsForJob checks that the scan doesn't issue a cert for a host that a job is already renewing,
+// but waits for it and sees the new cert.
+func TestScanWaitsForJob(t *testing.T) {
+	dc, bs := newTestDaemon(t)
+
+	j, err := dc.QueueJob(jobRenew, "www.example.gov.au", "self", "tester")
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-bs.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the job to start issuing")
+	}
+
+	scanned := make(chan error)
+	go func() {
+		scanned <- dc.renewCertIfNeeded("www.example.gov.au")
+	}()
+	select {
+	case err = <-scanned:
+		t.Fatalf("expected the scan to wait for the job, got %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(bs.release)
+	select {
+	case err = <-scanned:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the scan to finish once the job has")
+	}
+
+	waitForJob(t, &dc.JobQueue, j.ID, jobSucceeded)
+	if n := atomic.LoadInt32(&bs.issued); n != 1 {
+		t.Fatalf("expected one cert to be issued, got %d", n)
+	}
+	chc, err := dc.storage.LoadPath(pathFromHost("www.example.gov.au"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chc.Source != "self" || chc.Certificate == "" {
+		t.Fatalf("expected the job's cert to be saved, got %+v", chc)
+	}
+}
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"crypto/x509"
 	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/govau/cf-common/credhub"
@@ -15,6 +19,42 @@ type certStorage interface {
 	FetchCerts() ([]*credhubCert, error)
 	FetchHostnames() ([]string, error)
 	LoadPath(path string) (*credhubCert, error)
+
+	// LoadHistory returns all versions stored for path, newest first
+	LoadHistory(path string) ([]*credhubCert, error)
+	// LoadVersion returns a specific version, as returned by LoadHistory
+	LoadVersion(path, version string) (*credhubCert, error)
+
+	// SaveData, LoadData and DeleteData store other JSON objects that we need to persist, such as API tokens.
+	// Paths must not start with /certs.
+	SaveData(path string, v interface{}) error
+	LoadData(path string, v interface{}) error
+	DeleteData(path string) error
+	// ListData returns the paths of data items directly under prefix, which must end with "/"
+	ListData(prefix string) ([]string, error)
+
+	// LoadDataVersion is LoadData, also returning the version loaded, for SaveDataIfVersion
+	LoadDataVersion(path string, v interface{}) (string, error)
+	// SaveDataIfVersion saves v only if the version at path is still version, or if nothing is stored there
+	// and version is empty. Otherwise it returns errVersionConflict.
+	SaveDataIfVersion(path, version string, v interface{}) error
+}
+
+// errCertNotFound is returned by certStorage implementations when nothing is stored at a path
+var errCertNotFound = errors.New("cert not found")
+
+// errVersionConflict is returned by SaveDataIfVersion if something else has saved to the path
+var errVersionConflict = errors.New("stored data has changed")
+
+// errCompareAndSetUnsupported is returned by SaveDataIfVersion for storage that can't do it safely
+var errCompareAndSetUnsupported = errors.New("storage can't compare and set, use the directory or vault storage")
+
+// isCommsRelatedError returns true if err looks like a failure to talk to the storage backend
+func isCommsRelatedError(err error) bool {
+	if _, ok := err.(vaultErr); ok {
+		return true
+	}
+	return credhub.IsCommsRelatedError(err)
 }
 
 type credhubCert struct {
@@ -24,9 +64,37 @@ type credhubCert struct {
 	Certificate string         `json:"certificate"`
 	PrivateKey  string         `json:"private_key"`
 	Challenge   *acmeChallenge `json:"challenge"`
+	SANs        []string       `json:"sans"` // additional names, beyond the hostname this is stored under
+	Team        string         `json:"team"` // owning team, set when created if ownership is configured
+
+	KeyType          string       `json:"key_type"`           // if empty, the source default is used
+	SecondaryKeyType string       `json:"secondary_key_type"` // if set, a second cert is issued with this key type
+	Secondary        *certKeyPair `json:"secondary"`
+
+	RenewalPolicy string `json:"renewal_policy"` // if empty, the source default is used
+
+	// CSR is set if the private key is held elsewhere, e.g. by an appliance that won't export it.
+	// Certs are issued for it, and PrivateKey is left empty.
+	CSR string `json:"csr,omitempty"`
+
+	// Revoked is set once the primary cert has been revoked, until the certs are replaced.
+	// See Secondary.Revoked for the secondary cert.
+	Revoked *revocation `json:"revoked,omitempty"`
 
 	path        string    // set for convenience of callers, but not stored
 	dateCreated time.Time // set by CredHub automatically, set by us when pulling out
+	version     string    // opaque ID of this version, set by us when pulling out
+}
+
+// certKeyPair is an additional cert for the same names, with a different type of key
+type certKeyPair struct {
+	KeyType     string `json:"key_type"`
+	CA          string `json:"ca"`
+	Certificate string `json:"certificate"`
+	PrivateKey  string `json:"private_key"`
+
+	// Revoked is set once this cert has been revoked, which is after the primary one is
+	Revoked *revocation `json:"revoked,omitempty"`
 }
 
 func pathFromHost(hostname string) string {
@@ -44,14 +112,70 @@ func hostFromPath(path string) string {
 	return string(b)
 }
 
+// certHostnames returns the names a certificate for hostname should cover, with hostname
+// first (and used as the common name), followed by any additional SANs, de-duplicated.
+func certHostnames(hostname string, sans []string) []string {
+	rv := []string{hostname}
+	seen := map[string]bool{hostname: true}
+	for _, san := range sans {
+		if seen[san] {
+			continue
+		}
+		seen[san] = true
+		rv = append(rv, san)
+	}
+	return rv
+}
+
+// parseHostnames splits user input on whitespace and commas, and normalises each name found
+func parseHostnames(s string) []string {
+	var rv []string
+	for _, hn := range strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\r' || r == '\n'
+	}) {
+		rv = append(rv, strings.TrimSuffix(strings.ToLower(hn), "."))
+	}
+	return rv
+}
+
+// parseCertificatePEM returns the first certificate in s
+func parseCertificatePEM(s string) (*x509.Certificate, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, errors.New("no cert found in pem")
+	}
+	if block.Type != "CERTIFICATE" || len(block.Headers) != 0 {
+		return nil, errors.New("invalid cert found in pem")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// certCoversHostnames returns true if every one of hostnames is listed exactly in the cert's DNS names
+func certCoversHostnames(pc *x509.Certificate, hostnames []string) bool {
+	have := make(map[string]bool)
+	for _, hn := range pc.DNSNames {
+		have[strings.ToLower(hn)] = true
+	}
+	for _, hn := range hostnames {
+		if !have[hn] {
+			return false
+		}
+	}
+	return true
+}
+
 type certStore struct {
 	CredHub *credhub.Client
 }
 
 func (cs *certStore) DeletePath(path string) error {
-	return cs.CredHub.DeleteRequest("/api/v1/data", url.Values{
+	err := cs.CredHub.DeleteRequest("/api/v1/data", url.Values{
 		"name": {path},
 	})
+	if credhub.IsNotFoundError(err) {
+		return errCertNotFound
+	}
+	return err
 }
 
 func (cs *certStore) SavePath(path string, chc *credhubCert) error {
@@ -115,18 +239,125 @@ func (cs *certStore) FetchHostnames() ([]string, error) {
 	return rv, nil
 }
 
-func (cs *certStore) LoadPath(path string) (*credhubCert, error) {
-	var cr2 struct {
+func (cs *certStore) SaveData(path string, v interface{}) error {
+	var ignoreMe map[string]interface{}
+	return cs.CredHub.PutRequest("/api/v1/data", struct {
+		Name  string      `json:"name"`
+		Type  string      `json:"type"`
+		Value interface{} `json:"value"`
+	}{
+		Name:  path,
+		Type:  "json",
+		Value: v,
+	}, &ignoreMe)
+}
+
+func (cs *certStore) LoadData(path string, v interface{}) error {
+	var cr struct {
+		Data []struct {
+			Value json.RawMessage `json:"value"`
+		} `json:"data"`
+	}
+	err := cs.CredHub.MakeRequest("/api/v1/data", url.Values{
+		"name":    {path},
+		"current": {"true"},
+	}, &cr)
+	if err != nil {
+		if credhub.IsNotFoundError(err) {
+			return errCertNotFound
+		}
+		return err
+	}
+
+	if len(cr.Data) != 1 {
+		return errors.New("bad data from credhub")
+	}
+
+	return json.Unmarshal(cr.Data[0].Value, v)
+}
+
+func (cs *certStore) LoadDataVersion(path string, v interface{}) (string, error) {
+	var cr struct {
 		Data []struct {
-			Value       credhubCert `json:"value"`
-			DateCreated time.Time   `json:"version_created_at"`
+			ID    string          `json:"id"`
+			Value json.RawMessage `json:"value"`
 		} `json:"data"`
 	}
+	err := cs.CredHub.MakeRequest("/api/v1/data", url.Values{
+		"name":    {path},
+		"current": {"true"},
+	}, &cr)
+	if err != nil {
+		if credhub.IsNotFoundError(err) {
+			return "", errCertNotFound
+		}
+		return "", err
+	}
+	if len(cr.Data) != 1 {
+		return "", errors.New("bad data from credhub")
+	}
+	return cr.Data[0].ID, json.Unmarshal(cr.Data[0].Value, v)
+}
+
+// SaveDataIfVersion always fails, as CredHub can't compare and set, and writing first then checking
+// would leave the loser's write in place
+func (cs *certStore) SaveDataIfVersion(path, version string, v interface{}) error {
+	return errCompareAndSetUnsupported
+}
+
+func (cs *certStore) DeleteData(path string) error {
+	return cs.DeletePath(path)
+}
+
+func (cs *certStore) ListData(prefix string) ([]string, error) {
+	var cr struct {
+		Credentials []cred `json:"credentials"`
+	}
+	err := cs.CredHub.MakeRequest("/api/v1/data", url.Values{
+		"path": {prefix},
+	}, &cr)
+	if err != nil {
+		return nil, err
+	}
+
+	// CredHub finds everything below the path, we only want its children
+	var rv []string
+	for _, c := range cr.Credentials {
+		if strings.HasPrefix(c.Name, prefix) && !strings.Contains(c.Name[len(prefix):], "/") {
+			rv = append(rv, c.Name)
+		}
+	}
+	return rv, nil
+}
+
+// credhubVersion is a single version of a credential as returned by CredHub
+type credhubVersion struct {
+	ID          string      `json:"id"`
+	Name        string      `json:"name"`
+	Value       credhubCert `json:"value"`
+	DateCreated time.Time   `json:"version_created_at"`
+}
+
+func (cv *credhubVersion) cert(path string) *credhubCert {
+	rv := cv.Value
+	rv.path = path
+	rv.dateCreated = cv.DateCreated
+	rv.version = cv.ID
+	return &rv
+}
+
+func (cs *certStore) LoadPath(path string) (*credhubCert, error) {
+	var cr2 struct {
+		Data []credhubVersion `json:"data"`
+	}
 	err := cs.CredHub.MakeRequest("/api/v1/data", url.Values{
 		"name":    {path},
 		"current": {"true"},
 	}, &cr2)
 	if err != nil {
+		if credhub.IsNotFoundError(err) {
+			return nil, errCertNotFound
+		}
 		return nil, err
 	}
 
@@ -134,9 +365,46 @@ func (cs *certStore) LoadPath(path string) (*credhubCert, error) {
 		return nil, errors.New("bad data from credhub")
 	}
 
-	rv := cr2.Data[0].Value
-	rv.path = path
-	rv.dateCreated = cr2.Data[0].DateCreated
+	return cr2.Data[0].cert(path), nil
+}
+
+func (cs *certStore) LoadHistory(path string) ([]*credhubCert, error) {
+	// Without current=true, CredHub returns all versions, newest first
+	var cr2 struct {
+		Data []credhubVersion `json:"data"`
+	}
+	err := cs.CredHub.MakeRequest("/api/v1/data", url.Values{
+		"name": {path},
+	}, &cr2)
+	if err != nil {
+		if credhub.IsNotFoundError(err) {
+			return nil, errCertNotFound
+		}
+		return nil, err
+	}
+
+	rv := make([]*credhubCert, len(cr2.Data))
+	for i := range cr2.Data {
+		rv[i] = cr2.Data[i].cert(path)
+	}
+
+	return rv, nil
+}
+
+func (cs *certStore) LoadVersion(path, version string) (*credhubCert, error) {
+	var cv credhubVersion
+	err := cs.CredHub.MakeRequest("/api/v1/data/"+url.PathEscape(version), nil, &cv)
+	if err != nil {
+		if credhub.IsNotFoundError(err) {
+			return nil, errCertNotFound
+		}
+		return nil, err
+	}
+
+	// Make sure we're not handed a version of some other credential
+	if cv.Name != path {
+		return nil, errCertNotFound
+	}
 
-	return &rv, nil
+	return cv.cert(path), nil
 }
@@ -0,0 +1,701 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// fileStoreSaltName is a file in the directory with a random salt, used to derive the key from encryption_key
+	fileStoreSaltName = "salt"
+	fileStoreSaltSize = 16
+
+	// fileStoreLockName is a file in the directory that is locked while writing, in case other instances share it
+	fileStoreLockName = "lock"
+
+	// scrypt parameters, as recommended for interactive logins. The key is only derived at startup.
+	fileStoreScryptN = 1 << 15
+	fileStoreScryptR = 8
+	fileStoreScryptP = 1
+)
+
+// fileStore is a certStorage that keeps each version of each cert as an encrypted JSON file
+// in a directory, e.g. <dir>/certs/<hex hostname>/<version>.enc
+// Other data is kept without history, e.g. <dir>/data/<hex path>.enc
+// The key is derived from EncryptionKey with scrypt, and a random salt kept in <dir>/salt.
+type fileStore struct {
+	Path          string `yaml:"path"`
+	EncryptionKey string `yaml:"encryption_key"` // should be long and random, e.g. a generated 40 character password
+	MaxVersions   int    `yaml:"max_versions"`   // older versions are removed beyond this, 0 keeps all
+
+	lock sync.Mutex
+	aead cipher.AEAD
+}
+
+// errFileCompareAndSetUnsupported is returned by SaveDataIfVersion on platforms without shared file locks
+var errFileCompareAndSetUnsupported = errors.New("directory storage can't compare and set on this platform, use vault storage")
+
+// fileCertVersion is what we encrypt and store
+type fileCertVersion struct {
+	Created time.Time    `json:"created"`
+	Value   *credhubCert `json:"value"`
+}
+
+func (fs *fileStore) Init() error {
+	if fs.EncryptionKey == "" {
+		return errors.New("encryption key must be specified for directory storage")
+	}
+
+	for _, d := range []string{"certs", "data"} {
+		err := os.MkdirAll(filepath.Join(fs.Path, d), 0700)
+		if err != nil {
+			return err
+		}
+	}
+
+	salt, err := fs.loadOrCreateSalt()
+	if err != nil {
+		return err
+	}
+	key, err := scrypt.Key([]byte(fs.EncryptionKey), salt, fileStoreScryptN, fileStoreScryptR, fileStoreScryptP, 32)
+	if err != nil {
+		return err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	fs.aead, err = cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// loadOrCreateSalt returns the salt kept in the directory, creating it if this is a new directory.
+// If other instances share the directory, only one creates it.
+func (fs *fileStore) loadOrCreateSalt() ([]byte, error) {
+	filename := filepath.Join(fs.Path, fileStoreSaltName)
+	salt, err := ioutil.ReadFile(filename)
+	switch {
+	case err == nil:
+		if len(salt) != fileStoreSaltSize {
+			return nil, fmt.Errorf("%s is not a valid salt, as it must be %d bytes", filename, fileStoreSaltSize)
+		}
+		return salt, nil
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+
+	// Data written with another salt can't be read, so don't create one alongside any
+	for _, d := range []string{"certs", "data"} {
+		fis, err := ioutil.ReadDir(filepath.Join(fs.Path, d))
+		if err != nil {
+			return nil, err
+		}
+		if len(fis) != 0 {
+			return nil, fmt.Errorf("%s is missing, but there is already data in %s", filename, fs.Path)
+		}
+	}
+
+	salt = make([]byte, fileStoreSaltSize)
+	_, err = rand.Read(salt)
+	if err != nil {
+		return nil, err
+	}
+	err = writeFileExclusively(filename, salt)
+	if err != nil {
+		if os.IsExist(err) {
+			// Another instance got there first
+			return fs.loadOrCreateSalt()
+		}
+		return nil, err
+	}
+	return salt, nil
+}
+
+// lockDir takes the lock held while writing, which other processes sharing the directory also respect where
+// the platform supports it. Must be called with lock held.
+func (fs *fileStore) lockDir() (func(), error) {
+	f, err := os.OpenFile(filepath.Join(fs.Path, fileStoreLockName), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := lockFile(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		unlock()
+		f.Close()
+	}, nil
+}
+
+// dirForPath returns the directory holding versions for a storage path such as /certs/<hex>
+func (fs *fileStore) dirForPath(path string) (string, error) {
+	if hostFromPath(path) == "" || strings.Contains(path[len("/certs/"):], "/") {
+		return "", errors.New("invalid cert path")
+	}
+	return filepath.Join(fs.Path, filepath.FromSlash(path)), nil
+}
+
+// versions returns the version numbers found in dir, oldest first
+func (fs *fileStore) versions(dir string) ([]int, error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errCertNotFound
+		}
+		return nil, err
+	}
+
+	var rv []int
+	for _, fi := range fis {
+		if !strings.HasSuffix(fi.Name(), ".enc") {
+			continue // e.g. temp files
+		}
+		v, err := strconv.Atoi(strings.TrimSuffix(fi.Name(), ".enc"))
+		if err != nil {
+			continue
+		}
+		rv = append(rv, v)
+	}
+	if len(rv) == 0 {
+		return nil, errCertNotFound
+	}
+
+	sort.Ints(rv)
+	return rv, nil
+}
+
+func versionFilename(dir string, v int) string {
+	return filepath.Join(dir, strconv.Itoa(v)+".enc")
+}
+
+func (fs *fileStore) DeletePath(path string) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	dir, err := fs.dirForPath(path)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := fs.lockDir()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	_, err = fs.versions(dir)
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(dir)
+}
+
+// seal encrypts and binds the ciphertext to the path so that files can't be swapped between hosts
+func (fs *fileStore) seal(path string, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, fs.aead.NonceSize())
+	_, err := io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return fs.aead.Seal(nonce, nonce, plaintext, []byte(path)), nil
+}
+
+func (fs *fileStore) open(path string, data []byte) ([]byte, error) {
+	ns := fs.aead.NonceSize()
+	if len(data) < ns {
+		return nil, errors.New("bad data in file")
+	}
+	plaintext, err := fs.aead.Open(nil, data[:ns], data[ns:], []byte(path))
+	if err != nil {
+		return nil, errors.New("unable to decrypt file, check encryption key")
+	}
+	return plaintext, nil
+}
+
+func (fs *fileStore) SavePath(path string, chc *credhubCert) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	dir, err := fs.dirForPath(path)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := fs.lockDir()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	next := 1
+	existing, err := fs.versions(dir)
+	switch err {
+	case nil:
+		next = existing[len(existing)-1] + 1
+	case errCertNotFound:
+		err = os.MkdirAll(dir, 0700)
+		if err != nil {
+			return err
+		}
+	default:
+		return err
+	}
+
+	plaintext, err := json.Marshal(&fileCertVersion{
+		Created: time.Now().UTC(),
+		Value:   chc,
+	})
+	if err != nil {
+		return err
+	}
+
+	ciphertext, err := fs.seal(path, plaintext)
+	if err != nil {
+		return err
+	}
+
+	// Never replaces a version, even if another instance got past the lock somehow
+	err = writeFileExclusively(versionFilename(dir, next), ciphertext)
+	if err != nil {
+		return err
+	}
+
+	if fs.MaxVersions > 0 {
+		existing = append(existing, next)
+		for len(existing) > fs.MaxVersions {
+			err = os.Remove(versionFilename(dir, existing[0]))
+			if err != nil {
+				return err
+			}
+			existing = existing[1:]
+		}
+	}
+
+	return nil
+}
+
+// writeTempFile writes data to a new temp file in dir, returning its name
+func writeTempFile(dir string, data []byte) (string, error) {
+	f, err := ioutil.TempFile(dir, ".tmp-")
+	if err != nil {
+		return "", err
+	}
+	tmpName := f.Name()
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpName)
+		return "", err
+	}
+	return tmpName, nil
+}
+
+// writeFileAtomically writes to a temp file in the same directory, then renames it into place
+func writeFileAtomically(filename string, data []byte) error {
+	dir := filepath.Dir(filename)
+	tmpName, err := writeTempFile(dir, data)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmpName, filename)
+	if err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+// writeFileExclusively is writeFileAtomically, but fails if filename already exists, by linking the temp file
+// into place rather than renaming it
+func writeFileExclusively(filename string, data []byte) error {
+	dir := filepath.Dir(filename)
+	tmpName, err := writeTempFile(dir, data)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpName)
+
+	err = os.Link(tmpName, filename)
+	if err != nil {
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+// syncDir makes sure that changes to the entries in dir are persisted
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	d.Close()
+	return err
+}
+
+func (fs *fileStore) loadVersion(path, dir string, v int) (*credhubCert, error) {
+	data, err := ioutil.ReadFile(versionFilename(dir, v))
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := fs.open(path, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var fcv fileCertVersion
+	err = json.Unmarshal(plaintext, &fcv)
+	if err != nil {
+		return nil, err
+	}
+	if fcv.Value == nil {
+		return nil, errors.New("bad data in cert file")
+	}
+
+	rv := fcv.Value
+	rv.path = path
+	rv.dateCreated = fcv.Created
+	rv.version = strconv.Itoa(v)
+
+	return rv, nil
+}
+
+func (fs *fileStore) LoadPath(path string) (*credhubCert, error) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	dir, err := fs.dirForPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	vs, err := fs.versions(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return fs.loadVersion(path, dir, vs[len(vs)-1])
+}
+
+func (fs *fileStore) LoadHistory(path string) ([]*credhubCert, error) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	dir, err := fs.dirForPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	vs, err := fs.versions(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := make([]*credhubCert, len(vs))
+	for i, v := range vs {
+		rv[len(vs)-1-i], err = fs.loadVersion(path, dir, v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rv, nil
+}
+
+func (fs *fileStore) LoadVersion(path, version string) (*credhubCert, error) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	dir, err := fs.dirForPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := strconv.Atoi(version)
+	if err != nil {
+		return nil, errCertNotFound
+	}
+
+	rv, err := fs.loadVersion(path, dir, v)
+	if os.IsNotExist(err) {
+		return nil, errCertNotFound
+	}
+	return rv, err
+}
+
+// paths returns the storage path of every cert
+func (fs *fileStore) paths() ([]string, error) {
+	fis, err := ioutil.ReadDir(filepath.Join(fs.Path, "certs"))
+	if err != nil {
+		return nil, err
+	}
+
+	var rv []string
+	for _, fi := range fis {
+		if !fi.IsDir() {
+			continue
+		}
+		path := "/certs/" + fi.Name()
+		if hostFromPath(path) == "" {
+			continue
+		}
+		rv = append(rv, path)
+	}
+	return rv, nil
+}
+
+func (fs *fileStore) FetchCerts() ([]*credhubCert, error) {
+	paths, err := fs.paths()
+	if err != nil {
+		return nil, err
+	}
+
+	var rv []*credhubCert
+	for _, path := range paths {
+		chc, err := fs.LoadPath(path)
+		if err != nil {
+			if err == errCertNotFound {
+				continue // deleted since we listed
+			}
+			return nil, err
+		}
+		rv = append(rv, chc)
+	}
+
+	return rv, nil
+}
+
+func (fs *fileStore) FetchHostnames() ([]string, error) {
+	paths, err := fs.paths()
+	if err != nil {
+		return nil, err
+	}
+
+	rv := make([]string, len(paths))
+	for i, path := range paths {
+		rv[i] = hostFromPath(path)
+	}
+
+	return rv, nil
+}
+
+// dataFilename returns the file used for a non-cert path
+func (fs *fileStore) dataFilename(path string) (string, error) {
+	if path == "" || strings.HasPrefix(path, "/certs/") {
+		return "", errors.New("invalid data path")
+	}
+	return filepath.Join(fs.Path, "data", hex.EncodeToString([]byte(path))+".enc"), nil
+}
+
+func (fs *fileStore) SaveData(path string, v interface{}) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	filename, err := fs.dataFilename(path)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := fs.lockDir()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	plaintext, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	ciphertext, err := fs.seal(path, plaintext)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomically(filename, ciphertext)
+}
+
+func (fs *fileStore) LoadData(path string, v interface{}) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	filename, err := fs.dataFilename(path)
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errCertNotFound
+		}
+		return err
+	}
+
+	plaintext, err := fs.open(path, data)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(plaintext, v)
+}
+
+func (fs *fileStore) LoadDataVersion(path string, v interface{}) (string, error) {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	filename, err := fs.dataFilename(path)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", errCertNotFound
+		}
+		return "", err
+	}
+
+	plaintext, err := fs.open(path, data)
+	if err != nil {
+		return "", err
+	}
+
+	return dataFileVersion(data), json.Unmarshal(plaintext, v)
+}
+
+// dataFileVersion identifies the contents of a data file. Each save uses a fresh nonce, so it always changes.
+func dataFileVersion(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+// SaveDataIfVersion relies on the directory lock, in case other instances share the directory, so it
+// fails on platforms where other processes don't respect it
+func (fs *fileStore) SaveDataIfVersion(path, version string, v interface{}) error {
+	if !fileLocksShared {
+		return errFileCompareAndSetUnsupported
+	}
+
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	filename, err := fs.dataFilename(path)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := fs.lockDir()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current := ""
+	data, err := ioutil.ReadFile(filename)
+	switch {
+	case err == nil:
+		current = dataFileVersion(data)
+	case !os.IsNotExist(err):
+		return err
+	}
+	if current != version {
+		return errVersionConflict
+	}
+
+	plaintext, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	ciphertext, err := fs.seal(path, plaintext)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomically(filename, ciphertext)
+}
+
+func (fs *fileStore) DeleteData(path string) error {
+	fs.lock.Lock()
+	defer fs.lock.Unlock()
+
+	filename, err := fs.dataFilename(path)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := fs.lockDir()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = os.Remove(filename)
+	if os.IsNotExist(err) {
+		return errCertNotFound
+	}
+	return err
+}
+
+func (fs *fileStore) ListData(prefix string) ([]string, error) {
+	fis, err := ioutil.ReadDir(filepath.Join(fs.Path, "data"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var rv []string
+	for _, fi := range fis {
+		name := fi.Name()
+		if fi.IsDir() || !strings.HasSuffix(name, ".enc") {
+			continue
+		}
+		path, err := hex.DecodeString(strings.TrimSuffix(name, ".enc"))
+		if err != nil {
+			continue // not one of ours
+		}
+		if strings.HasPrefix(string(path), prefix) && !strings.Contains(string(path[len(prefix):]), "/") {
+			rv = append(rv, string(path))
+		}
+	}
+	return rv, nil
+}
@@ -0,0 +1,17 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package main
+
+import (
+	"os"
+)
+
+// fileLocksShared is false as we can't lock files here in a way that other processes respect, so only
+// one instance may use a directory, and it can't compare and set
+const fileLocksShared = false
+
+// lockFile does nothing, see fileLocksShared
+func lockFile(f *os.File) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,23 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileLocksShared is true as lockFile's locks are respected by other processes
+const fileLocksShared = true
+
+// lockFile takes an exclusive lock on f, which other processes also respect, returning a func to release it
+func lockFile(f *os.File) (func(), error) {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	}, nil
+}
@@ -0,0 +1,481 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// vaultStore is a certStorage that keeps certs in a HashiCorp Vault KV version 2 secrets engine
+type vaultStore struct {
+	Address   string   `yaml:"address"` // e.g. https://vault.example.com:8200
+	CACerts   []string `yaml:"ca_certificates"`
+	Namespace string   `yaml:"namespace"` // optional, Vault Enterprise only
+	Mount     string   `yaml:"mount"`     // KV v2 mount, defaults to "secret"
+	Prefix    string   `yaml:"prefix"`    // optional, prepended to all paths, e.g. "le-responder"
+
+	// Either a token, or AppRole credentials must be specified
+	Token   string `yaml:"token"`
+	AppRole struct {
+		Mount    string `yaml:"mount"` // defaults to "approle"
+		RoleID   string `yaml:"role_id"`
+		SecretID string `yaml:"secret_id"`
+	} `yaml:"approle"`
+
+	httpClient *http.Client
+
+	tokenMutex  sync.Mutex
+	token       string
+	tokenExpiry time.Time // zero if it doesn't expire
+}
+
+// vaultErr is returned for problems talking to Vault, as opposed to data not being found
+type vaultErr struct {
+	error
+}
+
+// vaultResponseErr is an unexpected status from Vault, with the errors it gave
+type vaultResponseErr struct {
+	StatusCode int
+	Errors     []string
+}
+
+func (e *vaultResponseErr) Error() string {
+	return fmt.Sprintf("not OK response from Vault: %d: %s", e.StatusCode, strings.Join(e.Errors, ", "))
+}
+
+// isCASMismatch returns true if err is Vault refusing a write as the check-and-set version didn't match
+func isCASMismatch(err error) bool {
+	ve, ok := err.(vaultErr)
+	if !ok {
+		return false
+	}
+	re, ok := ve.error.(*vaultResponseErr)
+	if !ok || re.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	for _, e := range re.Errors {
+		if strings.HasPrefix(e, "check-and-set parameter did not match") {
+			return true
+		}
+	}
+	return false
+}
+
+func (vs *vaultStore) Init() error {
+	vs.Address = strings.TrimSuffix(vs.Address, "/")
+	if vs.Mount == "" {
+		vs.Mount = "secret"
+	}
+	vs.Mount = strings.Trim(vs.Mount, "/")
+	vs.Prefix = strings.Trim(vs.Prefix, "/")
+	if vs.AppRole.Mount == "" {
+		vs.AppRole.Mount = "approle"
+	}
+
+	if vs.Token == "" && vs.AppRole.RoleID == "" {
+		return errors.New("vault token or approle role id must be specified")
+	}
+
+	if len(vs.CACerts) == 0 {
+		vs.httpClient = http.DefaultClient
+	} else {
+		pool := x509.NewCertPool()
+		for _, ca := range vs.CACerts {
+			ok := pool.AppendCertsFromPEM([]byte(ca))
+			if !ok {
+				return errors.New("AppendCertsFromPEM was not ok")
+			}
+		}
+		vs.httpClient = &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	}
+
+	return nil
+}
+
+// getToken returns a valid token, logging in with AppRole if needed
+func (vs *vaultStore) getToken(forceLogin bool) (string, error) {
+	if vs.Token != "" {
+		return vs.Token, nil
+	}
+
+	vs.tokenMutex.Lock()
+	defer vs.tokenMutex.Unlock()
+
+	if !forceLogin && vs.token != "" && (vs.tokenExpiry.IsZero() || time.Now().Add(5*time.Minute).Before(vs.tokenExpiry)) {
+		return vs.token, nil
+	}
+
+	var resp struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int    `json:"lease_duration"`
+		} `json:"auth"`
+	}
+	status, err := vs.rawRequest(http.MethodPost, "/v1/auth/"+vs.AppRole.Mount+"/login", "", map[string]string{
+		"role_id":   vs.AppRole.RoleID,
+		"secret_id": vs.AppRole.SecretID,
+	}, &resp)
+	if err != nil {
+		return "", err
+	}
+	if status != http.StatusOK || resp.Auth.ClientToken == "" {
+		return "", vaultErr{fmt.Errorf("unable to login to vault with approle, status: %d", status)}
+	}
+
+	vs.token = resp.Auth.ClientToken
+	vs.tokenExpiry = time.Time{}
+	if resp.Auth.LeaseDuration > 0 {
+		vs.tokenExpiry = time.Now().Add(time.Duration(resp.Auth.LeaseDuration) * time.Second)
+	}
+
+	return vs.token, nil
+}
+
+// rawRequest makes a request to Vault, decoding a JSON response into rv if there is one, and returning the status code.
+func (vs *vaultStore) rawRequest(method, path, token string, body, rv interface{}) (int, error) {
+	var data []byte
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	req, err := http.NewRequest(method, vs.Address+path, bytes.NewReader(data))
+	if err != nil {
+		return 0, vaultErr{err}
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if vs.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", vs.Namespace)
+	}
+
+	resp, err := vs.httpClient.Do(req)
+	if err != nil {
+		return 0, vaultErr{err}
+	}
+	contents, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return 0, vaultErr{err}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if rv != nil {
+			err = json.Unmarshal(contents, rv)
+			if err != nil {
+				return 0, vaultErr{err}
+			}
+		}
+	case http.StatusNoContent, http.StatusNotFound, http.StatusForbidden:
+		// caller to deal with
+	default:
+		re := &vaultResponseErr{StatusCode: resp.StatusCode}
+		var body struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(contents, &body) == nil && len(body.Errors) != 0 {
+			re.Errors = body.Errors
+		} else {
+			re.Errors = []string{string(contents)}
+		}
+		return 0, vaultErr{re}
+	}
+
+	return resp.StatusCode, nil
+}
+
+// request makes an authenticated request, mapping not found to errCertNotFound
+func (vs *vaultStore) request(method, path string, body, rv interface{}) error {
+	token, err := vs.getToken(false)
+	if err != nil {
+		return err
+	}
+
+	status, err := vs.rawRequest(method, path, token, body, rv)
+	if err != nil {
+		return err
+	}
+
+	// our token may have been revoked, try again with a new one
+	if status == http.StatusForbidden && vs.Token == "" {
+		token, err = vs.getToken(true)
+		if err != nil {
+			return err
+		}
+		status, err = vs.rawRequest(method, path, token, body, rv)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch status {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		return errCertNotFound
+	default:
+		return vaultErr{fmt.Errorf("permission denied by vault for: %s", path)}
+	}
+}
+
+// apiPath returns the API path for a storage path such as /certs/<hex>, kind is "data" or "metadata"
+func (vs *vaultStore) apiPath(kind, path string) string {
+	rv := "/v1/" + vs.Mount + "/" + kind
+	if vs.Prefix != "" {
+		rv += "/" + vs.Prefix
+	}
+	return rv + path
+}
+
+func (vs *vaultStore) DeletePath(path string) error {
+	// Deleting the metadata removes all versions, as CredHub does
+	return vs.request(http.MethodDelete, vs.apiPath("metadata", path), nil, nil)
+}
+
+func (vs *vaultStore) SavePath(path string, chc *credhubCert) error {
+	return vs.request(http.MethodPost, vs.apiPath("data", path), struct {
+		Data *credhubCert `json:"data"`
+	}{
+		Data: chc,
+	}, nil)
+}
+
+func (vs *vaultStore) listPaths() ([]string, error) {
+	var resp struct {
+		Data struct {
+			Keys []string `json:"keys"`
+		} `json:"data"`
+	}
+	err := vs.request("LIST", vs.apiPath("metadata", "/certs"), nil, &resp)
+	if err != nil {
+		if err == errCertNotFound {
+			return nil, nil // nothing stored yet
+		}
+		return nil, err
+	}
+
+	var rv []string
+	for _, k := range resp.Data.Keys {
+		if strings.HasSuffix(k, "/") {
+			continue // not one of ours
+		}
+		rv = append(rv, "/certs/"+k)
+	}
+	return rv, nil
+}
+
+func (vs *vaultStore) FetchCerts() ([]*credhubCert, error) {
+	paths, err := vs.listPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	var rv []*credhubCert
+	for _, path := range paths {
+		chc, err := vs.LoadPath(path)
+		if err != nil {
+			if err == errCertNotFound {
+				continue // latest version deleted, or deleted since we listed
+			}
+			return nil, err
+		}
+		rv = append(rv, chc)
+	}
+
+	return rv, nil
+}
+
+func (vs *vaultStore) FetchHostnames() ([]string, error) {
+	paths, err := vs.listPaths()
+	if err != nil {
+		return nil, err
+	}
+
+	rv := make([]string, len(paths))
+	for i, path := range paths {
+		rv[i] = hostFromPath(path)
+	}
+
+	return rv, nil
+}
+
+func (vs *vaultStore) LoadPath(path string) (*credhubCert, error) {
+	return vs.loadData(path, vs.apiPath("data", path))
+}
+
+func (vs *vaultStore) loadData(path, apiPath string) (*credhubCert, error) {
+	var resp struct {
+		Data struct {
+			Data     *credhubCert `json:"data"`
+			Metadata struct {
+				CreatedTime time.Time `json:"created_time"`
+				Version     int       `json:"version"`
+			} `json:"metadata"`
+		} `json:"data"`
+	}
+	err := vs.request(http.MethodGet, apiPath, nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Data.Data == nil {
+		return nil, errors.New("bad data from vault")
+	}
+
+	rv := resp.Data.Data
+	rv.path = path
+	rv.dateCreated = resp.Data.Metadata.CreatedTime
+	rv.version = strconv.Itoa(resp.Data.Metadata.Version)
+
+	return rv, nil
+}
+
+func (vs *vaultStore) LoadHistory(path string) ([]*credhubCert, error) {
+	var resp struct {
+		Data struct {
+			Versions map[string]struct {
+				DeletionTime string `json:"deletion_time"`
+				Destroyed    bool   `json:"destroyed"`
+			} `json:"versions"`
+		} `json:"data"`
+	}
+	err := vs.request(http.MethodGet, vs.apiPath("metadata", path), nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []int
+	for k, v := range resp.Data.Versions {
+		if v.Destroyed || v.DeletionTime != "" {
+			continue
+		}
+		n, err := strconv.Atoi(k)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, n)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+
+	rv := make([]*credhubCert, len(versions))
+	for i, v := range versions {
+		rv[i], err = vs.LoadVersion(path, strconv.Itoa(v))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rv, nil
+}
+
+func (vs *vaultStore) LoadVersion(path, version string) (*credhubCert, error) {
+	v, err := strconv.Atoi(version)
+	if err != nil {
+		return nil, errCertNotFound
+	}
+	return vs.loadData(path, fmt.Sprintf("%s?version=%d", vs.apiPath("data", path), v))
+}
+
+func (vs *vaultStore) SaveData(path string, v interface{}) error {
+	return vs.request(http.MethodPost, vs.apiPath("data", path), map[string]interface{}{
+		"data": v,
+	}, nil)
+}
+
+func (vs *vaultStore) LoadData(path string, v interface{}) error {
+	var resp struct {
+		Data struct {
+			Data json.RawMessage `json:"data"`
+		} `json:"data"`
+	}
+	err := vs.request(http.MethodGet, vs.apiPath("data", path), nil, &resp)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(resp.Data.Data, v)
+}
+
+func (vs *vaultStore) LoadDataVersion(path string, v interface{}) (string, error) {
+	var resp struct {
+		Data struct {
+			Data     json.RawMessage `json:"data"`
+			Metadata struct {
+				Version int `json:"version"`
+			} `json:"metadata"`
+		} `json:"data"`
+	}
+	err := vs.request(http.MethodGet, vs.apiPath("data", path), nil, &resp)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(resp.Data.Metadata.Version), json.Unmarshal(resp.Data.Data, v)
+}
+
+// SaveDataIfVersion uses the KV check-and-set option, where version 0 means only if nothing is stored
+func (vs *vaultStore) SaveDataIfVersion(path, version string, v interface{}) error {
+	cas := 0
+	if version != "" {
+		var err error
+		cas, err = strconv.Atoi(version)
+		if err != nil {
+			return errVersionConflict
+		}
+	}
+	err := vs.request(http.MethodPost, vs.apiPath("data", path), map[string]interface{}{
+		"options": map[string]interface{}{
+			"cas": cas,
+		},
+		"data": v,
+	}, nil)
+	if isCASMismatch(err) {
+		return errVersionConflict
+	}
+	return err
+}
+
+func (vs *vaultStore) DeleteData(path string) error {
+	return vs.DeletePath(path)
+}
+
+func (vs *vaultStore) ListData(prefix string) ([]string, error) {
+	var resp struct {
+		Data struct {
+			Keys []string `json:"keys"`
+		} `json:"data"`
+	}
+	err := vs.request("LIST", vs.apiPath("metadata", strings.TrimSuffix(prefix, "/")), nil, &resp)
+	if err != nil {
+		if err == errCertNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var rv []string
+	for _, k := range resp.Data.Keys {
+		if strings.HasSuffix(k, "/") {
+			continue // has children of its own
+		}
+		rv = append(rv, prefix+k)
+	}
+	return rv, nil
+}
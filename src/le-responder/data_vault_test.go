@@ -0,0 +1,396 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testVaultEpoch is when the stand-in says the first version of everything was created
+var testVaultEpoch = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// testVault stands in for the parts of a Vault server with a KV v2 mount at "secret" that we use,
+// like `vault server -dev`, but also with an AppRole login
+type testVault struct {
+	lock   sync.Mutex
+	data   map[string][]json.RawMessage // by path under the mount, every version oldest first
+	tokens map[string]bool
+	logins int
+	roleID string
+	secret string
+}
+
+func newTestVault(t *testing.T) (*testVault, string) {
+	tv := &testVault{
+		data:   make(map[string][]json.RawMessage),
+		tokens: map[string]bool{"root-token": true},
+		roleID: "role",
+		secret: "secret-id",
+	}
+	server := httptest.NewServer(tv)
+	t.Cleanup(server.Close)
+	return tv, server.URL
+}
+
+func (tv *testVault) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (tv *testVault) writeErrors(w http.ResponseWriter, status int, errs ...string) {
+	tv.writeJSON(w, status, map[string][]string{"errors": errs})
+}
+
+func (tv *testVault) loginCount() int {
+	tv.lock.Lock()
+	defer tv.lock.Unlock()
+	return tv.logins
+}
+
+// revokeTokens forgets every token issued by AppRole login, as if they had been revoked
+func (tv *testVault) revokeTokens() {
+	tv.lock.Lock()
+	defer tv.lock.Unlock()
+	tv.tokens = map[string]bool{"root-token": true}
+}
+
+func (tv *testVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tv.lock.Lock()
+	defer tv.lock.Unlock()
+
+	if r.URL.Path == "/v1/auth/approle/login" && r.Method == http.MethodPost {
+		var creds struct {
+			RoleID   string `json:"role_id"`
+			SecretID string `json:"secret_id"`
+		}
+		json.NewDecoder(r.Body).Decode(&creds)
+		if creds.RoleID != tv.roleID || creds.SecretID != tv.secret {
+			tv.writeErrors(w, http.StatusBadRequest, "invalid role or secret ID")
+			return
+		}
+		tv.logins++
+		token := fmt.Sprintf("approle-token-%d", tv.logins)
+		tv.tokens[token] = true
+		tv.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"auth": map[string]interface{}{
+				"client_token":   token,
+				"lease_duration": 3600,
+			},
+		})
+		return
+	}
+
+	if !tv.tokens[r.Header.Get("X-Vault-Token")] {
+		tv.writeErrors(w, http.StatusForbidden, "permission denied")
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/v1/secret/data/"):
+		tv.serveData(w, r, strings.TrimPrefix(r.URL.Path, "/v1/secret/data/"))
+	case strings.HasPrefix(r.URL.Path, "/v1/secret/metadata/"):
+		tv.serveMetadata(w, r, strings.TrimPrefix(r.URL.Path, "/v1/secret/metadata/"))
+	default:
+		tv.writeErrors(w, http.StatusNotFound)
+	}
+}
+
+func (tv *testVault) serveData(w http.ResponseWriter, r *http.Request, path string) {
+	versions := tv.data[path]
+	switch r.Method {
+	case http.MethodGet:
+		v := len(versions)
+		if s := r.URL.Query().Get("version"); s != "" {
+			v, _ = strconv.Atoi(s)
+		}
+		if v < 1 || v > len(versions) {
+			tv.writeErrors(w, http.StatusNotFound)
+			return
+		}
+		tv.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"data": map[string]interface{}{
+				"data": versions[v-1],
+				"metadata": map[string]interface{}{
+					"created_time": testVaultEpoch.Add(time.Duration(v) * time.Minute).Format(time.RFC3339Nano),
+					"version":      v,
+				},
+			},
+		})
+	case http.MethodPost:
+		var req struct {
+			Options struct {
+				CAS *int `json:"cas"`
+			} `json:"options"`
+			Data json.RawMessage `json:"data"`
+		}
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			tv.writeErrors(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if req.Options.CAS != nil && *req.Options.CAS != len(versions) {
+			tv.writeErrors(w, http.StatusBadRequest, "check-and-set parameter did not match the current version")
+			return
+		}
+		tv.data[path] = append(versions, req.Data)
+		tv.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"data": map[string]interface{}{"version": len(tv.data[path])},
+		})
+	default:
+		tv.writeErrors(w, http.StatusMethodNotAllowed)
+	}
+}
+
+func (tv *testVault) serveMetadata(w http.ResponseWriter, r *http.Request, path string) {
+	switch r.Method {
+	case "LIST":
+		prefix := strings.TrimSuffix(path, "/") + "/"
+		keys := make(map[string]bool)
+		for p := range tv.data {
+			if strings.HasPrefix(p, prefix) {
+				k := strings.TrimPrefix(p, prefix)
+				if i := strings.Index(k, "/"); i != -1 {
+					k = k[:i+1] // a folder
+				}
+				keys[k] = true
+			}
+		}
+		if len(keys) == 0 {
+			tv.writeErrors(w, http.StatusNotFound)
+			return
+		}
+		var rv []string
+		for k := range keys {
+			rv = append(rv, k)
+		}
+		sort.Strings(rv)
+		tv.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"data": map[string]interface{}{"keys": rv},
+		})
+	case http.MethodGet:
+		versions, ok := tv.data[path]
+		if !ok {
+			tv.writeErrors(w, http.StatusNotFound)
+			return
+		}
+		vm := make(map[string]interface{})
+		for i := range versions {
+			vm[strconv.Itoa(i+1)] = map[string]interface{}{
+				"created_time":  testVaultEpoch.Add(time.Duration(i+1) * time.Minute).Format(time.RFC3339Nano),
+				"deletion_time": "",
+				"destroyed":     false,
+			}
+		}
+		tv.writeJSON(w, http.StatusOK, map[string]interface{}{
+			"data": map[string]interface{}{"versions": vm},
+		})
+	case http.MethodDelete:
+		delete(tv.data, path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		tv.writeErrors(w, http.StatusMethodNotAllowed)
+	}
+}
+
+func newTestVaultStore(t *testing.T, address string) *vaultStore {
+	vs := &vaultStore{
+		Address: address + "/",
+		Prefix:  "le-responder",
+		Token:   "root-token",
+	}
+	err := vs.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vs
+}
+
+func TestVaultCerts(t *testing.T) {
+	_, address := newTestVault(t)
+	vs := newTestVaultStore(t, address)
+
+	_, err := vs.LoadPath(pathFromHost("missing.example.gov.au"))
+	if err != errCertNotFound {
+		t.Fatalf("expected errCertNotFound for a missing cert, got %v", err)
+	}
+	certs, err := vs.FetchCerts()
+	if err != nil || len(certs) != 0 {
+		t.Fatalf("expected no certs before any are saved, got %d, %v", len(certs), err)
+	}
+
+	www, api := pathFromHost("www.example.gov.au"), pathFromHost("api.example.gov.au")
+	for _, chc := range []*credhubCert{
+		{Source: "le-prod", Certificate: "www-1"},
+		{Source: "le-prod", Certificate: "api-1"},
+		{Source: "le-prod", Certificate: "www-2"},
+	} {
+		path := www
+		if strings.HasPrefix(chc.Certificate, "api") {
+			path = api
+		}
+		err = vs.SavePath(path, chc)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Other data must not be listed as certs
+	err = vs.SaveData(apiTokensPath, map[string]string{"x": "y"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hostnames, err := vs.FetchHostnames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(hostnames)
+	if strings.Join(hostnames, ",") != "api.example.gov.au,www.example.gov.au" {
+		t.Fatalf("unexpected hostnames: %q", hostnames)
+	}
+
+	history, err := vs.LoadHistory(www)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].Certificate != "www-2" || history[1].Certificate != "www-1" {
+		t.Fatalf("expected both versions newest first, got %+v", history)
+	}
+	if !history[0].dateCreated.Equal(testVaultEpoch.Add(2*time.Minute)) || history[0].version != "2" || history[0].path != www {
+		t.Fatalf("expected created_time and version from vault, got %s, %q, %q", history[0].dateCreated, history[0].version, history[0].path)
+	}
+
+	old, err := vs.LoadVersion(www, "1")
+	if err != nil || old.Certificate != "www-1" {
+		t.Fatalf("expected first version, got %+v, %v", old, err)
+	}
+	_, err = vs.LoadVersion(www, "3")
+	if err != errCertNotFound {
+		t.Fatalf("expected errCertNotFound for a missing version, got %v", err)
+	}
+
+	err = vs.DeletePath(www)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = vs.LoadPath(www)
+	if err != errCertNotFound {
+		t.Fatalf("expected errCertNotFound once deleted, got %v", err)
+	}
+}
+
+func TestVaultCompareAndSet(t *testing.T) {
+	_, address := newTestVault(t)
+	vs := newTestVaultStore(t, address)
+
+	var lease leaderLease
+	_, err := vs.LoadDataVersion(leaderLeasePath, &lease)
+	if err != errCertNotFound {
+		t.Fatalf("expected errCertNotFound before anything is saved, got %v", err)
+	}
+
+	err = vs.SaveDataIfVersion(leaderLeasePath, "", &leaderLease{Holder: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = vs.SaveDataIfVersion(leaderLeasePath, "", &leaderLease{Holder: "b"})
+	if err != errVersionConflict {
+		t.Fatalf("expected errVersionConflict creating over existing data, got %v", err)
+	}
+
+	version, err := vs.LoadDataVersion(leaderLeasePath, &lease)
+	if err != nil || version != "1" || lease.Holder != "a" {
+		t.Fatalf("expected version 1 held by a, got %q, %+v, %v", version, lease, err)
+	}
+	err = vs.SaveDataIfVersion(leaderLeasePath, version, &leaderLease{Holder: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = vs.SaveDataIfVersion(leaderLeasePath, version, &leaderLease{Holder: "b"})
+	if err != errVersionConflict {
+		t.Fatalf("expected errVersionConflict saving over a newer version, got %v", err)
+	}
+
+	// Other errors are not conflicts
+	err = vs.SaveDataIfVersion("/bad", "", func() {})
+	if err == nil || err == errVersionConflict {
+		t.Fatalf("expected an error that isn't a conflict, got %v", err)
+	}
+}
+
+func TestVaultListData(t *testing.T) {
+	_, address := newTestVault(t)
+	vs := newTestVaultStore(t, address)
+
+	for _, path := range []string{"/audit/2020-01-02", "/audit/2020-01-02/1-a", "/audit/2020-01-02/2-b", "/audit/2020-01-03/3-c"} {
+		err := vs.SaveData(path, &auditEntry{Actor: path})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	paths, err := vs.ListData("/audit/2020-01-02/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(paths, ",") != "/audit/2020-01-02/1-a,/audit/2020-01-02/2-b" {
+		t.Fatalf("unexpected paths: %q", paths)
+	}
+	paths, err = vs.ListData("/audit/2020-01-04/")
+	if err != nil || len(paths) != 0 {
+		t.Fatalf("expected nothing for a missing prefix, got %q, %v", paths, err)
+	}
+}
+
+func TestVaultAppRole(t *testing.T) {
+	tv, address := newTestVault(t)
+	vs := &vaultStore{Address: address}
+	vs.AppRole.RoleID = "role"
+	vs.AppRole.SecretID = "secret-id"
+	err := vs.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = vs.SaveData(apiTokensPath, map[string]string{"x": "y"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v map[string]string
+	err = vs.LoadData(apiTokensPath, &v)
+	if err != nil || v["x"] != "y" {
+		t.Fatalf("expected saved data, got %v, %v", v, err)
+	}
+	if n := tv.loginCount(); n != 1 {
+		t.Fatalf("expected the token to be reused, got %d logins", n)
+	}
+
+	// A revoked token is replaced by logging in again
+	tv.revokeTokens()
+	err = vs.LoadData(apiTokensPath, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := tv.loginCount(); n != 2 {
+		t.Fatalf("expected to log in again, got %d logins", n)
+	}
+
+	bad := &vaultStore{Address: address}
+	bad.AppRole.RoleID = "role"
+	bad.AppRole.SecretID = "wrong"
+	err = bad.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = bad.LoadData(apiTokensPath, &v)
+	if err == nil || !isCommsRelatedError(err) {
+		t.Fatalf("expected a vault error for a bad secret id, got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// dnsProvider is used by ACME sources to satisfy dns-01 challenges without a human
+type dnsProvider interface {
+	// Present creates a TXT record set with the given fully qualified name and values,
+	// and should not return until the authoritative servers are serving it.
+	Present(ctx context.Context, fqdn string, values []string) error
+
+	// CleanUp removes the values previously created by Present
+	CleanUp(ctx context.Context, fqdn string, values []string) error
+}
+
+type dnsConf struct {
+	Type string `yaml:"type"` // "rfc2136" or "route53"
+
+	// Seconds to wait after records are created before asking the CA to validate,
+	// useful if secondary name servers are slow to pick up changes.
+	PropagationSeconds int `yaml:"propagation_seconds"`
+
+	RFC2136 rfc2136Provider `yaml:"rfc2136"`
+	Route53 route53Provider `yaml:"route53"`
+}
+
+// Init validates the config and returns the provider selected, or nil if none is configured
+func (dc *dnsConf) Init() (dnsProvider, error) {
+	var rv interface {
+		dnsProvider
+		Init() error
+	}
+	switch dc.Type {
+	case "":
+		return nil, nil
+	case "rfc2136":
+		rv = &dc.RFC2136
+	case "route53":
+		rv = &dc.Route53
+	default:
+		return nil, errors.New("unknown dns provider type")
+	}
+
+	err := rv.Init()
+	if err != nil {
+		return nil, err
+	}
+
+	if dc.PropagationSeconds == 0 {
+		return rv, nil
+	}
+	return &delayedDNSProvider{
+		dnsProvider: rv,
+		delay:       time.Duration(dc.PropagationSeconds) * time.Second,
+	}, nil
+}
+
+type delayedDNSProvider struct {
+	dnsProvider
+	delay time.Duration
+}
+
+func (ddp *delayedDNSProvider) Present(ctx context.Context, fqdn string, values []string) error {
+	err := ddp.dnsProvider.Present(ctx, fqdn, values)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-time.After(ddp.delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// dns01RecordName returns the fully qualified name of the TXT record for a dns-01 challenge for the domain
+func dns01RecordName(domain string) string {
+	return "_acme-challenge." + strings.TrimSuffix(domain, ".") + "."
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// tsigFudge is the number of seconds either side of the time signed that a TSIG is valid for
+const tsigFudge = 300
+
+// rfc2136Provider publishes records by sending dynamic updates, signed with TSIG, to a primary name server
+type rfc2136Provider struct {
+	Server        string `yaml:"server"` // host:port of the primary name server, port defaults to 53
+	Zone          string `yaml:"zone"`   // zone to update, e.g. example.gov.au
+	TSIGKeyName   string `yaml:"tsig_key_name"`
+	TSIGAlgorithm string `yaml:"tsig_algorithm"` // hmac-sha256 (default), hmac-sha512 or hmac-sha1
+	TSIGSecret    string `yaml:"tsig_secret"`    // base64 encoded
+	TTL           int    `yaml:"ttl"`
+}
+
+func (rp *rfc2136Provider) Init() error {
+	if rp.Server == "" {
+		return errors.New("rfc2136 server must be specified")
+	}
+	if _, _, err := net.SplitHostPort(rp.Server); err != nil {
+		rp.Server = net.JoinHostPort(rp.Server, "53")
+	}
+	if rp.Zone == "" {
+		return errors.New("rfc2136 zone must be specified")
+	}
+	rp.Zone = dns.CanonicalName(rp.Zone)
+	if rp.TTL == 0 {
+		rp.TTL = 60
+	}
+
+	if rp.TSIGKeyName == "" {
+		return nil // unsigned updates, hopefully restricted by IP
+	}
+	// The client looks up secrets by the key name in canonical form
+	rp.TSIGKeyName = dns.CanonicalName(rp.TSIGKeyName)
+
+	switch dns.CanonicalName(rp.TSIGAlgorithm) {
+	case ".", dns.HmacSHA256:
+		rp.TSIGAlgorithm = dns.HmacSHA256
+	case dns.HmacSHA512:
+		rp.TSIGAlgorithm = dns.HmacSHA512
+	case dns.HmacSHA1:
+		rp.TSIGAlgorithm = dns.HmacSHA1
+	default:
+		return errors.New("unsupported tsig algorithm")
+	}
+
+	secret, err := base64.StdEncoding.DecodeString(rp.TSIGSecret)
+	if err != nil {
+		return err
+	}
+	if len(secret) == 0 {
+		return errors.New("tsig secret must be specified with a tsig key name")
+	}
+
+	return nil
+}
+
+func (rp *rfc2136Provider) Present(ctx context.Context, fqdn string, values []string) error {
+	return rp.update(ctx, fqdn, func(m *dns.Msg, rrs []dns.RR) { m.Insert(rrs) }, values)
+}
+
+func (rp *rfc2136Provider) CleanUp(ctx context.Context, fqdn string, values []string) error {
+	// Only delete our values, as other orders may be using the same name at the same time
+	return rp.update(ctx, fqdn, func(m *dns.Msg, rrs []dns.RR) { m.Remove(rrs) }, values)
+}
+
+func (rp *rfc2136Provider) update(ctx context.Context, fqdn string, op func(m *dns.Msg, rrs []dns.RR), values []string) error {
+	if !dns.IsSubDomain(rp.Zone, fqdn) || dns.CanonicalName(fqdn) == rp.Zone {
+		return fmt.Errorf("%s is not within zone %s", fqdn, rp.Zone)
+	}
+
+	rrs := make([]dns.RR, len(values))
+	for i, v := range values {
+		txt := &dns.TXT{Hdr: dns.RR_Header{
+			Name:   dns.Fqdn(fqdn),
+			Rrtype: dns.TypeTXT,
+			Class:  dns.ClassINET,
+			Ttl:    uint32(rp.TTL),
+		}}
+		// A single character-string is limited to 255 bytes, longer values are split over several
+		for len(v) > 255 {
+			txt.Txt = append(txt.Txt, v[:255])
+			v = v[255:]
+		}
+		txt.Txt = append(txt.Txt, v)
+		rrs[i] = txt
+	}
+
+	m := new(dns.Msg)
+	m.SetUpdate(rp.Zone)
+	op(m, rrs)
+
+	c := &dns.Client{Net: "tcp"}
+	if rp.TSIGKeyName != "" {
+		m.SetTsig(rp.TSIGKeyName, rp.TSIGAlgorithm, tsigFudge, time.Now().Unix())
+		c.TsigSecret = map[string]string{rp.TSIGKeyName: rp.TSIGSecret}
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+	}
+
+	r, _, err := c.ExchangeContext(ctx, m, rp.Server)
+	if err != nil {
+		return err
+	}
+	// Failures are often unsigned (e.g. if the server doesn't know our key), so check these first
+	if r.Rcode != dns.RcodeSuccess {
+		name, ok := dns.RcodeToString[r.Rcode]
+		if !ok {
+			name = fmt.Sprintf("rcode %d", r.Rcode)
+		}
+		return fmt.Errorf("dns update for %s failed: %s", fqdn, name)
+	}
+	// The client verifies a TSIG if there is one, but doesn't insist on it
+	if rp.TSIGKeyName != "" && r.IsTsig() == nil {
+		return errors.New("dns response is not signed")
+	}
+
+	return nil
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	testTSIGKeyName = "le-responder."
+	testTSIGSecret  = "c2VjcmV0LWtleS1mb3ItbGUtcmVzcG9uZGVyLXRlc3Rz"
+)
+
+// testDNSServer accepts signed dynamic updates for a single zone, keeping the TXT records it is sent
+type testDNSServer struct {
+	zone string
+
+	lock    sync.Mutex
+	records map[string][]string // by name
+	updates int
+}
+
+func (ts *testDNSServer) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	m := new(dns.Msg)
+	switch {
+	case r.IsTsig() == nil || w.TsigStatus() != nil:
+		// Like BIND, refuse anything not signed with a key we know, and don't sign the refusal
+		m.SetRcode(r, dns.RcodeNotAuth)
+		w.WriteMsg(m)
+		return
+	case r.Opcode != dns.OpcodeUpdate || len(r.Question) != 1 || r.Question[0].Name != ts.zone || r.Question[0].Qtype != dns.TypeSOA:
+		m.SetRcode(r, dns.RcodeFormatError)
+	default:
+		m.SetReply(r)
+		ts.lock.Lock()
+		ts.updates++
+		for _, rr := range r.Ns {
+			txt, ok := rr.(*dns.TXT)
+			if !ok {
+				m.SetRcode(r, dns.RcodeFormatError)
+				break
+			}
+			value := strings.Join(txt.Txt, "")
+			switch txt.Hdr.Class {
+			case dns.ClassINET:
+				ts.records[txt.Hdr.Name] = append(ts.records[txt.Hdr.Name], value)
+			case dns.ClassNONE:
+				var keep []string
+				for _, v := range ts.records[txt.Hdr.Name] {
+					if v != value {
+						keep = append(keep, v)
+					}
+				}
+				ts.records[txt.Hdr.Name] = keep
+			}
+		}
+		ts.lock.Unlock()
+	}
+
+	m.SetTsig(testTSIGKeyName, dns.HmacSHA256, 300, time.Now().Unix())
+	w.WriteMsg(m)
+}
+
+func (ts *testDNSServer) txt(name string) []string {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	return ts.records[name]
+}
+
+// startTestDNSServer returns a server listening on TCP on localhost, which is stopped when the test ends
+func startTestDNSServer(t *testing.T) (*testDNSServer, string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := &testDNSServer{
+		zone:    "example.gov.au.",
+		records: make(map[string][]string),
+	}
+	started := make(chan struct{})
+	server := &dns.Server{
+		Listener:          l,
+		Net:               "tcp",
+		Handler:           ts,
+		TsigSecret:        map[string]string{testTSIGKeyName: testTSIGSecret},
+		NotifyStartedFunc: func() { close(started) },
+		MsgAcceptFunc: func(dh dns.Header) dns.MsgAcceptAction {
+			return dns.MsgAccept // the default refuses updates
+		},
+	}
+	go server.ActivateAndServe()
+	<-started
+	t.Cleanup(func() {
+		server.Shutdown()
+	})
+
+	return ts, l.Addr().String()
+}
+
+func TestRFC2136PresentAndCleanUp(t *testing.T) {
+	ts, addr := startTestDNSServer(t)
+
+	rp := &rfc2136Provider{
+		Server:      addr,
+		Zone:        "Example.gov.au",
+		TSIGKeyName: "le-responder",
+		TSIGSecret:  testTSIGSecret,
+	}
+	err := rp.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	fqdn := "_acme-challenge.www.example.gov.au."
+	values := []string{"first-token", strings.Repeat("x", 300)} // long values are split into strings of 255
+	err = rp.Present(ctx, fqdn, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := ts.txt(fqdn)
+	if len(got) != 2 || got[0] != values[0] || got[1] != values[1] {
+		t.Fatalf("after present, expected %q, got %q", values, got)
+	}
+
+	err = rp.CleanUp(ctx, fqdn, values[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = ts.txt(fqdn)
+	if len(got) != 1 || got[0] != values[1] {
+		t.Fatalf("after clean up, expected only the second value, got %q", got)
+	}
+}
+
+func TestRFC2136BadKey(t *testing.T) {
+	ts, addr := startTestDNSServer(t)
+
+	rp := &rfc2136Provider{
+		Server:      addr,
+		Zone:        "example.gov.au",
+		TSIGKeyName: "le-responder",
+		TSIGSecret:  "d3Jvbmcta2V5",
+	}
+	err := rp.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err = rp.Present(ctx, "_acme-challenge.www.example.gov.au.", []string{"token"})
+	if err == nil || !strings.Contains(err.Error(), "NOTAUTH") {
+		t.Fatalf("expected NOTAUTH error, got %v", err)
+	}
+	ts.lock.Lock()
+	updates := ts.updates
+	ts.lock.Unlock()
+	if updates != 0 {
+		t.Fatalf("expected no updates to be applied, got %d", updates)
+	}
+}
+
+func TestRFC2136OutsideZone(t *testing.T) {
+	rp := &rfc2136Provider{
+		Server: "127.0.0.1:53",
+		Zone:   "example.gov.au",
+	}
+	err := rp.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = rp.Present(context.Background(), "_acme-challenge.www.example.com.", []string{"token"})
+	if err == nil || !strings.Contains(err.Error(), "not within zone") {
+		t.Fatalf("expected error for name outside zone, got %v", err)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/ec2rolecreds"
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+)
+
+const (
+	route53Endpoint = "https://route53.amazonaws.com/2013-04-01"
+
+	// route53PollInterval is how often we check whether a change is live
+	route53PollInterval = 5 * time.Second
+)
+
+// route53Provider publishes records in a Route 53 hosted zone. We talk to the API directly
+// as only a couple of calls are needed.
+type route53Provider struct {
+	HostedZoneID string `yaml:"hosted_zone_id"`
+	TTL          int    `yaml:"ttl"`
+
+	// Recommend leaving empty and will use IAM role instead:
+	AccessKey    string `yaml:"access_key"`
+	AccessSecret string `yaml:"access_secret"`
+
+	endpoint     string
+	pollInterval time.Duration
+
+	awsMutex sync.Mutex
+	creds    *credentials.Credentials
+}
+
+func (r53 *route53Provider) Init() error {
+	if r53.HostedZoneID == "" {
+		return errors.New("route53 hosted zone id must be specified")
+	}
+	r53.HostedZoneID = strings.TrimPrefix(r53.HostedZoneID, "/hostedzone/")
+	if r53.TTL == 0 {
+		r53.TTL = 60
+	}
+	if r53.endpoint == "" {
+		r53.endpoint = route53Endpoint
+	}
+	if r53.pollInterval == 0 {
+		r53.pollInterval = route53PollInterval
+	}
+	return nil
+}
+
+func (r53 *route53Provider) Present(ctx context.Context, fqdn string, values []string) error {
+	return r53.change(ctx, "UPSERT", fqdn, values)
+}
+
+func (r53 *route53Provider) CleanUp(ctx context.Context, fqdn string, values []string) error {
+	return r53.change(ctx, "DELETE", fqdn, values)
+}
+
+type route53ResourceRecord struct {
+	Value string `xml:"Value"`
+}
+
+type route53Change struct {
+	Action          string                  `xml:"Action"`
+	Name            string                  `xml:"ResourceRecordSet>Name"`
+	Type            string                  `xml:"ResourceRecordSet>Type"`
+	TTL             int                     `xml:"ResourceRecordSet>TTL"`
+	ResourceRecords []route53ResourceRecord `xml:"ResourceRecordSet>ResourceRecords>ResourceRecord"`
+}
+
+type route53ChangeRequest struct {
+	XMLName xml.Name        `xml:"https://route53.amazonaws.com/doc/2013-04-01/ ChangeResourceRecordSetsRequest"`
+	Comment string          `xml:"ChangeBatch>Comment"`
+	Changes []route53Change `xml:"ChangeBatch>Changes>Change"`
+}
+
+type route53ChangeInfo struct {
+	ID     string `xml:"ChangeInfo>Id"`
+	Status string `xml:"ChangeInfo>Status"`
+}
+
+// change sends the change, and waits for Route 53 to report that it is live on all of its servers
+func (r53 *route53Provider) change(ctx context.Context, action, fqdn string, values []string) error {
+	c := route53Change{
+		Action: action,
+		Name:   fqdn,
+		Type:   "TXT",
+		TTL:    r53.TTL,
+	}
+	for _, v := range values {
+		c.ResourceRecords = append(c.ResourceRecords, route53ResourceRecord{
+			Value: `"` + v + `"`,
+		})
+	}
+
+	body, err := xml.Marshal(&route53ChangeRequest{
+		Comment: "le-responder dns-01 challenge",
+		Changes: []route53Change{c},
+	})
+	if err != nil {
+		return err
+	}
+
+	var ci route53ChangeInfo
+	err = r53.do(ctx, http.MethodPost, fmt.Sprintf("%s/hostedzone/%s/rrset/", r53.endpoint, r53.HostedZoneID), append([]byte(xml.Header), body...), &ci)
+	if err != nil {
+		return err
+	}
+
+	for ci.Status != "INSYNC" {
+		select {
+		case <-time.After(r53.pollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		err = r53.do(ctx, http.MethodGet, r53.endpoint+"/change/"+strings.TrimPrefix(ci.ID, "/change/"), nil, &ci)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r53 *route53Provider) getCredentials() (*credentials.Credentials, error) {
+	r53.awsMutex.Lock()
+	defer r53.awsMutex.Unlock()
+
+	if r53.creds != nil {
+		return r53.creds, nil
+	}
+
+	if r53.AccessKey == "" { // if not specified, assume EC2RoleProvider
+		sessionForMetadata, err := session.NewSession()
+		if err != nil {
+			return nil, err
+		}
+		r53.creds = credentials.NewCredentials(&ec2rolecreds.EC2RoleProvider{
+			Client: ec2metadata.New(sessionForMetadata),
+		})
+	} else {
+		r53.creds = credentials.NewStaticCredentials(r53.AccessKey, r53.AccessSecret, "")
+	}
+	return r53.creds, nil
+}
+
+func (r53 *route53Provider) do(ctx context.Context, method, url string, body []byte, rv interface{}) error {
+	creds, err := r53.getCredentials()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/xml")
+	}
+
+	// Route 53 is a global service, signed for us-east-1
+	_, err = v4.NewSigner(creds).Sign(req, bytes.NewReader(body), "route53", "us-east-1", time.Now())
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	contents, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp struct {
+			Code    string `xml:"Error>Code"`
+			Message string `xml:"Error>Message"`
+		}
+		if xml.Unmarshal(contents, &errResp) == nil && errResp.Code != "" {
+			return fmt.Errorf("route53 %s: %s", errResp.Code, errResp.Message)
+		}
+		return fmt.Errorf("not OK response from route53: %s", contents)
+	}
+
+	return xml.Unmarshal(contents, rv)
+}
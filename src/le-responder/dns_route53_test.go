@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testRoute53 stands in for the parts of the Route 53 API that we use
+type testRoute53 struct {
+	lock    sync.Mutex
+	changes []route53Change
+	polls   int
+}
+
+func (tr *testRoute53) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDTEST/") || !strings.Contains(r.Header.Get("Authorization"), "/us-east-1/route53/aws4_request") {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`<ErrorResponse><Error><Code>SignatureDoesNotMatch</Code><Message>bad signature</Message></Error></ErrorResponse>`))
+		return
+	}
+
+	tr.lock.Lock()
+	defer tr.lock.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/hostedzone/Z123/rrset/":
+		body, _ := ioutil.ReadAll(r.Body)
+		var req route53ChangeRequest
+		err := xml.Unmarshal(body, &req)
+		if err != nil || len(req.Changes) != 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`<ErrorResponse><Error><Code>InvalidInput</Code><Message>bad request</Message></Error></ErrorResponse>`))
+			return
+		}
+		if req.Changes[0].Action == "DELETE" && len(tr.changes) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`<ErrorResponse><Error><Code>InvalidChangeBatch</Code><Message>record not found</Message></Error></ErrorResponse>`))
+			return
+		}
+		tr.changes = append(tr.changes, req.Changes[0])
+		w.Write([]byte(`<ChangeResourceRecordSetsResponse><ChangeInfo><Id>/change/C1</Id><Status>PENDING</Status></ChangeInfo></ChangeResourceRecordSetsResponse>`))
+	case r.Method == http.MethodGet && r.URL.Path == "/change/C1":
+		tr.polls++
+		w.Write([]byte(`<GetChangeResponse><ChangeInfo><Id>/change/C1</Id><Status>INSYNC</Status></ChangeInfo></GetChangeResponse>`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestRoute53Provider(t *testing.T, tr *testRoute53) *route53Provider {
+	server := httptest.NewServer(tr)
+	t.Cleanup(server.Close)
+
+	r53 := &route53Provider{
+		HostedZoneID: "/hostedzone/Z123",
+		AccessKey:    "AKIDTEST",
+		AccessSecret: "secret",
+		endpoint:     server.URL,
+		pollInterval: time.Millisecond,
+	}
+	err := r53.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r53
+}
+
+func TestRoute53PresentAndCleanUp(t *testing.T) {
+	tr := &testRoute53{}
+	r53 := newTestRoute53Provider(t, tr)
+
+	fqdn := "_acme-challenge.www.example.gov.au."
+	err := r53.Present(context.Background(), fqdn, []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = r53.CleanUp(context.Background(), fqdn, []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tr.changes) != 2 || tr.polls != 2 {
+		t.Fatalf("expected 2 changes each polled once, got %d changes and %d polls", len(tr.changes), tr.polls)
+	}
+	for i, action := range []string{"UPSERT", "DELETE"} {
+		c := tr.changes[i]
+		if c.Action != action || c.Name != fqdn || c.Type != "TXT" || c.TTL != 60 || len(c.ResourceRecords) != 2 || c.ResourceRecords[1].Value != `"b"` {
+			t.Fatalf("unexpected change %d: %+v", i, c)
+		}
+	}
+}
+
+func TestRoute53Error(t *testing.T) {
+	r53 := newTestRoute53Provider(t, &testRoute53{})
+
+	err := r53.CleanUp(context.Background(), "_acme-challenge.www.example.gov.au.", []string{"a"})
+	if err == nil || err.Error() != "route53 InvalidChangeBatch: record not found" {
+		t.Fatalf("expected InvalidChangeBatch error, got %v", err)
+	}
+}
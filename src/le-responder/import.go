@@ -0,0 +1,273 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// importedSourceName is the source of certs that were issued elsewhere and imported.
+// It is always present, but isn't listed with the configured sources as it can't issue certs.
+const importedSourceName = "imported"
+
+// importedSource never issues certs. Imported certs are kept until their source is changed to one that can renew them.
+type importedSource struct{}
+
+var errImportedNotRenewed = errors.New("imported certs are not renewed, change the source to one that can issue them")
+
+func (is *importedSource) AutoFetchCert(ctx context.Context, pkey crypto.Signer, hostnames []string) ([][]byte, error) {
+	return nil, errImportedNotRenewed
+}
+
+func (is *importedSource) ManualStartChallenge(ctx context.Context, hostnames []string) (*acmeChallenge, error) {
+	return nil, errImportedNotRenewed
+}
+
+func (is *importedSource) CompleteChallenge(ctx context.Context, pkey crypto.Signer, hostnames []string, chal *acmeChallenge) ([][]byte, error) {
+	return nil, errImportedNotRenewed
+}
+
+func (is *importedSource) SupportsManual() bool {
+	return false
+}
+
+func (is *importedSource) SupportsCSR() bool {
+	return false
+}
+
+func (is *importedSource) SupportsKeyType(kt string) error {
+	return validateKeyType(kt)
+}
+
+func (is *importedSource) Revoke(ctx context.Context, der []byte, key crypto.Signer, reason int) error {
+	return errors.New("imported certs must be revoked by the CA that issued them")
+}
+
+func (is *importedSource) SupportsRevoke() bool {
+	return false
+}
+
+// importPEMs is a cert issued elsewhere, as uploaded
+type importPEMs struct {
+	Certificate string `json:"certificate"`
+	Chain       string `json:"chain"`
+	PrivateKey  string `json:"private_key"`
+}
+
+// parse checks that the cert is for hostname, that the chain builds to a trusted root,
+// and that the key matches. It returns the cert to store, and the other names in it.
+func (ip *importPEMs) parse(hostname string) (*certKeyPair, []string, error) {
+	certs, err := parseCertificatesPEM(ip.Certificate + "\n" + ip.Chain)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(certs) == 0 {
+		return nil, nil, errors.New("no certificate found")
+	}
+	pc, chain := certs[0], certs[1:]
+
+	key, err := parsePrivateKey(ip.PrivateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !publicKeysEqual(pc.PublicKey, key.Public()) {
+		return nil, nil, errors.New("private key does not match the certificate")
+	}
+	kt := keyTypeOf(pc.PublicKey)
+	if kt == "" {
+		return nil, nil, errors.New("unsupported key type")
+	}
+
+	// We manage names exactly, so a wildcard can't stand in for the hostname
+	found := false
+	var sans []string
+	for _, hn := range parseHostnames(strings.Join(pc.DNSNames, " ")) {
+		if hn == hostname {
+			found = true
+		} else {
+			sans = append(sans, hn)
+		}
+	}
+	if !found {
+		return nil, nil, fmt.Errorf("certificate is not for %s", hostname)
+	}
+
+	// Any self-signed certs in the chain are roots, in case a private CA is used
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		roots = x509.NewCertPool()
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range chain {
+		if bytes.Equal(c.RawIssuer, c.RawSubject) && c.CheckSignatureFrom(c) == nil {
+			roots.AddCert(c)
+		} else {
+			intermediates.AddCert(c)
+		}
+	}
+	_, err = pc.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("chain does not build: %s", err)
+	}
+
+	pk, err := marshalPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	var ca string
+	for _, c := range chain {
+		ca += string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw}))
+	}
+
+	return &certKeyPair{
+		KeyType:     kt,
+		CA:          ca,
+		Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: pc.Raw})),
+		PrivateKey:  pk,
+	}, certHostnames(hostname, sans)[1:], nil
+}
+
+// parseCertificatesPEM returns all certificates in s, in order
+func parseCertificatesPEM(s string) ([]*x509.Certificate, error) {
+	var rv []*x509.Certificate
+	rest := []byte(s)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return rv, nil
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("unexpected %s found where certificates expected", block.Type)
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		rv = append(rv, c)
+	}
+}
+
+func publicKeysEqual(a, b crypto.PublicKey) bool {
+	ab, err := x509.MarshalPKIXPublicKey(a)
+	if err != nil {
+		return false
+	}
+	bb, err := x509.MarshalPKIXPublicKey(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(ab, bb)
+}
+
+// tarballCert is a cert found in a tarball written by outputObserver.createTarball
+type tarballCert struct {
+	Hostname  string
+	Primary   *importPEMs
+	Secondary *importPEMs // optional, from a HAProxy multi-cert bundle entry
+}
+
+// readTarball returns the certs in a tarball written by outputObserver.createTarball, sorted by hostname.
+// Each entry holds the key, cert and chain concatenated, and is named for the hex encoded hostname.
+func readTarball(r io.Reader) ([]*tarballCert, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gr)
+
+	byHost := make(map[string]*tarballCert)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// <hex>.crt, or <hex>.crt.<algorithm> for a secondary cert. Anything else, such as a trust bundle, is skipped.
+		bits := strings.SplitN(hdr.Name, ".", 3)
+		if len(bits) < 2 || bits[1] != "crt" {
+			continue
+		}
+		hn, err := hex.DecodeString(bits[0])
+		if err != nil {
+			continue
+		}
+
+		data, err := ioutil.ReadAll(io.LimitReader(tr, 1<<20))
+		if err != nil {
+			return nil, err
+		}
+		ip, err := splitCombinedPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", hdr.Name, err)
+		}
+
+		tc, ok := byHost[string(hn)]
+		if !ok {
+			tc = &tarballCert{Hostname: string(hn)}
+			byHost[string(hn)] = tc
+		}
+		if len(bits) == 3 {
+			tc.Secondary = ip
+		} else {
+			tc.Primary = ip
+		}
+	}
+
+	var rv []*tarballCert
+	for _, tc := range byHost {
+		if tc.Primary == nil {
+			return nil, fmt.Errorf("%s has a secondary certificate but no primary", tc.Hostname)
+		}
+		rv = append(rv, tc)
+	}
+	sort.Slice(rv, func(i, j int) bool {
+		return rv[i].Hostname < rv[j].Hostname
+	})
+	return rv, nil
+}
+
+// splitCombinedPEM separates a file holding a key, a cert and its chain, as used by HAProxy
+func splitCombinedPEM(data []byte) (*importPEMs, error) {
+	rv := &importPEMs{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		encoded := string(pem.EncodeToMemory(block))
+		switch {
+		case strings.HasSuffix(block.Type, "PRIVATE KEY"):
+			rv.PrivateKey = encoded
+		case block.Type == "CERTIFICATE" && rv.Certificate == "":
+			rv.Certificate = encoded
+		case block.Type == "CERTIFICATE":
+			rv.Chain += encoded
+		}
+	}
+	if rv.Certificate == "" || rv.PrivateKey == "" {
+		return nil, errors.New("expected a private key and certificate")
+	}
+	return rv, nil
+}
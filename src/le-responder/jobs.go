@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	jobRenew      = "renew"
+	jobComplete   = "complete"
+	jobCompromise = "compromise" // revoke, reissue with a fresh key, and update outputs now
+
+	jobQueued    = "queued"
+	jobRunning   = "running"
+	jobSucceeded = "succeeded"
+	jobFailed    = "failed"
+
+	// jobHandedOver is a job that this instance passed to the leader to run, see leaderElection
+	jobHandedOver = "handed_over"
+
+	defaultJobWorkers = 4
+
+	// finished jobs are kept in memory for this long, so that their status can be checked
+	jobRetention = 24 * time.Hour
+)
+
+// job is an action on a cert that is run in the background, as it may take some time talking to the CA
+type job struct {
+	ID       string     `json:"id"`
+	Kind     string     `json:"kind"`
+	Hostname string     `json:"hostname"`
+	Source   string     `json:"source,omitempty"`
+	Actor    string     `json:"actor"`
+	Status   string     `json:"status"`
+	Step     string     `json:"step,omitempty"` // the last step reached, e.g. "waiting for authorization"
+	Error    string     `json:"error,omitempty"`
+	Created  time.Time  `json:"created"`
+	Started  *time.Time `json:"started,omitempty"`
+	Finished *time.Time `json:"finished,omitempty"`
+}
+
+// jobQueue runs jobs with a pool of workers. Jobs are only kept in memory.
+type jobQueue struct {
+	Workers int `yaml:"workers"`
+
+	ctx context.Context
+	run func(ctx context.Context, j *job) error
+
+	lock    sync.Mutex
+	cond    *sync.Cond
+	queued  []*job
+	running map[string]bool // by hostname, so that we only run one job at a time for each
+	jobs    map[string]*job // by ID
+	stopped bool            // once set, no more jobs are started
+	active  sync.WaitGroup  // jobs running
+}
+
+var errJobsStopped = errors.New("shutting down, so not taking new jobs")
+
+// Init starts the workers, which will call run for each job with a context derived from ctx
+func (jq *jobQueue) Init(ctx context.Context, run func(ctx context.Context, j *job) error) error {
+	if jq.Workers == 0 {
+		jq.Workers = defaultJobWorkers
+	}
+	if jq.Workers < 0 {
+		return errors.New("job workers must not be negative")
+	}
+
+	jq.ctx = ctx
+	jq.run = run
+	jq.cond = sync.NewCond(&jq.lock)
+	jq.running = make(map[string]bool)
+	jq.jobs = make(map[string]*job)
+
+	for i := 0; i < jq.Workers; i++ {
+		go jq.work()
+	}
+
+	return nil
+}
+
+func newJob(kind, hostname, source, actor string) (*job, error) {
+	id := make([]byte, 8)
+	_, err := rand.Read(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &job{
+		ID:       hex.EncodeToString(id),
+		Kind:     kind,
+		Hostname: hostname,
+		Source:   source,
+		Actor:    actor,
+		Status:   jobQueued,
+		Created:  time.Now().UTC(),
+	}, nil
+}
+
+// Enqueue adds a job, returning a copy of it. If a job of the same kind is already queued for the hostname, that is returned instead.
+func (jq *jobQueue) Enqueue(kind, hostname, source, actor string) (*job, error) {
+	j, err := newJob(kind, hostname, source, actor)
+	if err != nil {
+		return nil, err
+	}
+	return jq.enqueue(j)
+}
+
+// EnqueueHandedOver adds a job that another instance handed over to us as leader, keeping its ID
+func (jq *jobQueue) EnqueueHandedOver(ho *job) (*job, error) {
+	j := *ho
+	j.Status, j.Step, j.Finished = jobQueued, "", nil
+	return jq.enqueue(&j)
+}
+
+func (jq *jobQueue) enqueue(j *job) (*job, error) {
+	jq.lock.Lock()
+	defer jq.lock.Unlock()
+
+	if jq.stopped {
+		return nil, errJobsStopped
+	}
+
+	for _, qj := range jq.queued {
+		if qj.Hostname == j.Hostname && qj.Kind == j.Kind {
+			rv := *qj
+			return &rv, nil
+		}
+	}
+
+	jq.jobs[j.ID] = j
+	jq.queued = append(jq.queued, j)
+	jq.cond.Signal()
+
+	rv := *j
+	return &rv, nil
+}
+
+// AddHandedOver records a job that has been handed over to the leader, so that it is still listed here.
+// It is kept for as long as a finished job, as we don't hear how it went.
+func (jq *jobQueue) AddHandedOver(ho *job, leader string) *job {
+	jq.lock.Lock()
+	defer jq.lock.Unlock()
+
+	j := *ho
+	now := time.Now().UTC()
+	// Instances that aren't the leader never finish jobs, so forget old ones here too
+	jq.prune(now)
+	j.Status, j.Finished = jobHandedOver, &now
+	j.Step = "handed over to the leader"
+	if leader != "" {
+		j.Step += ", " + leader
+	}
+	jq.jobs[j.ID] = &j
+
+	rv := j
+	return &rv
+}
+
+// Get returns a copy of the job, or nil if not found
+func (jq *jobQueue) Get(id string) *job {
+	jq.lock.Lock()
+	defer jq.lock.Unlock()
+
+	j, ok := jq.jobs[id]
+	if !ok {
+		return nil
+	}
+	rv := *j
+	return &rv
+}
+
+// List returns copies of all jobs, newest first
+func (jq *jobQueue) List() []*job {
+	jq.lock.Lock()
+	defer jq.lock.Unlock()
+
+	rv := make([]*job, 0, len(jq.jobs))
+	for _, j := range jq.jobs {
+		c := *j
+		rv = append(rv, &c)
+	}
+	sort.Slice(rv, func(i, j int) bool {
+		return rv[i].Created.After(rv[j].Created)
+	})
+	return rv
+}
+
+// next waits for a queued job whose hostname has nothing else running, and marks it as running.
+// Returns nil once stopped.
+func (jq *jobQueue) next() *job {
+	jq.lock.Lock()
+	defer jq.lock.Unlock()
+
+	for {
+		if jq.stopped {
+			return nil
+		}
+		for i, j := range jq.queued {
+			if jq.running[j.Hostname] {
+				continue
+			}
+			jq.queued = append(jq.queued[:i], jq.queued[i+1:]...)
+			jq.running[j.Hostname] = true
+			now := time.Now().UTC()
+			j.Status, j.Started = jobRunning, &now
+			jq.active.Add(1)
+			return j
+		}
+		jq.cond.Wait()
+	}
+}
+
+func (jq *jobQueue) setStep(j *job, step string) {
+	jq.lock.Lock()
+	j.Step = step
+	jq.lock.Unlock()
+}
+
+func (jq *jobQueue) finish(j *job, err error) {
+	jq.lock.Lock()
+	defer jq.lock.Unlock()
+
+	now := time.Now().UTC()
+	j.Finished = &now
+	if err == nil {
+		j.Status = jobSucceeded
+	} else {
+		j.Status, j.Error = jobFailed, err.Error()
+	}
+	delete(jq.running, j.Hostname)
+	jq.prune(now)
+
+	// Another job for the same hostname may now be able to run
+	jq.cond.Broadcast()
+
+	jq.active.Done()
+}
+
+// prune forgets jobs that finished more than jobRetention before now. Must be called with lock held.
+func (jq *jobQueue) prune(now time.Time) {
+	for id, oj := range jq.jobs {
+		if oj.Finished != nil && now.Sub(*oj.Finished) > jobRetention {
+			delete(jq.jobs, id)
+		}
+	}
+}
+
+// Stop stops any more jobs from starting. Those queued are left, and are lost when we exit.
+func (jq *jobQueue) Stop() {
+	jq.lock.Lock()
+	jq.stopped = true
+	jq.cond.Broadcast()
+	jq.lock.Unlock()
+}
+
+// Wait waits for running jobs to finish, and should be called after Stop
+func (jq *jobQueue) Wait() {
+	jq.active.Wait()
+}
+
+func (jq *jobQueue) work() {
+	for {
+		j := jq.next()
+		if j == nil {
+			return
+		}
+		log.Printf("starting %s job %s for %s...\n", j.Kind, j.ID, j.Hostname)
+		err := jq.run(withStepReporter(jq.ctx, func(step string) {
+			jq.setStep(j, step)
+		}), j)
+		if err != nil {
+			log.Printf("%s job %s for %s failed: %s\n", j.Kind, j.ID, j.Hostname, err)
+		}
+		jq.finish(j, err)
+	}
+}
+
+func withStepReporter(ctx context.Context, f func(step string)) context.Context {
+	return context.WithValue(ctx, keyStepReporter, f)
+}
+
+// reportStep records progress for the job running with ctx, if any
+func reportStep(ctx context.Context, format string, args ...interface{}) {
+	f, ok := ctx.Value(keyStepReporter).(func(string))
+	if ok {
+		f(fmt.Sprintf(format, args...))
+	}
+}
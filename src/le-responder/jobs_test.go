@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// waitForJob waits for the job with id to reach status
+func waitForJob(t *testing.T, jq *jobQueue, id, status string) *job {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		j := jq.Get(id)
+		if j != nil && j.Status == status {
+			return j
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s didn't reach %s, got %+v", id, status, j)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestJobQueueDedup(t *testing.T) {
+	release := make(chan struct{})
+	jq := &jobQueue{Workers: 2}
+	err := jq.Init(context.Background(), func(ctx context.Context, j *job) error {
+		<-release
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer jq.Stop()
+
+	// Runs straight away, so is no longer queued
+	first, err := jq.Enqueue(jobRenew, "a.example.gov.au", "le", "tester")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForJob(t, jq, first.ID, jobRunning)
+
+	ids := make(map[string]string) // by step name
+	for _, tc := range []struct {
+		name      string
+		kind      string
+		hostname  string
+		sameAs    string // the step whose job should be returned, or "" for a new one
+		wantState string
+	}{
+		{name: "second renew", kind: jobRenew, hostname: "a.example.gov.au", wantState: jobQueued},
+		{name: "third renew", kind: jobRenew, hostname: "a.example.gov.au", sameAs: "second renew", wantState: jobQueued},
+		{name: "other kind", kind: jobCompromise, hostname: "a.example.gov.au", wantState: jobQueued},
+		{name: "other host", kind: jobRenew, hostname: "b.example.gov.au", wantState: jobRunning},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			j, err := jq.Enqueue(tc.kind, tc.hostname, "le", "tester")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids[tc.name] = j.ID
+			if tc.sameAs != "" && j.ID != ids[tc.sameAs] {
+				t.Fatalf("expected the job queued by %q to be returned", tc.sameAs)
+			}
+			if tc.sameAs == "" && j.ID == first.ID {
+				t.Fatal("expected a new job")
+			}
+			waitForJob(t, jq, j.ID, tc.wantState)
+		})
+	}
+
+	close(release)
+	for _, j := range jq.List() {
+		waitForJob(t, jq, j.ID, jobSucceeded)
+	}
+	if n := len(jq.List()); n != 4 {
+		t.Fatalf("expected 4 jobs, got %d", n)
+	}
+}
+
+func TestJobQueuePrune(t *testing.T) {
+	jq := &jobQueue{Workers: 1}
+	err := jq.Init(context.Background(), func(ctx context.Context, j *job) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer jq.Stop()
+
+	old, err := jq.Enqueue(jobRenew, "old.example.gov.au", "le", "tester")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForJob(t, jq, old.ID, jobSucceeded)
+	oldHandedOver, err := newJob(jobRenew, "old-handed-over.example.gov.au", "le", "tester")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jq.AddHandedOver(oldHandedOver, "leader")
+
+	// Make both look like they finished before the retention period
+	jq.lock.Lock()
+	finished := time.Now().Add(-jobRetention - time.Minute)
+	for _, j := range jq.jobs {
+		j.Finished = &finished
+	}
+	jq.lock.Unlock()
+
+	// Instances that aren't the leader only hand over, which must prune too
+	handedOver, err := newJob(jobRenew, "new-handed-over.example.gov.au", "le", "tester")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jq.AddHandedOver(handedOver, "leader")
+	if jq.Get(old.ID) != nil || jq.Get(oldHandedOver.ID) != nil {
+		t.Fatal("expected old jobs to be pruned when handing over")
+	}
+
+	current, err := jq.Enqueue(jobRenew, "current.example.gov.au", "le", "tester")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForJob(t, jq, current.ID, jobSucceeded)
+	if n := len(jq.List()); n != 2 {
+		t.Fatalf("expected only recent jobs to be kept, got %d", n)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// Key types that may be configured for sources and certs
+const (
+	keyTypeRSA2048   = "rsa2048"
+	keyTypeRSA3072   = "rsa3072"
+	keyTypeRSA4096   = "rsa4096"
+	keyTypeECDSAP256 = "ecdsa-p256"
+	keyTypeECDSAP384 = "ecdsa-p384"
+	keyTypeEd25519   = "ed25519" // not accepted by public CAs, but fine for internal use
+
+	defaultKeyType = keyTypeRSA2048
+)
+
+// keyTypes is the list shown in the UI
+var keyTypes = []string{
+	keyTypeRSA2048,
+	keyTypeRSA3072,
+	keyTypeRSA4096,
+	keyTypeECDSAP256,
+	keyTypeECDSAP384,
+	keyTypeEd25519,
+}
+
+func validateKeyType(kt string) error {
+	for _, k := range keyTypes {
+		if k == kt {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown key type: %s", kt)
+}
+
+func generateKey(kt string) (crypto.Signer, error) {
+	switch kt {
+	case keyTypeRSA2048:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case keyTypeRSA3072:
+		return rsa.GenerateKey(rand.Reader, 3072)
+	case keyTypeRSA4096:
+		return rsa.GenerateKey(rand.Reader, 4096)
+	case keyTypeECDSAP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case keyTypeECDSAP384:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case keyTypeEd25519:
+		_, k, err := ed25519.GenerateKey(rand.Reader)
+		return k, err
+	default:
+		return nil, fmt.Errorf("unknown key type: %s", kt)
+	}
+}
+
+// keyTypeOf returns the key type for a public key, or empty string if not one we know
+func keyTypeOf(pub crypto.PublicKey) string {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		switch k.N.BitLen() {
+		case 2048:
+			return keyTypeRSA2048
+		case 3072:
+			return keyTypeRSA3072
+		case 4096:
+			return keyTypeRSA4096
+		}
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return keyTypeECDSAP256
+		case elliptic.P384():
+			return keyTypeECDSAP384
+		}
+	case ed25519.PublicKey:
+		return keyTypeEd25519
+	}
+	return ""
+}
+
+// keyAlgorithm returns "rsa", "ecdsa" or "ed25519", as used for HAProxy multi-cert bundle extensions
+func keyAlgorithm(kt string) string {
+	switch kt {
+	case keyTypeRSA2048, keyTypeRSA3072, keyTypeRSA4096:
+		return "rsa"
+	case keyTypeECDSAP256, keyTypeECDSAP384:
+		return "ecdsa"
+	default:
+		return kt
+	}
+}
+
+// marshalPrivateKey PEM encodes the key. RSA keys are PKCS#1 as they always have been,
+// ECDSA keys are SEC 1 "EC PRIVATE KEY" and anything else is PKCS#8.
+func marshalPrivateKey(key crypto.Signer) (string, error) {
+	var block *pem.Block
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		block = &pem.Block{
+			Bytes: x509.MarshalPKCS1PrivateKey(k),
+			Type:  "RSA PRIVATE KEY",
+		}
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			return "", err
+		}
+		block = &pem.Block{
+			Bytes: der,
+			Type:  "EC PRIVATE KEY",
+		}
+	default:
+		der, err := x509.MarshalPKCS8PrivateKey(k)
+		if err != nil {
+			return "", err
+		}
+		block = &pem.Block{
+			Bytes: der,
+			Type:  "PRIVATE KEY",
+		}
+	}
+	return string(pem.EncodeToMemory(block)), nil
+}
+
+// parsePrivateKey is the inverse of marshalPrivateKey
+func parsePrivateKey(s string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, errors.New("no private key found in pem")
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		rv, ok := k.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("unsupported private key type")
+		}
+		return rv, nil
+	default:
+		return nil, errors.New("invalid private key found in pem")
+	}
+}
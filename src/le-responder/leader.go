@@ -0,0 +1,289 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// leaderLeasePath is where the lease is kept in storage
+	leaderLeasePath = "/leader"
+
+	// leaderPendingPath is where other instances leave work for the leader
+	leaderPendingPath = "/leader-pending"
+
+	defaultLeaseSeconds = 60
+
+	// leaderPendingInterval is how often the leader picks up work left by other instances
+	leaderPendingInterval = 10 * time.Second
+)
+
+// leaderLease is held by the instance that runs the periodic scan and updates observers
+type leaderLease struct {
+	Holder   string    `json:"holder"`
+	Acquired time.Time `json:"acquired"`
+	Expires  time.Time `json:"expires"`
+}
+
+// leaderPending is work left for the leader by other instances
+type leaderPending struct {
+	Update bool   `json:"update"` // certs have changed, so observers should be updated
+	Jobs   []*job `json:"jobs"`
+}
+
+// leaderElection decides which instance is the leader, using a lease in storage that is renewed every
+// third of its length. If not enabled, this instance is always the leader. Instances' clocks must roughly agree.
+type leaderElection struct {
+	Enabled      bool   `yaml:"enabled"`
+	ID           string `yaml:"id"`            // must be unique to each instance, defaults to the hostname
+	LeaseSeconds int    `yaml:"lease_seconds"` // defaults to defaultLeaseSeconds
+
+	storage certStorage
+
+	lock     sync.Mutex
+	holder   string    // as last seen, "" if not known
+	expires  time.Time // when our lease ends, if we hold it
+	resigned bool      // once set, we don't campaign again
+	term     int       // incremented each time we become the leader
+}
+
+func (le *leaderElection) Init(storage certStorage) error {
+	if le.ID == "" {
+		hn, err := os.Hostname()
+		if err != nil {
+			return err
+		}
+		le.ID = hn
+	}
+	if le.LeaseSeconds == 0 {
+		le.LeaseSeconds = defaultLeaseSeconds
+	}
+	if le.LeaseSeconds < 3 {
+		return errors.New("leader election lease_seconds must be at least 3")
+	}
+	le.storage = storage
+
+	// The lease needs a real compare-and-set, or two instances can both think they lead
+	if _, ok := storage.(*certStore); ok && le.Enabled {
+		return errors.New("leader election needs the directory or vault storage, as credhub can't compare and set")
+	}
+	if _, ok := storage.(*fileStore); ok && le.Enabled && !fileLocksShared {
+		return errFileCompareAndSetUnsupported
+	}
+
+	if !le.Enabled {
+		le.holder = le.ID
+	}
+	le.updateMetric()
+
+	return nil
+}
+
+func (le *leaderElection) leaseDuration() time.Duration {
+	return time.Duration(le.LeaseSeconds) * time.Second
+}
+
+// IsLeader returns true if we hold the lease. We stop acting as leader once it ends, even if we
+// couldn't reach storage to renew it.
+func (le *leaderElection) IsLeader() bool {
+	if !le.Enabled {
+		return true
+	}
+
+	le.lock.Lock()
+	defer le.lock.Unlock()
+
+	return le.holder == le.ID && time.Now().Before(le.expires)
+}
+
+// Leader returns the ID of the leader as last seen, or "" if not known
+func (le *leaderElection) Leader() string {
+	le.lock.Lock()
+	defer le.lock.Unlock()
+
+	return le.holder
+}
+
+// Term returns a number that changes each time we become the leader, so that state cached while
+// leading can be discarded if another instance may have changed it since
+func (le *leaderElection) Term() int {
+	le.lock.Lock()
+	defer le.lock.Unlock()
+
+	return le.term
+}
+
+func (le *leaderElection) setHolder(holder string, expires time.Time) {
+	le.lock.Lock()
+	now := time.Now()
+	if holder == le.ID && !(le.holder == le.ID && now.Before(le.expires)) {
+		// Another instance may have led since we last did, even if we never saw it
+		le.term++
+	}
+	if holder != le.holder {
+		switch {
+		case holder == le.ID:
+			log.Println("we are now the leader")
+		case le.holder == le.ID:
+			log.Printf("we are no longer the leader, now: %q\n", holder)
+		default:
+			log.Printf("leader is now: %q\n", holder)
+		}
+	}
+	le.holder, le.expires = holder, expires
+	le.lock.Unlock()
+
+	le.updateMetric()
+}
+
+func (le *leaderElection) updateMetric() {
+	metricLeader.Reset()
+	v := 0.0
+	if le.IsLeader() {
+		v = 1
+	}
+	metricLeader.WithLabelValues(le.ID, le.Leader()).Set(v)
+}
+
+// campaign takes the lease if it is free or has expired, or renews it if we hold it
+func (le *leaderElection) campaign() error {
+	le.lock.Lock()
+	resigned := le.resigned
+	le.lock.Unlock()
+	if resigned {
+		return nil
+	}
+
+	// Measured from before we talk to storage, so that we give up the lease before others can take it
+	start := time.Now()
+
+	var lease leaderLease
+	version, err := le.storage.LoadDataVersion(leaderLeasePath, &lease)
+	if err != nil {
+		if err != errCertNotFound {
+			return err
+		}
+		version, lease = "", leaderLease{}
+	}
+
+	if lease.Holder != "" && lease.Holder != le.ID && start.Before(lease.Expires) {
+		le.setHolder(lease.Holder, time.Time{})
+		return nil
+	}
+
+	if lease.Holder != le.ID {
+		lease.Acquired = start.UTC()
+	}
+	lease.Holder = le.ID
+	lease.Expires = start.UTC().Add(le.leaseDuration())
+
+	err = le.storage.SaveDataIfVersion(leaderLeasePath, version, &lease)
+	if err != nil {
+		if err == errVersionConflict {
+			// Someone else got there first, we'll see who on our next go
+			le.setHolder("", time.Time{})
+			return nil
+		}
+		return err
+	}
+
+	le.setHolder(le.ID, lease.Expires)
+	return nil
+}
+
+// RunForever keeps campaigning for the lease, if enabled, until stopping is closed
+func (le *leaderElection) RunForever(stopping <-chan struct{}) {
+	if !le.Enabled {
+		return
+	}
+	for {
+		err := le.campaign()
+		if err != nil {
+			metricErrors.WithLabelValues("leader_election").Inc()
+			log.Println("error campaigning for leader, will try again soon:", err)
+			le.updateMetric() // as our lease may have run out
+		}
+		select {
+		case <-time.After(le.leaseDuration() / 3):
+		case <-stopping:
+			return
+		}
+	}
+}
+
+// Resign gives up the lease if we hold it, so that another instance can take over without waiting for it to expire
+func (le *leaderElection) Resign() {
+	if !le.Enabled {
+		return
+	}
+
+	le.lock.Lock()
+	le.resigned = true
+	le.lock.Unlock()
+
+	var lease leaderLease
+	version, err := le.storage.LoadDataVersion(leaderLeasePath, &lease)
+	if err != nil || lease.Holder != le.ID {
+		return
+	}
+	lease.Expires = time.Now().UTC()
+	err = le.storage.SaveDataIfVersion(leaderLeasePath, version, &lease)
+	if err != nil {
+		log.Println("error giving up the leader lease, it will expire:", err)
+		return
+	}
+	le.setHolder("", time.Time{})
+	log.Println("gave up the leader lease")
+}
+
+// handOver updates the work left for the leader, retrying if another instance changes it at the same time
+func (le *leaderElection) handOver(f func(lp *leaderPending)) error {
+	for i := 0; i < 5; i++ {
+		var lp leaderPending
+		version, err := le.storage.LoadDataVersion(leaderPendingPath, &lp)
+		if err != nil {
+			if err != errCertNotFound {
+				return err
+			}
+			version, lp = "", leaderPending{}
+		}
+
+		f(&lp)
+
+		err = le.storage.SaveDataIfVersion(leaderPendingPath, version, &lp)
+		if err != errVersionConflict {
+			return err
+		}
+	}
+	return errors.New("too many conflicting changes handing over to the leader, try again")
+}
+
+// takePending returns the work left for the leader, and clears it
+func (le *leaderElection) takePending() (*leaderPending, error) {
+	for i := 0; i < 5; i++ {
+		var lp leaderPending
+		version, err := le.storage.LoadDataVersion(leaderPendingPath, &lp)
+		if err != nil {
+			if err == errCertNotFound {
+				return &lp, nil
+			}
+			return nil, err
+		}
+		if !lp.Update && len(lp.Jobs) == 0 {
+			return &lp, nil
+		}
+
+		err = le.storage.SaveDataIfVersion(leaderPendingPath, version, &leaderPending{})
+		if err == nil {
+			return &lp, nil
+		}
+		if err != errVersionConflict {
+			return nil, err
+		}
+	}
+	return nil, errors.New("too many conflicting changes taking work handed over to the leader")
+}
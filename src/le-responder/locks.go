@@ -0,0 +1,63 @@
+package main
+
+import (
+	"sort"
+	"sync"
+)
+
+// keyedMutex allows locking by name, e.g. so that only one order at a time can use a hostname
+type keyedMutex struct {
+	lock  sync.Mutex
+	locks map[string]*keyedMutexEntry
+}
+
+type keyedMutexEntry struct {
+	sync.Mutex
+	refs int
+}
+
+// Lock locks all of the keys, waiting until it can, and returns a func to unlock them.
+// Keys are always locked in the same order, so that callers with overlapping keys can't deadlock.
+func (km *keyedMutex) Lock(keys ...string) func() {
+	seen := make(map[string]bool)
+	var sorted []string
+	for _, k := range keys {
+		if !seen[k] {
+			seen[k] = true
+			sorted = append(sorted, k)
+		}
+	}
+	sort.Strings(sorted)
+
+	km.lock.Lock()
+	if km.locks == nil {
+		km.locks = make(map[string]*keyedMutexEntry)
+	}
+	entries := make([]*keyedMutexEntry, len(sorted))
+	for i, k := range sorted {
+		e, ok := km.locks[k]
+		if !ok {
+			e = &keyedMutexEntry{}
+			km.locks[k] = e
+		}
+		e.refs++
+		entries[i] = e
+	}
+	km.lock.Unlock()
+
+	for _, e := range entries {
+		e.Lock()
+	}
+
+	return func() {
+		km.lock.Lock()
+		defer km.lock.Unlock()
+		for i, e := range entries {
+			e.Unlock()
+			e.refs--
+			if e.refs == 0 {
+				delete(km.locks, sorted[i])
+			}
+		}
+	}
+}
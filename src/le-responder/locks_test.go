@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyedMutex(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		held   []string
+		wanted []string
+		blocks bool
+	}{
+		{name: "same key", held: []string{"a"}, wanted: []string{"a"}, blocks: true},
+		{name: "different key", held: []string{"a"}, wanted: []string{"b"}, blocks: false},
+		{name: "overlapping keys", held: []string{"a", "b"}, wanted: []string{"c", "b"}, blocks: true},
+		{name: "duplicate keys", held: []string{"a", "a"}, wanted: []string{"b", "b"}, blocks: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var km keyedMutex
+			unlock := km.Lock(tc.held...)
+
+			locked := make(chan func())
+			go func() {
+				locked <- km.Lock(tc.wanted...)
+			}()
+
+			var unlockWanted func()
+			select {
+			case unlockWanted = <-locked:
+				if tc.blocks {
+					t.Fatal("expected to wait for the keys held")
+				}
+			case <-time.After(100 * time.Millisecond):
+				if !tc.blocks {
+					t.Fatal("expected not to wait for other keys")
+				}
+			}
+
+			unlock()
+			if unlockWanted == nil {
+				select {
+				case unlockWanted = <-locked:
+				case <-time.After(5 * time.Second):
+					t.Fatal("expected to get the keys once unlocked")
+				}
+			}
+			unlockWanted()
+
+			if len(km.locks) != 0 {
+				t.Fatalf("expected no keys to be left once all are unlocked, got %d", len(km.locks))
+			}
+		})
+	}
+}
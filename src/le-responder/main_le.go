@@ -5,22 +5,64 @@ package main
 import (
 	"flag"
 	log "github.com/sirupsen/logrus"
+	"os"
 )
 
 func main() {
 	var configPath string
 	var daemon bool
+	var migrateFrom, migrateTo string
+	var dryRun bool
 
 	flag.StringVar(&configPath, "config", "", "Path to config file - required")
 	flag.BoolVar(&daemon, "daemon", false, "If set, run as a daemon, and reload pid each time")
+	flag.StringVar(&migrateFrom, "migrate-from", "", "If set, copy all certs from this storage (credhub, directory or vault) to -migrate-to, then exit")
+	flag.StringVar(&migrateTo, "migrate-to", "", "Storage to copy certs to, must also be configured in the config file")
+	flag.BoolVar(&dryRun, "dry-run", false, "If set with -migrate-from, report what would be copied without writing anything")
 	flag.Parse()
 
+	if migrateFrom != "" || migrateTo != "" {
+		runMigration(configPath, migrateFrom, migrateTo, dryRun)
+		return
+	}
+
 	conf, err := newConf(configPath)
 	if err != nil {
 		log.Fatal("error parsing config file", err)
 	}
 
 	if daemon {
-		conf.RunForever()
+		conf.RunForever(configPath)
+	}
+}
+
+func runMigration(configPath, migrateFrom, migrateTo string, dryRun bool) {
+	if migrateFrom == "" || migrateTo == "" || migrateFrom == migrateTo {
+		log.Fatal("-migrate-from and -migrate-to must both be specified, and be different")
+	}
+
+	conf, err := loadConf(configPath)
+	if err != nil {
+		log.Fatal("error parsing config file", err)
+	}
+
+	from, err := conf.storage(migrateFrom)
+	if err != nil {
+		log.Fatal("error initialising source storage: ", err)
+	}
+	to, err := conf.storage(migrateTo)
+	if err != nil {
+		log.Fatal("error initialising destination storage: ", err)
+	}
+
+	// Only the retention period is needed, not the rest of the audit log's config
+	err = conf.Audit.Init(from)
+	if err != nil {
+		log.Fatal("error initialising audit log: ", err)
+	}
+
+	err = migrateStorage(from, to, conf.Audit.RetentionDays, dryRun, os.Stdout)
+	if err != nil {
+		log.Fatal(err)
 	}
 }
@@ -1,6 +1,12 @@
 package main
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"context"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/crypto/acme"
+)
 
 var (
 	metricErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
@@ -9,14 +15,114 @@ var (
 	metricIssued = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: "le_responder_certificates_total",
 	}, []string{"source"})
+	metricRevoked = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "le_responder_revocations_total",
+		Help: "Certs revoked, by source and reason",
+	}, []string{"source", "reason"})
 	metricHealth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "le_responder_health",
 	}, []string{"task"})
+	metricLeader = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "le_responder_leader",
+		Help: "1 if this instance is the leader. Labelled with this instance's ID, and the leader's as last seen.",
+	}, []string{"id", "leader"})
+	metricRenewalFailures = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "le_responder_renewal_consecutive_failures",
+		Help: "Number of attempts to issue a cert that have failed since the last success",
+	}, []string{"hostname"})
+	metricRenewalFailingSince = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "le_responder_renewal_failing_since_timestamp_seconds",
+		Help: "When the first of the consecutive failures happened, or 0 if not failing",
+	}, []string{"hostname"})
+
+	// Per cert, refreshed on each scan and whenever observers are updated
+	metricCertNotAfter = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "le_responder_certificate_not_after_timestamp_seconds",
+		Help: "When the cert expires",
+	}, []string{"hostname", "source"})
+	metricCertExpiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "le_responder_certificate_expiry_seconds",
+		Help: "Seconds until the cert expires, as of the last refresh",
+	}, []string{"hostname", "source"})
+	metricCertDaysBefore = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "le_responder_certificate_renew_days_before",
+		Help: "How many days before expiry the cert will be renewed. For the ari policy, this is the fallback if the CA can't say.",
+	}, []string{"hostname", "source"})
+	metricCertChallengePending = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "le_responder_certificate_challenge_pending",
+		Help: "1 if a manual challenge has been started and not completed",
+	}, []string{"hostname", "source"})
+	metricCertLastAttempt = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "le_responder_certificate_last_renewal_attempt_timestamp_seconds",
+		Help: "When we last tried to issue the cert, or 0 if never",
+	}, []string{"hostname", "source"})
+	metricCertLastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "le_responder_certificate_last_renewal_success_timestamp_seconds",
+		Help: "When we last issued the cert, or 0 if never",
+	}, []string{"hostname", "source"})
+
+	metricACMEStepDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "le_responder_acme_step_duration_seconds",
+		Help:    "How long each step of an ACME order takes",
+		Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 20, 30, 60},
+	}, []string{"source", "step"})
+	metricACMEErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "le_responder_acme_errors_total",
+		Help: "Errors from ACME servers, by problem type, e.g. rateLimited",
+	}, []string{"source", "type"})
 )
 
+// resetCertMetrics removes all per-cert values, before they are set again for the current certs
+func resetCertMetrics() {
+	metricCertNotAfter.Reset()
+	metricCertExpiry.Reset()
+	metricCertDaysBefore.Reset()
+	metricCertChallengePending.Reset()
+	metricCertLastAttempt.Reset()
+	metricCertLastSuccess.Reset()
+}
+
+// acmeProblemType returns a short name for the type of error returned by the ACME client, for metrics
+func acmeProblemType(err error) string {
+	switch e := err.(type) {
+	case *acme.Error:
+		if e.ProblemType == "" {
+			return "unknown"
+		}
+		bits := strings.Split(e.ProblemType, ":")
+		return bits[len(bits)-1]
+	case *acme.AuthorizationError:
+		if len(e.Errors) != 0 {
+			if _, ok := e.Errors[0].(*acme.Error); ok {
+				return acmeProblemType(e.Errors[0])
+			}
+		}
+		return "authorization"
+	case *acme.OrderError:
+		return "order"
+	default:
+		if err == context.DeadlineExceeded {
+			return "timeout"
+		}
+		return "other"
+	}
+}
+
 func init() {
 	// Metrics have to be registered to be exposed:
 	prometheus.MustRegister(metricErrors)
 	prometheus.MustRegister(metricIssued)
+	prometheus.MustRegister(metricRevoked)
 	prometheus.MustRegister(metricHealth)
+	prometheus.MustRegister(metricLeader)
+	prometheus.MustRegister(metricRenewalFailures)
+	prometheus.MustRegister(metricRenewalFailingSince)
+	prometheus.MustRegister(metricCertNotAfter)
+	prometheus.MustRegister(metricCertExpiry)
+	prometheus.MustRegister(metricCertDaysBefore)
+	prometheus.MustRegister(metricCertChallengePending)
+	prometheus.MustRegister(metricCertLastAttempt)
+	prometheus.MustRegister(metricCertLastSuccess)
+	prometheus.MustRegister(metricACMEStepDuration)
+	prometheus.MustRegister(metricACMEErrors)
 }
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"text/tabwriter"
+	"time"
+)
+
+// migratedDataPaths are the non-cert paths that we also copy, along with the audit log
+var migratedDataPaths = []string{
+	apiTokensPath,
+	caKeysPath,
+	caRevocationsPath,
+	renewalStatePath,
+}
+
+// auditDataPaths returns the paths of audit entries in from that are within the retention period,
+// looking back as far as auditLog prunes, so that anything not yet pruned is kept
+func auditDataPaths(from certStorage, retentionDays int) ([]string, error) {
+	var rv []string
+	now := time.Now()
+	for i := 0; i < retentionDays+7; i++ {
+		day := auditPath(now.AddDate(0, 0, -i))
+
+		// Days written by older versions
+		var v json.RawMessage
+		err := from.LoadData(day, &v)
+		switch err {
+		case nil:
+			rv = append(rv, day)
+		case errCertNotFound:
+		default:
+			return nil, err
+		}
+
+		paths, err := from.ListData(day + "/")
+		if err != nil {
+			return nil, err
+		}
+		rv = append(rv, paths...)
+	}
+	return rv, nil
+}
+
+// storedCertFingerprint returns a hash over everything we store for a cert, so that two copies can be compared
+func storedCertFingerprint(chc *credhubCert) (string, error) {
+	data, err := json.Marshal(chc)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:]), nil
+}
+
+// migrateStorage copies the current version of every cert in from to to, verifying each by reading
+// it back, along with other data and the audit entries from the last auditRetentionDays.
+// A report is written to w. If dryRun is set, nothing is written.
+// Returns an error if anything could not be copied.
+func migrateStorage(from, to certStorage, auditRetentionDays int, dryRun bool, w io.Writer) error {
+	certs, err := from.FetchCerts()
+	if err != nil {
+		return err
+	}
+	auditPaths, err := auditDataPaths(from, auditRetentionDays)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "HOSTNAME\tFINGERPRINT\tRESULT")
+
+	failed := 0
+	for _, chc := range certs {
+		status, fp, err := migrateCert(chc, to, dryRun)
+		if err != nil {
+			failed++
+			status = "FAILED: " + err.Error()
+		}
+		if len(fp) > 16 {
+			fp = fp[:16]
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", hostFromPath(chc.path), fp, status)
+	}
+
+	for _, path := range append(migratedDataPaths, auditPaths...) {
+		status, err := migrateData(path, from, to, dryRun)
+		if err != nil {
+			failed++
+			status = "FAILED: " + err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t\t%s\n", path, status)
+	}
+
+	err = tw.Flush()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\n%d certs and %d audit log items found, %d failed", len(certs), len(auditPaths), failed)
+	if dryRun {
+		fmt.Fprint(w, " (dry run, nothing written)")
+	}
+	fmt.Fprintln(w)
+
+	if failed != 0 {
+		return fmt.Errorf("%d certs or data items failed to migrate", failed)
+	}
+	return nil
+}
+
+// migrateCert copies a single cert, returning a status message and its fingerprint
+func migrateCert(chc *credhubCert, to certStorage, dryRun bool) (string, string, error) {
+	fp, err := storedCertFingerprint(chc)
+	if err != nil {
+		return "", "", err
+	}
+
+	existing, err := to.LoadPath(chc.path)
+	switch err {
+	case nil:
+		efp, err := storedCertFingerprint(existing)
+		if err != nil {
+			return "", fp, err
+		}
+		if efp == fp {
+			return "already present", fp, nil
+		}
+		if dryRun {
+			return "would overwrite", fp, nil
+		}
+	case errCertNotFound:
+		if dryRun {
+			return "would copy", fp, nil
+		}
+	default:
+		return "", fp, err
+	}
+
+	err = to.SavePath(chc.path, chc)
+	if err != nil {
+		return "", fp, err
+	}
+
+	saved, err := to.LoadPath(chc.path)
+	if err != nil {
+		return "", fp, err
+	}
+	sfp, err := storedCertFingerprint(saved)
+	if err != nil {
+		return "", fp, err
+	}
+	if sfp != fp {
+		return "", fp, fmt.Errorf("fingerprint mismatch after copy: %s", sfp)
+	}
+
+	return "copied", fp, nil
+}
+
+// migrateData copies other data, which has no history
+func migrateData(path string, from, to certStorage, dryRun bool) (string, error) {
+	var v json.RawMessage
+	err := from.LoadData(path, &v)
+	switch err {
+	case nil:
+	case errCertNotFound:
+		return "not present", nil
+	default:
+		return "", err
+	}
+
+	if dryRun {
+		return "would copy", nil
+	}
+
+	err = to.SaveData(path, v)
+	if err != nil {
+		return "", err
+	}
+
+	var saved json.RawMessage
+	err = to.LoadData(path, &saved)
+	if err != nil {
+		return "", err
+	}
+	if !jsonEqual(v, saved) {
+		return "", errors.New("contents differ after copy")
+	}
+
+	return "copied", nil
+}
+
+// jsonEqual compares JSON values, ignoring formatting and key order
+func jsonEqual(a, b []byte) bool {
+	var av, bv interface{}
+	if json.Unmarshal(a, &av) != nil || json.Unmarshal(b, &bv) != nil {
+		return false
+	}
+	return reflect.DeepEqual(av, bv)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMigrateStorage(t *testing.T) {
+	from := newTestFileStore(t)
+	to := &fileStore{
+		Path:          t.TempDir(),
+		EncryptionKey: "another-encryption-key",
+	}
+	err := to.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, hn := range []string{"www.example.gov.au", "api.example.gov.au"} {
+		err = from.SavePath(pathFromHost(hn), &credhubCert{
+			Source:      "self",
+			Type:        "user",
+			Certificate: "cert for " + hn,
+			PrivateKey:  "key for " + hn,
+			SANs:        []string{"alt." + hn},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = from.SaveData(renewalStatePath, &renewalStateList{Hosts: map[string]*renewalState{
+		"www.example.gov.au": {ConsecutiveFailures: 2, LastError: "timeout"},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	audit := &auditLog{}
+	err = audit.Init(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	audit.Finish(audit.Start("tester", "create", "www.example.gov.au", "self"), nil)
+
+	// migrate runs it, and returns the report
+	migrate := func(dryRun bool) string {
+		var buf bytes.Buffer
+		err := migrateStorage(from, to, 30, dryRun, &buf)
+		if err != nil {
+			t.Fatalf("%s\n%s", err, buf.String())
+		}
+		return buf.String()
+	}
+
+	report := migrate(true)
+	if !strings.Contains(report, "2 certs and 1 audit log items found, 0 failed (dry run, nothing written)") {
+		t.Fatalf("unexpected dry run report:\n%s", report)
+	}
+	if strings.Count(report, "would copy") != 4 { // 2 certs, renewal state and the audit entry
+		t.Fatalf("expected 4 items to be copied:\n%s", report)
+	}
+	certs, err := to.FetchCerts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certs) != 0 {
+		t.Fatalf("expected a dry run to write nothing, got %d certs", len(certs))
+	}
+	var rsl renewalStateList
+	if err = to.LoadData(renewalStatePath, &rsl); err != errCertNotFound {
+		t.Fatalf("expected a dry run to write nothing, got %v", err)
+	}
+
+	report = migrate(false)
+	if strings.Count(report, "copied") != 4 {
+		t.Fatalf("expected 4 items to be copied:\n%s", report)
+	}
+	for _, hn := range []string{"www.example.gov.au", "api.example.gov.au"} {
+		want, err := from.LoadPath(pathFromHost(hn))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := to.LoadPath(pathFromHost(hn))
+		if err != nil {
+			t.Fatal(err)
+		}
+		wfp, _ := storedCertFingerprint(want)
+		gfp, _ := storedCertFingerprint(got)
+		if wfp != gfp {
+			t.Fatalf("%s: expected the same cert after migrating, got %+v", hn, got)
+		}
+	}
+	err = to.LoadData(renewalStatePath, &rsl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs := rsl.Hosts["www.example.gov.au"]; rs == nil || rs.LastError != "timeout" {
+		t.Fatalf("expected renewal state to be copied, got %+v", rsl)
+	}
+	entries, err := to.ListData(auditPath(time.Now()) + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected the audit entry to be copied, got %v", entries)
+	}
+
+	// Running again finds the certs already present, and a changed one would be overwritten
+	err = to.SavePath(pathFromHost("api.example.gov.au"), &credhubCert{Source: "self", Certificate: "changed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	report = migrate(true)
+	if strings.Count(report, "already present") != 1 || strings.Count(report, "would overwrite") != 1 {
+		t.Fatalf("unexpected report:\n%s", report)
+	}
+	migrate(false)
+	got, err := to.LoadPath(pathFromHost("api.example.gov.au"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Certificate != "cert for api.example.gov.au" {
+		t.Fatalf("expected the changed cert to be overwritten, got %q", got.Certificate)
+	}
+}
@@ -93,11 +93,22 @@ type outputObserver struct {
 	S3  []*bucket `yaml:"s3"`
 	ACM []*acmObs `yaml:"acm"`
 
+	// TrustBundle, if set, is the name in the tarball to write the CA certs of any ca sources to,
+	// e.g. trust/ca-bundle.pem, so that clients of internal hostnames can be configured to trust them.
+	TrustBundle string `yaml:"trust_bundle"`
+
 	ssOracle shouldShipOracle
+	bundler  trustBundler
+}
+
+type trustBundler interface {
+	// TrustBundle returns the PEM CA certs that sources sign with, if any
+	TrustBundle() (string, error)
 }
 
-func (n *outputObserver) Init(ssOracle shouldShipOracle) error {
+func (n *outputObserver) Init(ssOracle shouldShipOracle, bundler trustBundler) error {
 	n.ssOracle = ssOracle
+	n.bundler = bundler
 	return nil
 }
 
@@ -116,30 +127,50 @@ func (n *outputObserver) createTarball(certs []*credhubCert) ([]byte, error) {
 			// not issued yet, skip
 			continue
 		}
+		if cert.PrivateKey == "" {
+			// issued for a CSR, the key is held elsewhere so a proxy can't use it
+			continue
+		}
 
 		he := hex.EncodeToString([]byte(hn))
 
-		certBytes := []byte(strings.Join([]string{
-			strings.TrimSpace(cert.PrivateKey),
-			strings.TrimSpace(cert.Certificate),
-			strings.TrimSpace(cert.CA),
-			"", // so that we have a trailing new line
-		}, "\n"))
-
-		err := tarWriter.WriteHeader(&tar.Header{
-			Name:     he + ".crt",
-			Mode:     0600,
-			Size:     int64(len(certBytes)),
-			Typeflag: tar.TypeReg,
-			ModTime:  cert.dateCreated,
-		})
+		err := writeCertToTarball(tarWriter, he+".crt", cert.PrivateKey, cert.Certificate, cert.CA, cert)
 		if err != nil {
 			return nil, err
 		}
-		_, err = tarWriter.Write(certBytes)
+
+		// A second cert for a different key type is named per the HAProxy multi-cert bundle
+		// convention, e.g. <name>.crt.ecdsa
+		if cert.Secondary != nil {
+			err = writeCertToTarball(tarWriter, he+".crt."+keyAlgorithm(cert.Secondary.KeyType), cert.Secondary.PrivateKey, cert.Secondary.Certificate, cert.Secondary.CA, cert)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if n.TrustBundle != "" {
+		// Fail rather than ship without it, as clients would stop trusting certs from ca sources
+		bundle, err := n.bundler.TrustBundle()
 		if err != nil {
 			return nil, err
 		}
+		if len(bundle) != 0 {
+			// No ModTime, so that the tarball is unchanged if nothing else is
+			err = tarWriter.WriteHeader(&tar.Header{
+				Name:     n.TrustBundle,
+				Mode:     0644,
+				Size:     int64(len(bundle)),
+				Typeflag: tar.TypeReg,
+			})
+			if err != nil {
+				return nil, err
+			}
+			_, err = tarWriter.Write([]byte(bundle))
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	err := tarWriter.Close()
@@ -154,6 +185,28 @@ func (n *outputObserver) createTarball(certs []*credhubCert) ([]byte, error) {
 	return buffer.Bytes(), nil
 }
 
+func writeCertToTarball(tarWriter *tar.Writer, name, privateKey, certificate, ca string, cert *credhubCert) error {
+	certBytes := []byte(strings.Join([]string{
+		strings.TrimSpace(privateKey),
+		strings.TrimSpace(certificate),
+		strings.TrimSpace(ca),
+		"", // so that we have a trailing new line
+	}, "\n"))
+
+	err := tarWriter.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     0600,
+		Size:     int64(len(certBytes)),
+		Typeflag: tar.TypeReg,
+		ModTime:  cert.dateCreated,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = tarWriter.Write(certBytes)
+	return err
+}
+
 func (n *outputObserver) CertsAreUpdated(certs []*credhubCert) error {
 	tb, err := n.createTarball(certs)
 	if err != nil {
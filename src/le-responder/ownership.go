@@ -0,0 +1,141 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/govau/cf-common/uaa"
+)
+
+// ownershipConf assigns hostnames to teams, so that teams can only manage their own certs.
+// If no teams are configured, or a hostname matches no team, anyone may manage it (subject to their role).
+type ownershipConf struct {
+	Teams []teamConf `yaml:"teams"`
+}
+
+type teamConf struct {
+	Name string `yaml:"name"`
+
+	// Members are matched by email address, or "client:<client id>" / "token:<name>" for API callers
+	Members []string `yaml:"members"`
+
+	// UAA scopes (groups) that also grant membership
+	Scopes []string `yaml:"scopes"`
+
+	// Domains owned, e.g. "www.example.gov.au", "*.example.gov.au" for any subdomain, or "*" for everything
+	Domains []string `yaml:"domains"`
+}
+
+func (oc *ownershipConf) configured() bool {
+	return len(oc.Teams) != 0
+}
+
+// scopes returns every scope that grants membership of a team, so that we can request them at login
+func (oc *ownershipConf) scopes() []string {
+	var rv []string
+	for _, t := range oc.Teams {
+		rv = append(rv, t.Scopes...)
+	}
+	return rv
+}
+
+// domainMatch returns how specifically pattern matches hostname, or -1 if it doesn't
+func domainMatch(pattern, hostname string) int {
+	pattern = strings.ToLower(pattern)
+	switch {
+	case pattern == "*":
+		return 0
+	case strings.HasPrefix(pattern, "*."):
+		if strings.HasSuffix(hostname, pattern[1:]) {
+			return len(pattern)
+		}
+	case pattern == hostname:
+		return len(pattern) + 1 // an exact match beats a wildcard of the same length
+	}
+	return -1
+}
+
+// teamForHostname returns the team with the most specific domain matching hostname, or empty string if none
+func (oc *ownershipConf) teamForHostname(hostname string) string {
+	hostname = strings.ToLower(hostname)
+	best, rv := -1, ""
+	for _, t := range oc.Teams {
+		for _, d := range t.Domains {
+			m := domainMatch(d, hostname)
+			if m > best {
+				best, rv = m, t.Name
+			}
+		}
+	}
+	return rv
+}
+
+// teamFor returns the team that owns a cert. Certs added before ownership was configured are matched by hostname.
+func (oc *ownershipConf) teamFor(hostname string, chc *credhubCert) string {
+	if chc != nil && chc.Team != "" {
+		return chc.Team
+	}
+	return oc.teamForHostname(hostname)
+}
+
+// teamsFor returns the teams that the identity, or any of the scopes, are members of
+func (oc *ownershipConf) teamsFor(identity string, scopes []string) []string {
+	var rv []string
+	for _, t := range oc.Teams {
+		member := stringInList(identity, t.Members)
+		for _, s := range scopes {
+			if stringInList(s, t.Scopes) {
+				member = true
+			}
+		}
+		if member {
+			rv = append(rv, t.Name)
+		}
+	}
+	return rv
+}
+
+// teamsForRequest returns the teams of the logged in user making the request
+func (as *adminServer) teamsForRequest(r *http.Request, liu *uaa.LoggedInUser) []string {
+	return as.ownership().teamsFor(liu.EmailAddress, tokenScopes(liu.AccessToken))
+}
+
+// canManageTeam returns true if the logged in user may manage certs owned by team
+func (as *adminServer) canManageTeam(r *http.Request, liu *uaa.LoggedInUser, team string) bool {
+	if !as.ownership().configured() || team == "" {
+		return true
+	}
+	return stringInList(team, as.teamsForRequest(r, liu))
+}
+
+// checkCanManage returns an error if the logged in user may not manage the cert for hostname, or
+// if it doesn't exist yet, may not create it. Any additional names must also belong to the user's teams.
+func (as *adminServer) checkCanManage(r *http.Request, liu *uaa.LoggedInUser, hostname string, sans []string) error {
+	oc := as.ownership()
+	if !oc.configured() {
+		return nil
+	}
+
+	chc, err := as.storage.LoadPath(pathFromHost(hostname))
+	switch err {
+	case nil:
+	case errCertNotFound:
+		chc = nil
+	default:
+		return err
+	}
+
+	team := oc.teamFor(hostname, chc)
+	if !as.canManageTeam(r, liu, team) {
+		return &apiError{http.StatusForbidden, "this cert is owned by team " + team + ", which you are not a member of"}
+	}
+
+	for _, san := range sans {
+		sanTeam := oc.teamForHostname(san)
+		if sanTeam != team && !as.canManageTeam(r, liu, sanTeam) {
+			return &apiError{http.StatusForbidden, san + " is owned by team " + sanTeam + ", which you are not a member of"}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/govau/cf-common/uaa"
+)
+
+var testOwnership = ownershipConf{Teams: []teamConf{
+	{Name: "platform", Members: []string{"ops@example.gov.au"}, Domains: []string{"*"}},
+	{Name: "web", Scopes: []string{"team.web"}, Domains: []string{"*.example.gov.au"}},
+	{Name: "dev", Members: []string{"dev@example.gov.au"}, Domains: []string{"*.dev.example.gov.au"}},
+	{Name: "api", Members: []string{"client:api"}, Domains: []string{"api.dev.example.gov.au"}},
+}}
+
+func TestTeamForHostname(t *testing.T) {
+	for _, tc := range []struct {
+		hostname string
+		want     string
+	}{
+		{hostname: "www.example.gov.au", want: "web"},
+		{hostname: "WWW.Example.gov.au", want: "web"},
+		{hostname: "example.gov.au", want: "platform"},
+		{hostname: "notexample.gov.au", want: "platform"},
+		{hostname: "www.dev.example.gov.au", want: "dev"},
+		{hostname: "dev.example.gov.au", want: "web"},
+		{hostname: "api.dev.example.gov.au", want: "api"},
+		{hostname: "v2.api.dev.example.gov.au", want: "dev"},
+	} {
+		t.Run(tc.hostname, func(t *testing.T) {
+			if got := testOwnership.teamForHostname(tc.hostname); got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+
+	if got := (&ownershipConf{}).teamForHostname("www.example.gov.au"); got != "" {
+		t.Fatalf("expected no team when none are configured, got %q", got)
+	}
+	if got := testOwnership.teamFor("www.example.gov.au", &credhubCert{Team: "dev"}); got != "dev" {
+		t.Fatalf("expected the team stored with the cert, got %q", got)
+	}
+}
+
+func TestTeamsFor(t *testing.T) {
+	for _, tc := range []struct {
+		identity string
+		scopes   []string
+		want     []string
+	}{
+		{identity: "ops@example.gov.au", want: []string{"platform"}},
+		{identity: "someone@example.gov.au", scopes: []string{"openid", "team.web"}, want: []string{"web"}},
+		{identity: "dev@example.gov.au", scopes: []string{"team.web"}, want: []string{"web", "dev"}},
+		{identity: "someone@example.gov.au"},
+	} {
+		t.Run(tc.identity, func(t *testing.T) {
+			got := testOwnership.teamsFor(tc.identity, tc.scopes)
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCheckCanManage(t *testing.T) {
+	storage := newTestFileStore(t)
+	// Added to dev's team before ownership matched it to web
+	err := storage.SavePath(pathFromHost("legacy.example.gov.au"), &credhubCert{Source: "self", Team: "dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	as := &adminServer{Ownership: testOwnership, storage: storage}
+
+	for _, tc := range []struct {
+		name     string
+		identity string
+		hostname string
+		sans     []string
+		wantErr  bool
+	}{
+		{name: "own team", identity: "dev@example.gov.au", hostname: "www.dev.example.gov.au"},
+		{name: "other team", identity: "dev@example.gov.au", hostname: "www.example.gov.au", wantErr: true},
+		{name: "stored team", identity: "dev@example.gov.au", hostname: "legacy.example.gov.au"},
+		{name: "stored team for another", identity: "client:api", hostname: "legacy.example.gov.au", wantErr: true},
+		{name: "san of other team", identity: "dev@example.gov.au", hostname: "www.dev.example.gov.au", sans: []string{"api.dev.example.gov.au"}, wantErr: true},
+		{name: "san of a team we are in", identity: "ops@example.gov.au", hostname: "example.gov.au", sans: []string{"cdn.example.org"}},
+		{name: "not a member of any team", identity: "someone@example.gov.au", hostname: "www.example.org", wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/update", nil)
+			err := as.checkCanManage(r, &uaa.LoggedInUser{EmailAddress: tc.identity}, tc.hostname, tc.sans)
+			if !tc.wantErr {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			ae, ok := err.(*apiError)
+			if !ok || ae.status != http.StatusForbidden {
+				t.Fatalf("expected forbidden, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"crypto/x509"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// renewalStatePath is where renewal state for all hosts is kept in storage
+	renewalStatePath = "/renewal-state"
+
+	defaultRetryMinSeconds = 10 * 60
+	defaultRetryMaxSeconds = 6 * 60 * 60
+
+	// Renewal policies, which decide when a cert is due for renewal
+	renewalPolicyDaysBefore = "days_before" // a fixed number of days before expiry, the default
+	renewalPolicyLifetime   = "lifetime"    // once a fraction of the cert's lifetime has passed, suiting short-lived certs
+	renewalPolicyARI        = "ari"         // when the CA suggests, falling back to lifetime if it can't say
+
+	defaultLifetimeFraction = 2.0 / 3
+
+	// followerStateCache is how long an instance that isn't the leader reuses renewal state, e.g. while
+	// listing every cert in the admin UI
+	followerStateCache = 10 * time.Second
+)
+
+var renewalPolicies = []string{renewalPolicyDaysBefore, renewalPolicyLifetime, renewalPolicyARI}
+
+func validateRenewalPolicy(policy string) error {
+	for _, p := range renewalPolicies {
+		if p == policy {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown renewal policy %q", policy)
+}
+
+// renewalPolicy decides when a cert is due for renewal
+type renewalPolicy struct {
+	Policy           string
+	DaysBefore       int
+	LifetimeFraction float64 // of the lifetime that must pass before renewal
+}
+
+// renewAt returns when pc should be renewed. For ARI this is the fallback for when the CA can't be asked.
+func (rp *renewalPolicy) renewAt(pc *x509.Certificate) time.Time {
+	if rp.Policy == renewalPolicyDaysBefore {
+		return pc.NotAfter.Add(-24 * time.Hour * time.Duration(rp.DaysBefore))
+	}
+	return pc.NotBefore.Add(time.Duration(float64(pc.NotAfter.Sub(pc.NotBefore)) * rp.LifetimeFraction))
+}
+
+// renewalState records how renewal of a cert has gone, so that we can back off from retrying failures
+type renewalState struct {
+	LastAttempt         time.Time `json:"last_attempt"`
+	LastSuccess         time.Time `json:"last_success"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	FailingSince        time.Time `json:"failing_since"` // first of the consecutive failures
+	LastError           string    `json:"last_error,omitempty"`
+	NextAttempt         time.Time `json:"next_attempt"` // automatic renewals won't be tried before this
+}
+
+// Failing returns true if the last attempt failed
+func (rs *renewalState) Failing() bool {
+	return rs.ConsecutiveFailures != 0
+}
+
+// FailingDays returns how many whole days renewal has been failing for
+func (rs *renewalState) FailingDays() int {
+	return int(time.Since(rs.FailingSince).Hours() / 24)
+}
+
+type renewalStateList struct {
+	Hosts map[string]*renewalState `json:"hosts"`
+}
+
+// renewalLeader says whether we are the leader, and so the only instance renewing certs
+type renewalLeader interface {
+	IsLeader() bool
+	Term() int
+}
+
+// renewalTracker keeps renewal state for each host in storage
+type renewalTracker struct {
+	storage  certStorage
+	leader   renewalLeader
+	minDelay time.Duration
+	maxDelay time.Duration
+
+	lock       sync.Mutex
+	states     map[string]*renewalState // nil until loaded
+	loadedTerm int                      // the leader's term when states were loaded
+	loadedAt   time.Time
+}
+
+// load fetches state from storage the first time it's needed, as storage may not be up when we start.
+// Only the leader writes it, so it is kept while we stay leader. Other instances fetch it again
+// once it is a little old, and the leader does when it takes over, as its copy may be stale.
+// Must be called with lock held.
+func (rt *renewalTracker) load() error {
+	term := rt.leader.Term()
+	if rt.states != nil {
+		if rt.leader.IsLeader() {
+			if term == rt.loadedTerm {
+				return nil
+			}
+		} else if time.Since(rt.loadedAt) < followerStateCache {
+			return nil
+		}
+	}
+	var rsl renewalStateList
+	err := rt.storage.LoadData(renewalStatePath, &rsl)
+	if err != nil && err != errCertNotFound {
+		return err
+	}
+	if rsl.Hosts == nil {
+		rsl.Hosts = make(map[string]*renewalState)
+	}
+	rt.states, rt.loadedTerm, rt.loadedAt = rsl.Hosts, term, time.Now()
+	rt.updateMetrics()
+	return nil
+}
+
+// save must be called with lock held
+func (rt *renewalTracker) save() {
+	err := rt.storage.SaveData(renewalStatePath, &renewalStateList{Hosts: rt.states})
+	if err != nil {
+		metricErrors.WithLabelValues("renewal_state").Inc()
+		log.Println("error saving renewal state, ignoring:", err)
+	}
+	rt.updateMetrics()
+}
+
+// backoff returns how long to wait before retrying after the given number of consecutive failures.
+// The delay doubles each time up to maxDelay, and is randomised so that failures don't all retry together.
+func (rt *renewalTracker) backoff(failures int) time.Duration {
+	delay := rt.minDelay
+	for i := 1; i < failures && delay < rt.maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > rt.maxDelay {
+		delay = rt.maxDelay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// Record updates the state for hostname after an attempt to issue a cert, err being the outcome
+func (rt *renewalTracker) Record(hostname string, err error) {
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+
+	lerr := rt.load()
+	if lerr != nil {
+		log.Println("error loading renewal state, ignoring:", lerr)
+		return
+	}
+
+	now := time.Now().UTC()
+	rs, ok := rt.states[hostname]
+	if !ok {
+		rs = &renewalState{}
+		rt.states[hostname] = rs
+	}
+	rs.LastAttempt = now
+	if err == nil {
+		rs.LastSuccess = now
+		rs.ConsecutiveFailures = 0
+		rs.FailingSince = time.Time{}
+		rs.LastError = ""
+		rs.NextAttempt = time.Time{}
+	} else {
+		if rs.ConsecutiveFailures == 0 {
+			rs.FailingSince = now
+		}
+		rs.ConsecutiveFailures++
+		rs.LastError = err.Error()
+		rs.NextAttempt = now.Add(rt.backoff(rs.ConsecutiveFailures))
+	}
+	rt.save()
+}
+
+// ShouldAttempt returns false, with the current state, if we are backing off from retrying hostname
+func (rt *renewalTracker) ShouldAttempt(hostname string) (bool, *renewalState, error) {
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+
+	err := rt.load()
+	if err != nil {
+		return false, nil, err
+	}
+	rs, ok := rt.states[hostname]
+	if !ok || !rs.Failing() || !time.Now().Before(rs.NextAttempt) {
+		return true, nil, nil
+	}
+	rv := *rs
+	return false, &rv, nil
+}
+
+// Get returns a copy of the state for hostname, or nil if it has never been renewed
+func (rt *renewalTracker) Get(hostname string) *renewalState {
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+
+	if rt.load() != nil {
+		return nil
+	}
+	rs, ok := rt.states[hostname]
+	if !ok {
+		return nil
+	}
+	rv := *rs
+	return &rv
+}
+
+// NextRetry returns the earliest time that a failing host should be retried, or zero time if none are failing
+func (rt *renewalTracker) NextRetry() time.Time {
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+
+	var rv time.Time
+	for _, rs := range rt.states {
+		if rs.Failing() && (rv.IsZero() || rs.NextAttempt.Before(rv)) {
+			rv = rs.NextAttempt
+		}
+	}
+	return rv
+}
+
+// Forget removes state for any host not in hostnames, e.g. after certs are deleted
+func (rt *renewalTracker) Forget(hostnames []string) {
+	rt.lock.Lock()
+	defer rt.lock.Unlock()
+
+	if rt.load() != nil {
+		return
+	}
+	keep := make(map[string]bool)
+	for _, hn := range hostnames {
+		keep[hn] = true
+	}
+	changed := false
+	for hn := range rt.states {
+		if !keep[hn] {
+			delete(rt.states, hn)
+			changed = true
+		}
+	}
+	if changed {
+		rt.save()
+	}
+}
+
+// updateMetrics must be called with lock held
+func (rt *renewalTracker) updateMetrics() {
+	metricRenewalFailures.Reset()
+	metricRenewalFailingSince.Reset()
+	for hn, rs := range rt.states {
+		metricRenewalFailures.WithLabelValues(hn).Set(float64(rs.ConsecutiveFailures))
+		failingSince := 0.0
+		if rs.Failing() {
+			failingSince = float64(rs.FailingSince.Unix())
+		}
+		metricRenewalFailingSince.WithLabelValues(hn).Set(failingSince)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var errTest = errors.New("test failure")
+
+func TestRenewalBackoff(t *testing.T) {
+	rt := &renewalTracker{
+		minDelay: 10 * time.Minute,
+		maxDelay: 6 * time.Hour,
+	}
+	for _, tc := range []struct {
+		failures int
+		full     time.Duration // before it is randomised, between half and all of this
+	}{
+		{failures: 1, full: 10 * time.Minute},
+		{failures: 2, full: 20 * time.Minute},
+		{failures: 3, full: 40 * time.Minute},
+		{failures: 5, full: 160 * time.Minute},
+		{failures: 6, full: 320 * time.Minute},
+		{failures: 7, full: 6 * time.Hour},
+		{failures: 1000, full: 6 * time.Hour},
+	} {
+		for i := 0; i < 100; i++ {
+			d := rt.backoff(tc.failures)
+			if d < tc.full/2 || d > tc.full {
+				t.Fatalf("%d failures: expected between %s and %s, got %s", tc.failures, tc.full/2, tc.full, d)
+			}
+		}
+	}
+}
+
+func TestRenewalTrackerRecord(t *testing.T) {
+	rt := &renewalTracker{
+		storage:  newTestFileStore(t),
+		leader:   &leaderElection{},
+		minDelay: time.Hour,
+		maxDelay: 4 * time.Hour,
+	}
+
+	ok, _, err := rt.ShouldAttempt("www.example.gov.au")
+	if err != nil || !ok {
+		t.Fatalf("expected to attempt a host never tried, got %v %v", ok, err)
+	}
+
+	rt.Record("www.example.gov.au", errTest)
+	rt.Record("www.example.gov.au", errTest)
+	ok, rs, err := rt.ShouldAttempt("www.example.gov.au")
+	if err != nil || ok {
+		t.Fatalf("expected to back off after failures, got %v %v", ok, err)
+	}
+	if rs.ConsecutiveFailures != 2 || rs.LastError != errTest.Error() {
+		t.Fatalf("unexpected state: %+v", rs)
+	}
+	if until := time.Until(rs.NextAttempt); until < time.Hour-time.Minute || until > 2*time.Hour {
+		t.Fatalf("expected the second retry in 1 to 2 hours, got %s", until)
+	}
+	if next := rt.NextRetry(); !next.Equal(rs.NextAttempt) {
+		t.Fatalf("expected next retry at %s, got %s", rs.NextAttempt, next)
+	}
+
+	// A success, e.g. from a manual renewal, resets it
+	rt.Record("www.example.gov.au", nil)
+	ok, _, err = rt.ShouldAttempt("www.example.gov.au")
+	if err != nil || !ok {
+		t.Fatalf("expected to attempt after a success, got %v %v", ok, err)
+	}
+	if !rt.NextRetry().IsZero() {
+		t.Fatal("expected no retries once nothing is failing")
+	}
+
+	rt.Forget(nil)
+	if rt.Get("www.example.gov.au") != nil {
+		t.Fatal("expected state to be forgotten")
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Revocation reasons, as named in RFC 5280. CAs such as Let's Encrypt only accept these.
+const (
+	revocationReasonUnspecified          = "unspecified"
+	revocationReasonKeyCompromise        = "keyCompromise"
+	revocationReasonAffiliationChanged   = "affiliationChanged"
+	revocationReasonSuperseded           = "superseded"
+	revocationReasonCessationOfOperation = "cessationOfOperation"
+)
+
+// revocationReasons is the list shown in the UI, with the RFC 5280 code for each
+var revocationReasons = []string{
+	revocationReasonUnspecified,
+	revocationReasonKeyCompromise,
+	revocationReasonAffiliationChanged,
+	revocationReasonSuperseded,
+	revocationReasonCessationOfOperation,
+}
+
+var revocationReasonCodes = map[string]int{
+	revocationReasonUnspecified:          0,
+	revocationReasonKeyCompromise:        1,
+	revocationReasonAffiliationChanged:   3,
+	revocationReasonSuperseded:           4,
+	revocationReasonCessationOfOperation: 5,
+}
+
+func revocationReasonCode(reason string) (int, error) {
+	rv, ok := revocationReasonCodes[reason]
+	if !ok {
+		return 0, fmt.Errorf("unknown revocation reason: %s", reason)
+	}
+	return rv, nil
+}
+
+// revocation records that the certs stored with it were revoked, so that they are replaced
+type revocation struct {
+	Reason string    `json:"reason"`
+	At     time.Time `json:"at"`
+	Actor  string    `json:"actor"`
+}
+
+// fullyRevoked returns true if every cert stored with chc has been revoked. The primary cert is revoked
+// first, so if revoking the secondary fails, only the primary is marked revoked.
+func (chc *credhubCert) fullyRevoked() bool {
+	return chc.Revoked != nil && (chc.Secondary == nil || chc.Secondary.Revoked != nil)
+}
+
+// revokedChecker is implemented by sources that keep their own list of revoked certs
+type revokedChecker interface {
+	IsRevoked(pc *x509.Certificate) (bool, error)
+}
+
+// checkNotRevoked returns an error if any cert in chc, an old version of the cert at path, has been revoked,
+// as recorded by any version of it, or by its source
+func (dc *daemonConf) checkNotRevoked(path string, chc *credhubCert) error {
+	history, err := dc.storage.LoadHistory(path)
+	if err != nil {
+		return err
+	}
+	revoked := make(map[string]bool) // by PEM cert
+	for _, h := range append(history, chc) {
+		if h.Revoked != nil {
+			revoked[strings.TrimSpace(h.Certificate)] = true
+		}
+		if h.Secondary != nil && h.Secondary.Revoked != nil {
+			revoked[strings.TrimSpace(h.Secondary.Certificate)] = true
+		}
+	}
+
+	certs := []string{chc.Certificate}
+	if chc.Secondary != nil {
+		certs = append(certs, chc.Secondary.Certificate)
+	}
+	rc, _ := dc.current().certFactories[chc.Source].(revokedChecker)
+	for _, c := range certs {
+		if revoked[strings.TrimSpace(c)] {
+			return errors.New("a cert in this version has since been revoked, so it can't be restored")
+		}
+		if rc == nil {
+			continue
+		}
+		pc, err := parseCertificatePEM(c)
+		if err != nil {
+			return err
+		}
+		r, err := rc.IsRevoked(pc)
+		if err != nil {
+			return err
+		}
+		if r {
+			return fmt.Errorf("cert %s is in the CRL for source %s, so it can't be restored", pc.SerialNumber.Text(16), chc.Source)
+		}
+	}
+	return nil
+}
+
+// revokeKey returns the cert's private key if it should be used to sign a revocation, rather than the ACME
+// account key. Signing with it proves the key is compromised, so the CA can block it from future use.
+func revokeKey(privateKey, reason string) crypto.Signer {
+	if reason != revocationReasonKeyCompromise || privateKey == "" {
+		return nil
+	}
+	key, err := parsePrivateKey(privateKey)
+	if err != nil {
+		return nil
+	}
+	switch key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey: // the only types that can sign JWS requests
+		return key
+	default:
+		return nil
+	}
+}
+
+// SourceCanRevoke returns true if certs from the source can be revoked
+func (dc *daemonConf) SourceCanRevoke(cs string) bool {
+	cf, ok := dc.current().certFactories[cs]
+	if !ok {
+		return false
+	}
+	return cf.SupportsRevoke()
+}
+
+// SourceRevokesOnDelete returns true if certs from the source should be revoked when they are deleted
+func (dc *daemonConf) SourceRevokesOnDelete(cs string) bool {
+	sd, ok := dc.current().sourceDefaults[cs]
+	if !ok {
+		return false
+	}
+	return sd.RevokeOnDelete && dc.SourceCanRevoke(cs)
+}
+
+// RevokeCert revokes the current certs for hostname. Callers should queue a renewal to replace them, unless deleting them.
+// The caller must hold the host lock.
+func (dc *daemonConf) RevokeCert(hostname, reason, actor string) (err error) {
+	ae := dc.audit.Start(actor, "revoke", hostname, "")
+	defer func() { dc.audit.Finish(ae, err) }()
+
+	ctx, cancel := context.WithTimeout(dc.workCtx, 1*time.Minute)
+	defer cancel()
+
+	chc, err := dc.revokeCert(ctx, hostname, reason, actor)
+	if chc != nil {
+		ae.Source = chc.Source
+	}
+	return err
+}
+
+// revokeCert revokes the current certs for hostname, and marks them as revoked in storage. The caller must hold the host lock.
+// The cert is returned if it was found.
+func (dc *daemonConf) revokeCert(ctx context.Context, hostname, reason, actor string) (*credhubCert, error) {
+	code, err := revocationReasonCode(reason)
+	if err != nil {
+		return nil, err
+	}
+
+	path := pathFromHost(hostname)
+	chc, err := dc.storage.LoadPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if chc.fullyRevoked() {
+		return chc, errors.New("already revoked")
+	}
+
+	cf, ok := dc.current().certFactories[chc.Source]
+	if !ok {
+		return chc, fmt.Errorf("no cert source found for: %s", chc.Source)
+	}
+	if !cf.SupportsRevoke() {
+		return chc, fmt.Errorf("certs from source %s cannot be revoked", chc.Source)
+	}
+
+	// Each revocation is saved as soon as it's done, so that a failure revoking the secondary cert
+	// doesn't lose that the primary was revoked, and trying again only revokes what's left
+	if chc.Revoked == nil {
+		chc.Revoked, err = revokeOne(ctx, cf, chc.Certificate, chc.PrivateKey, reason, code, actor)
+		if err != nil {
+			return chc, err
+		}
+		err = dc.storage.SavePath(path, chc)
+		if err != nil {
+			return chc, err
+		}
+		metricRevoked.WithLabelValues(chc.Source, reason).Inc()
+	}
+	if chc.Secondary != nil && chc.Secondary.Revoked == nil {
+		chc.Secondary.Revoked, err = revokeOne(ctx, cf, chc.Secondary.Certificate, chc.Secondary.PrivateKey, reason, code, actor)
+		if err != nil {
+			return chc, err
+		}
+		err = dc.storage.SavePath(path, chc)
+		if err != nil {
+			return chc, err
+		}
+	}
+
+	return chc, nil
+}
+
+// revokeOne revokes a single PEM cert, returning the revocation to record
+func revokeOne(ctx context.Context, cf certSource, certificate, privateKey, reason string, code int, actor string) (*revocation, error) {
+	if certificate == "" {
+		return nil, errors.New("no cert has been issued yet")
+	}
+	pc, err := parseCertificatePEM(certificate)
+	if err != nil {
+		return nil, err
+	}
+	reportStep(ctx, "revoking %s", pc.SerialNumber.Text(16))
+	err = cf.Revoke(ctx, pc.Raw, revokeKey(privateKey, reason), code)
+	if err != nil {
+		return nil, err
+	}
+	return &revocation{
+		Reason: reason,
+		At:     time.Now().UTC(),
+		Actor:  actor,
+	}, nil
+}
+
+// keyCompromise revokes the current certs for hostname, issues new ones with a fresh key, and updates outputs
+// straight away, rather than waiting for other updates as usual
+func (dc *daemonConf) keyCompromise(ctx context.Context, hostname, actor string) (err error) {
+	ae := dc.audit.Start(actor, "compromise", hostname, "")
+	defer func() { dc.audit.Finish(ae, err) }()
+
+	reportStep(ctx, "waiting for other renewals of this host")
+	unlock := dc.hostLocks.Lock(hostname)
+
+	revokeCtx, cancel := context.WithTimeout(ctx, 1*time.Minute)
+	defer cancel()
+
+	chc, err := dc.revokeCert(revokeCtx, hostname, revocationReasonKeyCompromise, actor)
+	unlock()
+	if chc != nil {
+		ae.Source = chc.Source
+	}
+	if err != nil {
+		return err
+	}
+
+	if chc.CSR != "" {
+		return errors.New("revoked, but the key is held elsewhere, so upload a CSR for a new key to issue a replacement")
+	}
+
+	err = dc.renewCertNow(ctx, hostname, chc.Source, actor)
+	if err != nil {
+		return err
+	}
+
+	reportStep(ctx, "updating outputs")
+	return dc.updateObservers(dc.LeaderElection.IsLeader())
+}
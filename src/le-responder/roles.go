@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/govau/cf-common/uaa"
+)
+
+// role is what a user of the admin UI or API may do. Each role can do everything the ones before it can.
+type role int
+
+const (
+	roleNone role = iota
+	roleViewer
+	roleOperator
+	roleAdmin
+)
+
+func (r role) String() string {
+	switch r {
+	case roleViewer:
+		return "viewer"
+	case roleOperator:
+		return "operator"
+	case roleAdmin:
+		return "admin"
+	default:
+		return "none"
+	}
+}
+
+func parseRole(s string) (role, error) {
+	for _, r := range []role{roleViewer, roleOperator, roleAdmin} {
+		if r.String() == s {
+			return r, nil
+		}
+	}
+	return roleNone, fmt.Errorf("unknown role: %s", s)
+}
+
+// rolesConf maps users and UAA scopes (groups) to roles. If nothing is configured, everyone is an admin.
+// Users are matched by email address, or for API callers by "client:<client id>".
+type rolesConf struct {
+	Admins    []string `yaml:"admins"`
+	Operators []string `yaml:"operators"`
+	Viewers   []string `yaml:"viewers"`
+
+	AdminScopes    []string `yaml:"admin_scopes"`
+	OperatorScopes []string `yaml:"operator_scopes"`
+	ViewerScopes   []string `yaml:"viewer_scopes"`
+}
+
+func (rc *rolesConf) configured() bool {
+	return len(rc.Admins)+len(rc.Operators)+len(rc.Viewers)+len(rc.AdminScopes)+len(rc.OperatorScopes)+len(rc.ViewerScopes) != 0
+}
+
+// scopes returns every scope that grants a role, so that we can request them at login
+func (rc *rolesConf) scopes() []string {
+	var rv []string
+	rv = append(rv, rc.AdminScopes...)
+	rv = append(rv, rc.OperatorScopes...)
+	rv = append(rv, rc.ViewerScopes...)
+	return rv
+}
+
+// roleFor returns the highest role granted to the identity or any of the scopes
+func (rc *rolesConf) roleFor(identity string, scopes []string) role {
+	if !rc.configured() {
+		return roleAdmin
+	}
+
+	has := func(users, roleScopes []string) bool {
+		if stringInList(identity, users) {
+			return true
+		}
+		for _, s := range scopes {
+			if stringInList(s, roleScopes) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case has(rc.Admins, rc.AdminScopes):
+		return roleAdmin
+	case has(rc.Operators, rc.OperatorScopes):
+		return roleOperator
+	case has(rc.Viewers, rc.ViewerScopes):
+		return roleViewer
+	default:
+		return roleNone
+	}
+}
+
+// tokenScopes returns the scopes in a UAA access token that has already been validated
+func tokenScopes(at string) []string {
+	var claims jwt.MapClaims
+	_, _, err := (&jwt.Parser{}).ParseUnverified(at, &claims)
+	if err != nil {
+		return nil
+	}
+	var rv []string
+	scopes, _ := claims["scope"].([]interface{})
+	for _, s := range scopes {
+		if ss, ok := s.(string); ok {
+			rv = append(rv, ss)
+		}
+	}
+	return rv
+}
+
+type ctxKey int
+
+const (
+	// keyRole is set in the request context when the role has already been determined, e.g. for local API tokens
+	keyRole ctxKey = iota
+
+	// keyStepReporter is set in the context of a background job, to record how far it got
+	keyStepReporter
+
+	// keyReplaces is set in the context of an order to the ARI cert ID of the cert it replaces, if any
+	keyReplaces
+)
+
+func withRole(r *http.Request, rl role) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), keyRole, rl))
+}
+
+// roleForRequest returns the role of the logged in user making the request
+func (as *adminServer) roleForRequest(r *http.Request, liu *uaa.LoggedInUser) role {
+	if rl, ok := r.Context().Value(keyRole).(role); ok {
+		return rl
+	}
+	return as.roles().roleFor(liu.EmailAddress, tokenScopes(liu.AccessToken))
+}
+
+// actionRoles is the role needed for each action in update
+var actionRoles = map[string]role{
+	"create":   roleAdmin,
+	"delete":   roleAdmin,
+	"source":   roleAdmin,
+	"names":    roleAdmin,
+	"restore":  roleAdmin,
+	"revoke":   roleAdmin,
+	"auto":     roleOperator,
+	"manual":   roleOperator,
+	"complete": roleOperator,
+
+	// Operators can respond to a compromised key straight away, as it replaces the cert
+	"compromise": roleOperator,
+}
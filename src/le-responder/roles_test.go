@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/govau/cf-common/uaa"
+)
+
+func TestRoleFor(t *testing.T) {
+	rc := &rolesConf{
+		Admins:         []string{"admin@example.gov.au"},
+		Operators:      []string{"operator@example.gov.au", "admin@example.gov.au"},
+		Viewers:        []string{"client:dashboard"},
+		OperatorScopes: []string{"le-responder.operate"},
+		ViewerScopes:   []string{"le-responder.view"},
+	}
+	for _, tc := range []struct {
+		name     string
+		rc       *rolesConf
+		identity string
+		scopes   []string
+		want     role
+	}{
+		{name: "nothing configured", rc: &rolesConf{}, identity: "anyone@example.gov.au", want: roleAdmin},
+		{name: "admin", rc: rc, identity: "admin@example.gov.au", want: roleAdmin},
+		{name: "operator", rc: rc, identity: "operator@example.gov.au", want: roleOperator},
+		{name: "api client", rc: rc, identity: "client:dashboard", want: roleViewer},
+		{name: "scope", rc: rc, identity: "someone@example.gov.au", scopes: []string{"openid", "le-responder.view"}, want: roleViewer},
+		{name: "highest of user and scope", rc: rc, identity: "client:dashboard", scopes: []string{"le-responder.operate"}, want: roleOperator},
+		{name: "unknown", rc: rc, identity: "someone@example.gov.au", scopes: []string{"openid"}, want: roleNone},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.rc.roleFor(tc.identity, tc.scopes)
+			if got != tc.want {
+				t.Fatalf("expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestActionRoles(t *testing.T) {
+	for _, tc := range []struct {
+		action  string
+		allowed []role
+	}{
+		{action: "create", allowed: []role{roleAdmin}},
+		{action: "delete", allowed: []role{roleAdmin}},
+		{action: "source", allowed: []role{roleAdmin}},
+		{action: "names", allowed: []role{roleAdmin}},
+		{action: "restore", allowed: []role{roleAdmin}},
+		{action: "revoke", allowed: []role{roleAdmin}},
+		{action: "auto", allowed: []role{roleOperator, roleAdmin}},
+		{action: "manual", allowed: []role{roleOperator, roleAdmin}},
+		{action: "complete", allowed: []role{roleOperator, roleAdmin}},
+		{action: "compromise", allowed: []role{roleOperator, roleAdmin}},
+	} {
+		t.Run(tc.action, func(t *testing.T) {
+			needed, ok := actionRoles[tc.action]
+			if !ok {
+				t.Fatal("expected a role to be needed")
+			}
+			for _, rl := range []role{roleNone, roleViewer, roleOperator, roleAdmin} {
+				want := false
+				for _, a := range tc.allowed {
+					if a == rl {
+						want = true
+					}
+				}
+				if got := rl >= needed; got != want {
+					t.Errorf("%s: expected allowed to be %v, got %v", rl, want, got)
+				}
+			}
+		})
+	}
+}
+
+// TestRoleForRequest checks that a role already set on the request, e.g. for a local API token, is used as is
+func TestRoleForRequest(t *testing.T) {
+	as := &adminServer{Roles: rolesConf{Admins: []string{"admin@example.gov.au"}}}
+	liu := &uaa.LoggedInUser{EmailAddress: "admin@example.gov.au"}
+
+	r := httptest.NewRequest("POST", "/update", nil)
+	if got := as.roleForRequest(r, liu); got != roleAdmin {
+		t.Fatalf("expected admin, got %s", got)
+	}
+	if got := as.roleForRequest(withRole(r, roleViewer), liu); got != roleViewer {
+		t.Fatalf("expected the role on the request, got %s", got)
+	}
+}
@@ -1,16 +1,21 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -41,20 +46,48 @@ type adminServer struct {
 	InsecureCookies bool     `yaml:"insecure_cookies"`
 	AllowedUsers    []string `yaml:"allowed_users"`
 
+	API       apiAuthConf   `yaml:"api"`
+	Roles     rolesConf     `yaml:"roles"`
+	Ownership ownershipConf `yaml:"ownership"`
+
 	cookies     *sessions.CookieStore
 	storage     certStorage
 	certRenewer certRenewer
 	ourHostname string
+	apiTokens   *apiTokenStore
+	audit       *auditLog
 
 	ourCertMutex sync.RWMutex
-	ourCert      *tls.Certificate
+	ourCerts     []*tls.Certificate // more than one if we have certs for different key types
+
+	uaaClient    *uaa.Client
+	adminHandler http.Handler
+
+	loginMutex   sync.RWMutex // guards AllowedUsers, Roles and Ownership too, as they can be reloaded
+	loginHandler http.Handler // rebuilt when they are
+
+	serverMutex sync.Mutex
+	server      *http.Server
 }
 
-func (as *adminServer) Init(storage certStorage, certRenewer certRenewer, ourHostname string) error {
+func (as *adminServer) Init(storage certStorage, certRenewer certRenewer, audit *auditLog, ourHostname string) error {
 	as.cookies = uaa.MustCreateBasicCookieHandler(false)
 	as.storage = storage
 	as.certRenewer = certRenewer
 	as.ourHostname = ourHostname
+	as.apiTokens = &apiTokenStore{storage: storage}
+	as.audit = audit
+
+	as.uaaClient = &uaa.Client{
+		URL:          as.UAA.InternalURL,
+		CACerts:      as.UAA.CACerts,
+		ClientID:     as.UAA.ClientID,
+		ClientSecret: as.UAA.ClientSecret,
+		ExternalURL:  as.UAA.ExternalURL,
+	}
+	as.adminHandler = as.createAdminHandler()
+	as.ReloadAccess(as.AllowedUsers, as.Roles, as.Ownership)
+
 	return nil
 }
 
@@ -69,8 +102,16 @@ func (as *adminServer) CertsAreUpdated(certs []*credhubCert) error {
 		if err != nil {
 			return err
 		}
+		ourCerts := []*tls.Certificate{&tlsCert}
+		if cert.Secondary != nil {
+			secondary, err := tls.X509KeyPair([]byte(fmt.Sprintf("%s\n%s\n", strings.TrimSpace(cert.Secondary.Certificate), strings.TrimSpace(cert.Secondary.CA))), []byte(cert.Secondary.PrivateKey))
+			if err != nil {
+				return err
+			}
+			ourCerts = append(ourCerts, &secondary)
+		}
 		as.ourCertMutex.Lock()
-		as.ourCert = &tlsCert
+		as.ourCerts = ourCerts
 		as.ourCertMutex.Unlock()
 		return nil
 	}
@@ -89,49 +130,126 @@ func (as *adminServer) RunForever() {
 	hook.SetSync(logrus.PanicLevel, logrus.FatalLevel) // sync (blocking) for fatal stuff
 	logrus.AddHook(hook)
 
-	log.Println("admin server exit:", (&http.Server{
+	server := &http.Server{
 		Addr: fmt.Sprintf(":%d", as.Port),
-		Handler: (&uaa.LoginHandler{
-			Cookies: as.cookies,
-			UAA: &uaa.Client{
-				URL:          as.UAA.InternalURL,
-				CACerts:      as.UAA.CACerts,
-				ClientID:     as.UAA.ClientID,
-				ClientSecret: as.UAA.ClientSecret,
-				ExternalURL:  as.UAA.ExternalURL,
-			},
-			Scopes: []string{
-				"openid",
-			},
-			AllowedUsers:   as.AllowedUsers,
-			BaseURL:        as.ExternalURL,
-			ExternalUAAURL: as.UAA.ExternalURL,
-			Logger:         log.New(os.Stderr, "", log.LstdFlags),
-			ShouldIgnore: func(r *http.Request) bool {
-				switch r.URL.Path {
-				case "/favicon.ico", "/metrics":
-					return true
-				default:
-					return false
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// API calls with a bearer token are checked by us, as the login handler only understands user tokens
+			if !isAPIBearerRequest(r) {
+				as.loginMutex.RLock()
+				loginHandler := as.loginHandler
+				as.loginMutex.RUnlock()
+				loginHandler.ServeHTTP(w, r)
+				return
+			}
+			liu, rl, err := as.authenticateBearer(as.uaaClient, r)
+			if err != nil {
+				log.Println("api authentication failed:", err)
+				if err == errAPIForbidden {
+					writeJSON(w, http.StatusForbidden, map[string]string{"error": err.Error()})
+				} else {
+					w.Header().Set("WWW-Authenticate", "Bearer")
+					writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid or missing bearer token"})
 				}
-			},
-		}).Wrap(as.createAdminHandler()),
+				return
+			}
+			as.adminHandler.ServeHTTP(w, withRole(r.WithContext(context.WithValue(r.Context(), uaa.KeyLoggedInUser, liu)), rl))
+		}),
 		TLSConfig: &tls.Config{
 			GetCertificate: func(chi *tls.ClientHelloInfo) (*tls.Certificate, error) {
 				as.ourCertMutex.RLock()
 				defer as.ourCertMutex.RUnlock()
-				if as.ourCert == nil {
+				if len(as.ourCerts) == 0 {
 					return nil, errors.New("we don't have a cert yet")
 				}
-				return as.ourCert, nil
+				for _, c := range as.ourCerts {
+					if chi.SupportsCertificate(c) == nil {
+						return c, nil
+					}
+				}
+				return as.ourCerts[0], nil
 			},
 		},
-	}).ListenAndServeTLS("", "")) // we can leave empty as we already have set via tls.Config
+	}
+	as.serverMutex.Lock()
+	as.server = server
+	as.serverMutex.Unlock()
+
+	err := server.ListenAndServeTLS("", "") // we can leave empty as we already have set via tls.Config
+	if err == http.ErrServerClosed {
+		return // Shutdown was called
+	}
+	log.Println("admin server exit:", err)
+}
+
+// Shutdown stops accepting connections, and waits for requests in progress to finish until ctx is done
+func (as *adminServer) Shutdown(ctx context.Context) error {
+	as.serverMutex.Lock()
+	server := as.server
+	as.serverMutex.Unlock()
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
+}
+
+// ReloadAccess replaces the users allowed to log in, their roles and the teams owning certs, without dropping connections
+func (as *adminServer) ReloadAccess(users []string, roles rolesConf, ownership ownershipConf) {
+	loginHandler := (&uaa.LoginHandler{
+		Cookies: as.cookies,
+		UAA:     as.uaaClient,
+		Scopes: append(append([]string{
+			"openid",
+		}, roles.scopes()...), ownership.scopes()...),
+		AllowedUsers:   users,
+		BaseURL:        as.ExternalURL,
+		ExternalUAAURL: as.UAA.ExternalURL,
+		Logger:         log.New(os.Stderr, "", log.LstdFlags),
+		ShouldIgnore: func(r *http.Request) bool {
+			switch r.URL.Path {
+			case "/favicon.ico", "/metrics", "/api/v1/openapi.json":
+				return true
+			default:
+				return false
+			}
+		},
+	}).Wrap(as.adminHandler)
+
+	as.loginMutex.Lock()
+	as.AllowedUsers = users
+	as.Roles = roles
+	as.Ownership = ownership
+	as.loginHandler = loginHandler
+	as.loginMutex.Unlock()
+}
+
+// allowedUsers returns the users allowed to log in, or none if anyone may
+func (as *adminServer) allowedUsers() []string {
+	as.loginMutex.RLock()
+	defer as.loginMutex.RUnlock()
+	return as.AllowedUsers
+}
+
+// roles returns the roles as last loaded
+func (as *adminServer) roles() *rolesConf {
+	as.loginMutex.RLock()
+	defer as.loginMutex.RUnlock()
+	rc := as.Roles
+	return &rc
+}
+
+// ownership returns the teams as last loaded
+func (as *adminServer) ownership() *ownershipConf {
+	as.loginMutex.RLock()
+	defer as.loginMutex.RUnlock()
+	oc := as.Ownership
+	return &oc
 }
 
 func (as *adminServer) add(vars map[string]string, liu *uaa.LoggedInUser, w http.ResponseWriter, r *http.Request) (map[string]interface{}, error) {
 	return map[string]interface{}{
-		"sources": as.certRenewer.Sources(),
+		"sources":         as.certRenewer.Sources(),
+		"keyTypes":        keyTypes,
+		"renewalPolicies": renewalPolicies,
 	}, nil
 }
 
@@ -148,6 +266,680 @@ func (as *adminServer) source(vars map[string]string, liu *uaa.LoggedInUser, w h
 	}, nil
 }
 
+func (as *adminServer) revoke(vars map[string]string, liu *uaa.LoggedInUser, w http.ResponseWriter, r *http.Request) (map[string]interface{}, error) {
+	hostname := hostFromPath(r.FormValue("path"))
+	if hostname == "" {
+		as.flashMessage(w, r, "cannot find cert")
+		http.Redirect(w, r, "/", http.StatusFound)
+		return nil, nil
+	}
+	chc, err := as.storage.LoadPath(pathFromHost(hostname))
+	if err != nil {
+		as.flashMessage(w, r, err.Error())
+		http.Redirect(w, r, "/", http.StatusFound)
+		return nil, nil
+	}
+	return map[string]interface{}{
+		"host":    hostname,
+		"reasons": revocationReasons,
+		"csr":     chc.CSR != "",
+	}, nil
+}
+
+func (as *adminServer) names(vars map[string]string, liu *uaa.LoggedInUser, w http.ResponseWriter, r *http.Request) (map[string]interface{}, error) {
+	hostname := hostFromPath(r.FormValue("path"))
+	if hostname == "" {
+		as.flashMessage(w, r, "cannot find cert")
+		http.Redirect(w, r, "/", http.StatusFound)
+		return nil, nil
+	}
+	chc, err := as.storage.LoadPath(pathFromHost(hostname))
+	if err != nil {
+		as.flashMessage(w, r, err.Error())
+		http.Redirect(w, r, "/", http.StatusFound)
+		return nil, nil
+	}
+	return map[string]interface{}{
+		"host":             hostname,
+		"sans":             strings.Join(chc.SANs, "\n"),
+		"keyType":          chc.KeyType,
+		"secondaryKeyType": chc.SecondaryKeyType,
+		"keyTypes":         keyTypes,
+		"renewalPolicy":    chc.RenewalPolicy,
+		"csr":              chc.CSR,
+		"renewalPolicies":  renewalPolicies,
+	}, nil
+}
+
+// importPage imports a single cert, or a tarball of them, and shows how each went
+func (as *adminServer) importPage(vars map[string]string, liu *uaa.LoggedInUser, w http.ResponseWriter, r *http.Request) (map[string]interface{}, error) {
+	var results []*importResult
+	switch r.FormValue("action") {
+	case "single":
+		results = append(results, as.importChecked(r, liu, strings.TrimSuffix(strings.ToLower(strings.TrimSpace(r.FormValue("host"))), "."), &importPEMs{
+			Certificate: r.FormValue("certificate"),
+			Chain:       r.FormValue("chain"),
+			PrivateKey:  r.FormValue("private_key"),
+		}, nil))
+
+	case "tarball":
+		f, _, err := r.FormFile("tarball")
+		if err != nil {
+			as.flashMessage(w, r, "no tarball uploaded")
+			http.Redirect(w, r, "/import", http.StatusFound)
+			return nil, nil
+		}
+		defer f.Close()
+
+		results, err = as.importTarball(r, liu, f)
+		if err != nil {
+			as.flashMessage(w, r, err.Error())
+			http.Redirect(w, r, "/import", http.StatusFound)
+			return nil, nil
+		}
+	}
+
+	session, _ := as.cookies.Get(r, "f")
+	flashes := session.Flashes()
+	if len(flashes) != 0 {
+		session.Save(r, w)
+	}
+
+	return map[string]interface{}{
+		"results":  results,
+		"messages": flashes,
+	}, nil
+}
+
+func (as *adminServer) tokens(vars map[string]string, liu *uaa.LoggedInUser, w http.ResponseWriter, r *http.Request) (map[string]interface{}, error) {
+	if !as.API.LocalTokens {
+		as.flashMessage(w, r, "local API tokens are not enabled")
+		http.Redirect(w, r, "/", http.StatusFound)
+		return nil, nil
+	}
+
+	var newToken string
+	if r.Method == http.MethodPost {
+		switch r.FormValue("action") {
+		case "create":
+			rl, err := parseRole(r.FormValue("role"))
+			if err != nil {
+				as.flashMessage(w, r, err.Error())
+				break
+			}
+			if rl > as.roleForRequest(r, liu) {
+				as.flashMessage(w, r, "cannot create a token with more access than you have")
+				break
+			}
+			token, err := as.apiTokens.Create(strings.TrimSpace(r.FormValue("name")), liu.EmailAddress, rl)
+			if err != nil {
+				as.flashMessage(w, r, err.Error())
+				break
+			}
+			// Render directly, rather than redirect, so that the token is never stored in a cookie
+			newToken = token
+
+		case "revoke":
+			err := as.apiTokens.Revoke(r.FormValue("id"))
+			if err != nil {
+				as.flashMessage(w, r, err.Error())
+				break
+			}
+			as.flashMessage(w, r, "token revoked")
+
+		default:
+			as.flashMessage(w, r, "unknown action")
+		}
+
+		if newToken == "" {
+			http.Redirect(w, r, "/tokens", http.StatusFound)
+			return nil, nil
+		}
+	}
+
+	tokens, err := as.apiTokens.List()
+	if err != nil {
+		return nil, err
+	}
+
+	session, _ := as.cookies.Get(r, "f")
+	flashes := session.Flashes()
+	if len(flashes) != 0 {
+		session.Save(r, w)
+	}
+
+	return map[string]interface{}{
+		"tokens":   tokens,
+		"newToken": newToken,
+		"messages": flashes,
+		"roles":    []string{roleViewer.String(), roleOperator.String(), roleAdmin.String()},
+	}, nil
+}
+
+func (as *adminServer) auditPage(vars map[string]string, liu *uaa.LoggedInUser, w http.ResponseWriter, r *http.Request) (map[string]interface{}, error) {
+	days := 7
+	if r.FormValue("days") != "" {
+		var err error
+		days, err = strconv.Atoi(r.FormValue("days"))
+		if err != nil {
+			days = 0 // rejected below
+		}
+	}
+	hostname := strings.TrimSpace(r.FormValue("host"))
+	actor := strings.TrimSpace(r.FormValue("user"))
+
+	entries, err := as.audit.Query(days, hostname, actor)
+	if err != nil {
+		as.flashMessage(w, r, err.Error())
+		http.Redirect(w, r, "/", http.StatusFound)
+		return nil, nil
+	}
+
+	// Export as JSON lines, oldest first, as is usual for logs
+	if r.FormValue("format") == "jsonl" {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", "attachment; filename=audit.jsonl")
+		enc := json.NewEncoder(w)
+		for i := len(entries) - 1; i >= 0; i-- {
+			err = enc.Encode(entries[i])
+			if err != nil {
+				log.Println(err)
+				return nil, nil
+			}
+		}
+		return nil, nil
+	}
+
+	return map[string]interface{}{
+		"entries": entries,
+		"days":    days,
+		"host":    hostname,
+		"actor":   actor,
+		"query": url.Values{
+			"days":   {strconv.Itoa(days)},
+			"host":   {hostname},
+			"user":   {actor},
+			"format": {"jsonl"},
+		}.Encode(),
+	}, nil
+}
+
+func (as *adminServer) jobs(vars map[string]string, liu *uaa.LoggedInUser, w http.ResponseWriter, r *http.Request) (map[string]interface{}, error) {
+	jobs := as.certRenewer.Jobs()
+
+	// Refresh the page while anything is still to finish
+	active := false
+	for _, j := range jobs {
+		if j.Status == jobQueued || j.Status == jobRunning {
+			active = true
+		}
+	}
+
+	session, _ := as.cookies.Get(r, "f")
+	flashes := session.Flashes()
+	if len(flashes) != 0 {
+		session.Save(r, w)
+	}
+
+	return map[string]interface{}{
+		"jobs":     jobs,
+		"active":   active,
+		"messages": flashes,
+	}, nil
+}
+
+// uiCertVersion is a row on the history page
+type uiCertVersion struct {
+	Version  string
+	Saved    time.Time
+	Source   string
+	Cert     *x509.Certificate // nil if no cert was issued in this version
+	KeyTypes []string
+	Current  bool
+}
+
+func (as *adminServer) history(vars map[string]string, liu *uaa.LoggedInUser, w http.ResponseWriter, r *http.Request) (map[string]interface{}, error) {
+	hostname := hostFromPath(r.FormValue("path"))
+	if hostname == "" {
+		as.flashMessage(w, r, "cannot find cert")
+		http.Redirect(w, r, "/", http.StatusFound)
+		return nil, nil
+	}
+	path := pathFromHost(hostname)
+	versions, err := as.storage.LoadHistory(path)
+	if err != nil {
+		as.flashMessage(w, r, err.Error())
+		http.Redirect(w, r, "/", http.StatusFound)
+		return nil, nil
+	}
+
+	rows := make([]uiCertVersion, len(versions))
+	for i, chc := range versions {
+		rows[i] = uiCertVersion{
+			Version: chc.version,
+			Saved:   chc.dateCreated,
+			Source:  chc.Source,
+			Current: i == 0,
+		}
+		if chc.Certificate != "" {
+			pc, err := parseCertificatePEM(chc.Certificate)
+			if err != nil {
+				return nil, err
+			}
+			rows[i].Cert = pc
+			rows[i].KeyTypes = append(rows[i].KeyTypes, keyTypeOf(pc.PublicKey))
+			if chc.Secondary != nil {
+				rows[i].KeyTypes = append(rows[i].KeyTypes, chc.Secondary.KeyType)
+			}
+		}
+	}
+
+	return map[string]interface{}{
+		"host":      hostname,
+		"path":      path,
+		"versions":  rows,
+		"canManage": as.checkCanManage(r, liu, hostname, nil) == nil,
+	}, nil
+}
+
+// validateKeyTypes checks that the key types requested are OK for the source
+func (as *adminServer) validateKeyTypes(source string, kts ...string) error {
+	for _, kt := range kts {
+		if kt == "" {
+			continue
+		}
+		err := as.certRenewer.SourceSupportsKeyType(source, kt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateRenewalPolicy checks that the renewal policy requested, if any, is OK for the source
+func (as *adminServer) validateRenewalPolicy(source, policy string) error {
+	if policy == "" {
+		return nil
+	}
+	return as.certRenewer.SourceSupportsRenewalPolicy(source, policy)
+}
+
+// validateCSR checks that an uploaded CSR can be used for hostname with the source, and returns the additional names in it
+func (as *adminServer) validateCSR(hostname, source, csr string) ([]string, error) {
+	if !as.certRenewer.CanDelete(hostname) {
+		return nil, errors.New("certs used by le-responder itself need their keys generated here")
+	}
+	if !as.certRenewer.SourceSupportsCSR(source) {
+		return nil, fmt.Errorf("source %s cannot issue certs for a CSR", source)
+	}
+	parsed, sans, err := validateCSR(csr, hostname)
+	if err != nil {
+		return nil, err
+	}
+	err = as.certRenewer.SourceSupportsKeyType(source, keyTypeOf(parsed.PublicKey))
+	if err != nil {
+		return nil, err
+	}
+	return sans, nil
+}
+
+// sansFor returns the additional names a cert will have, which come from the CSR if there is one.
+// Used to check ownership of all names before making changes.
+func sansFor(hostname string, sans []string, csr string) ([]string, error) {
+	if csr == "" {
+		return sans, nil
+	}
+	_, rv, err := validateCSR(csr, hostname)
+	if err != nil {
+		return nil, &apiError{http.StatusBadRequest, err.Error()}
+	}
+	return rv, nil
+}
+
+func (as *adminServer) isSource(source string) bool {
+	for _, s := range as.certRenewer.Sources() {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
+
+// createCert starts managing a new cert, which will be issued on the next periodic scan.
+// Used by both the UI and the API, as are the other helpers below. All are recorded in the audit log against actor.
+// If csr is set, the names in it are used instead of sans.
+func (as *adminServer) createCert(actor, hostname, source string, sans []string, keyType, secondaryKeyType, renewalPolicy, csr string) (err error) {
+	ae := as.audit.Start(actor, "create", hostname, source)
+	defer func() { as.audit.Finish(ae, err) }()
+
+	if len(hostname) == 0 {
+		return &apiError{http.StatusBadRequest, "empty hostname"}
+	}
+	path := pathFromHost(hostname)
+
+	defer as.certRenewer.LockHost(hostname)()
+
+	// Look to see if it exists
+	_, err = as.storage.LoadPath(path)
+	if err == nil {
+		return &apiError{http.StatusConflict, "already managed"}
+	}
+
+	if len(source) == 0 {
+		return &apiError{http.StatusBadRequest, "empty source"}
+	}
+	if !as.isSource(source) {
+		return &apiError{http.StatusBadRequest, "unknown source"}
+	}
+
+	err = as.validateKeyTypes(source, keyType, secondaryKeyType)
+	if err != nil {
+		return &apiError{http.StatusBadRequest, err.Error()}
+	}
+
+	err = as.validateRenewalPolicy(source, renewalPolicy)
+	if err != nil {
+		return &apiError{http.StatusBadRequest, err.Error()}
+	}
+
+	if csr != "" {
+		sans, err = as.validateCSR(hostname, source, csr)
+		if err != nil {
+			return &apiError{http.StatusBadRequest, err.Error()}
+		}
+		keyType, secondaryKeyType = "", ""
+	}
+
+	return as.storage.SavePath(path, &credhubCert{
+		Source:           source,
+		SANs:             sans,
+		Team:             as.ownership().teamForHostname(hostname),
+		KeyType:          keyType,
+		SecondaryKeyType: secondaryKeyType,
+		RenewalPolicy:    renewalPolicy,
+		CSR:              csr,
+	})
+}
+
+// deleteCert stops managing a cert. It is revoked first if revoke is set, or the source is configured to,
+// and if that fails it is not deleted.
+func (as *adminServer) deleteCert(actor, hostname string, revoke bool) (err error) {
+	ae := as.audit.Start(actor, "delete", hostname, "")
+	defer func() { as.audit.Finish(ae, err) }()
+
+	if hostname == "" {
+		return &apiError{http.StatusNotFound, "cannot find cert"}
+	}
+
+	if !as.certRenewer.CanDelete(hostname) {
+		return &apiError{http.StatusForbidden, "not allowed to delete cert for this server"}
+	}
+
+	path := pathFromHost(hostname)
+
+	defer as.certRenewer.LockHost(hostname)()
+
+	existing, err := as.storage.LoadPath(path)
+	if err != nil {
+		return err
+	}
+	ae.Source = existing.Source
+
+	// Nothing to revoke if it was never issued, or has been already
+	if existing.Certificate != "" && !existing.fullyRevoked() && (revoke || as.certRenewer.SourceRevokesOnDelete(existing.Source)) {
+		err = as.checkCanRevoke(existing)
+		if err != nil {
+			return err
+		}
+		err = as.certRenewer.RevokeCert(hostname, revocationReasonCessationOfOperation, actor)
+		if err != nil {
+			return &apiError{http.StatusBadGateway, "not deleted, as revoking failed: " + err.Error()}
+		}
+	}
+
+	return as.storage.DeletePath(path)
+}
+
+// checkCanRevoke returns an error if the cert can't be revoked
+func (as *adminServer) checkCanRevoke(chc *credhubCert) error {
+	if !as.certRenewer.SourceCanRevoke(chc.Source) {
+		return &apiError{http.StatusConflict, "certs from source " + chc.Source + " cannot be revoked"}
+	}
+	if chc.Certificate == "" {
+		return &apiError{http.StatusConflict, "no cert has been issued yet"}
+	}
+	if chc.fullyRevoked() {
+		return &apiError{http.StatusConflict, "already revoked"}
+	}
+	return nil
+}
+
+// revokeCert revokes the current certs, and queues a job to replace them straight away, which is returned.
+// The job is nil if they can't be replaced until a CSR for a new key is uploaded.
+func (as *adminServer) revokeCert(actor, hostname, reason string) (*job, error) {
+	_, err := revocationReasonCode(reason)
+	if err != nil {
+		return nil, &apiError{http.StatusBadRequest, err.Error()}
+	}
+	unlock := as.certRenewer.LockHost(hostname)
+	chc, err := as.storage.LoadPath(pathFromHost(hostname))
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	err = as.checkCanRevoke(chc)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	err = as.certRenewer.RevokeCert(hostname, reason, actor)
+	unlock()
+	if err != nil {
+		return nil, &apiError{http.StatusBadGateway, err.Error()}
+	}
+
+	// Renewing would reuse the key in the CSR
+	if chc.CSR != "" && reason == revocationReasonKeyCompromise {
+		return nil, nil
+	}
+	j, err := as.certRenewer.QueueJob(jobRenew, hostname, chc.Source, actor)
+	if err != nil {
+		return nil, &apiError{http.StatusInternalServerError, "revoked, but queueing a replacement failed, it will be replaced on the next scan: " + err.Error()}
+	}
+	return j, nil
+}
+
+// queueKeyCompromise starts the key compromise workflow, which revokes, reissues with a fresh key, and updates outputs
+func (as *adminServer) queueKeyCompromise(actor, hostname string) (*job, error) {
+	chc, err := as.storage.LoadPath(pathFromHost(hostname))
+	if err != nil {
+		return nil, err
+	}
+	err = as.checkCanRevoke(chc)
+	if err != nil {
+		return nil, err
+	}
+	return as.certRenewer.QueueJob(jobCompromise, hostname, chc.Source, actor)
+}
+
+func (as *adminServer) changeSource(actor, hostname, source string) (err error) {
+	ae := as.audit.Start(actor, "source", hostname, source)
+	defer func() { as.audit.Finish(ae, err) }()
+
+	if len(hostname) == 0 {
+		return &apiError{http.StatusBadRequest, "empty hostname"}
+	}
+	path := pathFromHost(hostname)
+
+	defer as.certRenewer.LockHost(hostname)()
+
+	// Look to see if it exists
+	existing, err := as.storage.LoadPath(path)
+	if err != nil {
+		return err
+	}
+
+	if len(source) == 0 {
+		return &apiError{http.StatusBadRequest, "empty source"}
+	}
+	if !as.isSource(source) {
+		return &apiError{http.StatusBadRequest, "unknown source"}
+	}
+	if existing.CSR != "" && !as.certRenewer.SourceSupportsCSR(source) {
+		return &apiError{http.StatusBadRequest, "source cannot issue certs for a CSR"}
+	}
+	err = as.validateKeyTypes(source, existing.KeyType, existing.SecondaryKeyType)
+	if err != nil {
+		return &apiError{http.StatusBadRequest, err.Error()}
+	}
+
+	existing.Source = source
+
+	return as.storage.SavePath(path, existing)
+}
+
+// changeNames changes the additional names, key types, renewal policy and CSR, which apply from the next renewal.
+// If csr is set, the names in it are used instead of sans, else keys are generated here.
+func (as *adminServer) changeNames(actor, hostname string, sans []string, keyType, secondaryKeyType, renewalPolicy, csr string) (err error) {
+	ae := as.audit.Start(actor, "names", hostname, "")
+	defer func() { as.audit.Finish(ae, err) }()
+
+	if len(hostname) == 0 {
+		return &apiError{http.StatusBadRequest, "empty hostname"}
+	}
+	path := pathFromHost(hostname)
+
+	defer as.certRenewer.LockHost(hostname)()
+
+	existing, err := as.storage.LoadPath(path)
+	if err != nil {
+		return err
+	}
+	ae.Source = existing.Source
+
+	err = as.validateKeyTypes(existing.Source, keyType, secondaryKeyType)
+	if err != nil {
+		return &apiError{http.StatusBadRequest, err.Error()}
+	}
+
+	err = as.validateRenewalPolicy(existing.Source, renewalPolicy)
+	if err != nil {
+		return &apiError{http.StatusBadRequest, err.Error()}
+	}
+
+	if csr != "" {
+		sans, err = as.validateCSR(hostname, existing.Source, csr)
+		if err != nil {
+			return &apiError{http.StatusBadRequest, err.Error()}
+		}
+		keyType, secondaryKeyType = "", ""
+	}
+
+	existing.SANs = sans
+	existing.KeyType = keyType
+	existing.SecondaryKeyType = secondaryKeyType
+	existing.RenewalPolicy = renewalPolicy
+	existing.CSR = csr
+
+	return as.storage.SavePath(path, existing)
+}
+
+// importCert starts managing a cert issued elsewhere, with an optional secondary cert for another key type.
+// It is shipped as is, and not renewed until its source is changed.
+func (as *adminServer) importCert(actor, hostname string, primary, secondary *importPEMs) (err error) {
+	ae := as.audit.Start(actor, "import", hostname, importedSourceName)
+	defer func() { as.audit.Finish(ae, err) }()
+
+	if len(hostname) == 0 {
+		return &apiError{http.StatusBadRequest, "empty hostname"}
+	}
+	if !as.certRenewer.CanDelete(hostname) {
+		return &apiError{http.StatusForbidden, "certs used by le-responder itself cannot be imported"}
+	}
+	path := pathFromHost(hostname)
+
+	defer as.certRenewer.LockHost(hostname)()
+
+	// Look to see if it exists
+	_, err = as.storage.LoadPath(path)
+	if err == nil {
+		return &apiError{http.StatusConflict, "already managed"}
+	}
+
+	ckp, sans, err := primary.parse(hostname)
+	if err != nil {
+		return &apiError{http.StatusBadRequest, err.Error()}
+	}
+	chc := &credhubCert{
+		Source:      importedSourceName,
+		Type:        "user",
+		CA:          ckp.CA,
+		Certificate: ckp.Certificate,
+		PrivateKey:  ckp.PrivateKey,
+		SANs:        sans,
+		Team:        as.ownership().teamForHostname(hostname),
+		KeyType:     ckp.KeyType,
+	}
+
+	if secondary != nil {
+		skp, secondarySANs, err := secondary.parse(hostname)
+		if err != nil {
+			return &apiError{http.StatusBadRequest, "secondary: " + err.Error()}
+		}
+		if skp.KeyType == ckp.KeyType {
+			return &apiError{http.StatusBadRequest, "secondary: must have a different key type to the primary"}
+		}
+		if strings.Join(secondarySANs, " ") != strings.Join(sans, " ") {
+			return &apiError{http.StatusBadRequest, "secondary: must be for the same names as the primary"}
+		}
+		chc.SecondaryKeyType = skp.KeyType
+		chc.Secondary = skp
+	}
+
+	return as.certRenewer.ImportCert(hostname, chc)
+}
+
+// importResult is reported for each cert when importing
+type importResult struct {
+	Hostname string `json:"hostname"`
+	Status   string `json:"status"` // "imported", "skipped" if already managed, or "failed"
+	Error    string `json:"error,omitempty"`
+}
+
+// importChecked imports a cert if the logged in user may manage all of the names in it
+func (as *adminServer) importChecked(r *http.Request, liu *uaa.LoggedInUser, hostname string, primary, secondary *importPEMs) *importResult {
+	rv := &importResult{Hostname: hostname, Status: "imported"}
+
+	// Errors parsing are reported by importCert, so that they are audited
+	_, sans, _ := primary.parse(hostname)
+	err := as.checkCanManage(r, liu, hostname, sans)
+	if err == nil {
+		err = as.importCert(liu.EmailAddress, hostname, primary, secondary)
+	}
+	if err != nil {
+		rv.Status = "failed"
+		if ae, ok := err.(*apiError); ok && ae.status == http.StatusConflict {
+			rv.Status = "skipped"
+		}
+		rv.Error = err.Error()
+	}
+	return rv
+}
+
+// importTarball imports each cert in a tarball as written by outputObserver.createTarball,
+// so that certs can be migrated from another installation
+func (as *adminServer) importTarball(r *http.Request, liu *uaa.LoggedInUser, tarball io.Reader) ([]*importResult, error) {
+	certs, err := readTarball(tarball)
+	if err != nil {
+		return nil, &apiError{http.StatusBadRequest, "cannot read tarball: " + err.Error()}
+	}
+	rv := make([]*importResult, len(certs))
+	for i, tc := range certs {
+		rv[i] = as.importChecked(r, liu, tc.Hostname, tc.Primary, tc.Secondary)
+	}
+	return rv, nil
+}
+
 func (as *adminServer) flashMessage(w http.ResponseWriter, r *http.Request, m string) {
 	session, _ := as.cookies.Get(r, "f")
 	log.Println(m)
@@ -156,49 +948,51 @@ func (as *adminServer) flashMessage(w http.ResponseWriter, r *http.Request, m st
 }
 
 func (as *adminServer) update(vars map[string]string, liu *uaa.LoggedInUser, w http.ResponseWriter, r *http.Request) (map[string]interface{}, error) {
-	switch r.FormValue("action") {
-	case "create":
-		hostname := r.FormValue("host")
-		if len(hostname) == 0 {
-			as.flashMessage(w, r, "empty hostname")
-			break
-		}
-		path := pathFromHost(hostname)
+	action := r.FormValue("action")
+	if as.roleForRequest(r, liu) < actionRoles[action] {
+		as.flashMessage(w, r, "you do not have permission to do that")
+		http.Redirect(w, r, "/", http.StatusFound)
+		return nil, nil
+	}
 
-		// Look to see if it exists
-		_, err := as.storage.LoadPath(path)
-		if err == nil {
-			as.flashMessage(w, r, "already managed")
-			break
+	// Check the user's team owns the cert, or for new names, that it will
+	var hostname string
+	var sans []string
+	switch action {
+	case "create", "names":
+		hostname = r.FormValue("host")
+		var err error
+		sans, err = sansFor(hostname, parseHostnames(r.FormValue("sans")), strings.TrimSpace(r.FormValue("csr")))
+		if err != nil {
+			as.flashMessage(w, r, err.Error())
+			http.Redirect(w, r, "/", http.StatusFound)
+			return nil, nil
 		}
-
-		source := r.FormValue("source")
-		if len(source) == 0 {
-			as.flashMessage(w, r, "empty source")
-			break
+	case "source", "revoke", "compromise":
+		hostname = r.FormValue("host")
+	default:
+		hostname = hostFromPath(r.FormValue("path"))
+	}
+	if hostname != "" {
+		err := as.checkCanManage(r, liu, hostname, sans)
+		if err != nil {
+			as.flashMessage(w, r, err.Error())
+			http.Redirect(w, r, "/", http.StatusFound)
+			return nil, nil
 		}
+	}
 
-		err = as.storage.SavePath(path, &credhubCert{
-			Source: source,
-		})
+	redirectTo := "/"
+	switch action {
+	case "create":
+		err := as.createCert(liu.EmailAddress, r.FormValue("host"), r.FormValue("source"), parseHostnames(r.FormValue("sans")), r.FormValue("key_type"), r.FormValue("secondary_key_type"), r.FormValue("renewal_policy"), strings.TrimSpace(r.FormValue("csr")))
 		if err != nil {
 			as.flashMessage(w, r, err.Error())
 			break
 		}
 
 	case "delete":
-		hostname := hostFromPath(r.FormValue("path"))
-		if hostname == "" {
-			as.flashMessage(w, r, "cannot find cert")
-			break
-		}
-
-		if !as.certRenewer.CanDelete(hostname) {
-			as.flashMessage(w, r, "not allowed to delete cert for this server")
-			break
-		}
-
-		err := as.storage.DeletePath(pathFromHost(hostname))
+		err := as.deleteCert(liu.EmailAddress, hostFromPath(r.FormValue("path")), false)
 		if err != nil {
 			as.flashMessage(w, r, err.Error())
 			break
@@ -217,16 +1011,17 @@ func (as *adminServer) update(vars map[string]string, liu *uaa.LoggedInUser, w h
 		chd, err := as.storage.LoadPath(pathFromHost(hostname))
 		if err != nil {
 			as.flashMessage(w, r, err.Error())
+			break
 		}
 
-		err = as.certRenewer.RenewCertNow(hostname, chd.Source)
+		j, err := as.certRenewer.QueueJob(jobRenew, hostname, chd.Source, liu.EmailAddress)
 		if err != nil {
 			as.flashMessage(w, r, err.Error())
 			break
 		}
 
-		as.flashMessage(w, r, "cert successfully renewed")
-		break
+		as.flashMessage(w, r, "renewal queued as job "+j.ID)
+		redirectTo = "/jobs"
 
 	case "manual":
 		hostname := hostFromPath(r.FormValue("path"))
@@ -235,7 +1030,7 @@ func (as *adminServer) update(vars map[string]string, liu *uaa.LoggedInUser, w h
 			break
 		}
 
-		err := as.certRenewer.StartManualChallenge(hostname)
+		err := as.certRenewer.StartManualChallenge(hostname, liu.EmailAddress)
 		if err != nil {
 			as.flashMessage(w, r, err.Error())
 			break
@@ -251,50 +1046,75 @@ func (as *adminServer) update(vars map[string]string, liu *uaa.LoggedInUser, w h
 			break
 		}
 
-		err := as.certRenewer.CompleteChallenge(hostname)
+		j, err := as.certRenewer.QueueJob(jobComplete, hostname, "", liu.EmailAddress)
 		if err != nil {
 			as.flashMessage(w, r, err.Error())
 			break
 		}
 
-		as.flashMessage(w, r, "cert issued")
-		break
+		as.flashMessage(w, r, "challenge completion queued as job "+j.ID)
+		redirectTo = "/jobs"
 
 	case "source":
-		hostname := r.FormValue("host")
-		if len(hostname) == 0 {
-			as.flashMessage(w, r, "empty hostname")
+		err := as.changeSource(liu.EmailAddress, r.FormValue("host"), r.FormValue("source"))
+		if err != nil {
+			as.flashMessage(w, r, err.Error())
 			break
 		}
-		path := pathFromHost(hostname)
 
-		// Look to see if it exists
-		existing, err := as.storage.LoadPath(path)
+	case "names":
+		err := as.changeNames(liu.EmailAddress, r.FormValue("host"), parseHostnames(r.FormValue("sans")), r.FormValue("key_type"), r.FormValue("secondary_key_type"), r.FormValue("renewal_policy"), strings.TrimSpace(r.FormValue("csr")))
 		if err != nil {
 			as.flashMessage(w, r, err.Error())
 			break
 		}
 
-		source := r.FormValue("source")
-		if len(source) == 0 {
-			as.flashMessage(w, r, "empty source")
+		as.flashMessage(w, r, "settings updated, they will apply from the next renewal")
+
+	case "revoke":
+		j, err := as.revokeCert(liu.EmailAddress, r.FormValue("host"), r.FormValue("reason"))
+		if err != nil {
+			as.flashMessage(w, r, err.Error())
+			break
+		}
+
+		if j == nil {
+			as.flashMessage(w, r, "cert revoked, upload a CSR for a new key to issue a replacement")
+			break
+		}
+		as.flashMessage(w, r, "cert revoked, replacement queued as job "+j.ID)
+
+	case "compromise":
+		j, err := as.queueKeyCompromise(liu.EmailAddress, r.FormValue("host"))
+		if err != nil {
+			as.flashMessage(w, r, err.Error())
 			break
 		}
 
-		existing.Source = source
+		as.flashMessage(w, r, "key compromise queued as job "+j.ID)
+		redirectTo = "/jobs"
+
+	case "restore":
+		hostname := hostFromPath(r.FormValue("path"))
+		if hostname == "" {
+			as.flashMessage(w, r, "cannot find cert")
+			break
+		}
 
-		err = as.storage.SavePath(path, existing)
+		err := as.certRenewer.RestoreVersion(hostname, r.FormValue("version"), liu.EmailAddress)
 		if err != nil {
 			as.flashMessage(w, r, err.Error())
 			break
 		}
 
+		as.flashMessage(w, r, "previous version restored")
+
 	default:
 		as.flashMessage(w, r, "unknown action")
 		break
 	}
 
-	http.Redirect(w, r, "/", http.StatusFound)
+	http.Redirect(w, r, redirectTo, http.StatusFound)
 	return nil, nil
 }
 
@@ -304,7 +1124,13 @@ type uiCert struct {
 	ShowDelete    bool
 	ShowRenew     bool
 	ShowManual    bool
+	ShowRevoke    bool
+	CanManage     bool
+	Imported      bool // never renewed, so the source should be changed before it expires
+	Team          string
 	DaysRemaining int
+	KeyTypes      []string
+	Renewal       *renewalState // nil if never renewed
 	CredHubCert   *credhubCert
 }
 
@@ -315,14 +1141,31 @@ func (as *adminServer) home(vars map[string]string, liu *uaa.LoggedInUser, w htt
 		return nil, err
 	}
 
-	certsForUI := make([]uiCert, len(certs))
-	for i, curCred := range certs {
+	rl := as.roleForRequest(r, liu)
+
+	// By default only show certs owned by the user's teams, if they are in any
+	oc := as.ownership()
+	var teams []string
+	if oc.configured() {
+		teams = as.teamsForRequest(r, liu)
+	}
+	showAll := r.FormValue("all") != "" || len(teams) == 0
+
+	certsForUI := make([]uiCert, 0, len(certs))
+	for _, curCred := range certs {
 		nameToShow := hostFromPath(curCred.path)
 		if nameToShow == "" {
 			nameToShow = "cannot decode: " + string(curCred.path)
 		}
 
+		team := oc.teamFor(nameToShow, curCred)
+		if !showAll && !stringInList(team, teams) {
+			continue
+		}
+		canManage := as.canManageTeam(r, liu, team)
+
 		daysRemaining := -1
+		var issuedKeyTypes []string
 
 		if curCred.Certificate != "" {
 			block, _ := pem.Decode([]byte(curCred.Certificate))
@@ -339,17 +1182,27 @@ func (as *adminServer) home(vars map[string]string, liu *uaa.LoggedInUser, w htt
 			}
 
 			daysRemaining = int(pc.NotAfter.Sub(time.Now()).Hours() / 24)
+			issuedKeyTypes = append(issuedKeyTypes, keyTypeOf(pc.PublicKey))
+			if curCred.Secondary != nil {
+				issuedKeyTypes = append(issuedKeyTypes, curCred.Secondary.KeyType)
+			}
 		}
 
-		certsForUI[i] = uiCert{
+		certsForUI = append(certsForUI, uiCert{
 			Name:          nameToShow,
 			Path:          curCred.path,
 			DaysRemaining: daysRemaining,
-			ShowDelete:    as.certRenewer.CanDelete(nameToShow),
-			ShowRenew:     true,
-			ShowManual:    as.certRenewer.SourceCanManual(curCred.Source),
+			KeyTypes:      issuedKeyTypes,
+			ShowDelete:    canManage && rl >= roleAdmin && as.certRenewer.CanDelete(nameToShow),
+			ShowRenew:     canManage && rl >= roleOperator && curCred.Source != importedSourceName,
+			ShowManual:    canManage && rl >= roleOperator && as.certRenewer.SourceCanManual(curCred.Source),
+			ShowRevoke:    canManage && rl >= roleOperator && as.certRenewer.SourceCanRevoke(curCred.Source) && curCred.Certificate != "" && !curCred.fullyRevoked(),
+			CanManage:     canManage,
+			Imported:      curCred.Source == importedSourceName,
+			Team:          team,
+			Renewal:       as.certRenewer.RenewalState(nameToShow),
 			CredHubCert:   curCred,
-		}
+		})
 	}
 
 	sort.Slice(certsForUI, func(i, j int) bool {
@@ -362,15 +1215,24 @@ func (as *adminServer) home(vars map[string]string, liu *uaa.LoggedInUser, w htt
 		session.Save(r, w)
 	}
 
+	leader, isLeader := as.certRenewer.Leader()
+
 	return map[string]interface{}{
-		"certs":    certsForUI,
-		"messages": flashes,
+		"certs":       certsForUI,
+		"messages":    flashes,
+		"localTokens": as.API.LocalTokens,
+		"ownership":   oc.configured(),
+		"teams":       teams,
+		"showAll":     showAll,
+		"leader":      leader,
+		"isLeader":    isLeader,
 	}, nil
 }
 
 // Fetch the logged in user, and create a cloudfoundry client object and pass that to the underlying real handler.
 // Finally, if a template name is specified, and no error returned, execute the template with the values returned
-func (as *adminServer) wrapWithClient(tmpl string, f func(vars map[string]string, liu *uaa.LoggedInUser, w http.ResponseWriter, r *http.Request) (map[string]interface{}, error)) http.HandlerFunc {
+// The user must have at least minRole.
+func (as *adminServer) wrapWithClient(tmpl string, minRole role, f func(vars map[string]string, liu *uaa.LoggedInUser, w http.ResponseWriter, r *http.Request) (map[string]interface{}, error)) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		liu, ok := r.Context().Value(uaa.KeyLoggedInUser).(*uaa.LoggedInUser)
 		if !ok {
@@ -379,6 +1241,18 @@ func (as *adminServer) wrapWithClient(tmpl string, f func(vars map[string]string
 			return
 		}
 
+		rl := as.roleForRequest(r, liu)
+		if rl < minRole {
+			if rl == roleNone {
+				log.Println("no role for:", liu.EmailAddress)
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			as.flashMessage(w, r, "you do not have permission to do that")
+			http.Redirect(w, r, "/", http.StatusFound)
+			return
+		}
+
 		toPass, err := f(mux.Vars(r), liu, w, r)
 		if err != nil {
 			log.Println(err)
@@ -403,6 +1277,9 @@ func (as *adminServer) wrapWithClient(tmpl string, f func(vars map[string]string
 			return
 		}
 		toPass["user"] = liu
+		toPass["role"] = rl.String()
+		toPass["canOperate"] = rl >= roleOperator
+		toPass["canAdmin"] = rl >= roleAdmin
 		toPass[csrf.TemplateTag] = csrf.TemplateField(r)
 		template.Must(template.New("orgs").Parse(string(data))).Execute(w, toPass)
 	}
@@ -410,14 +1287,23 @@ func (as *adminServer) wrapWithClient(tmpl string, f func(vars map[string]string
 
 func (as *adminServer) createAdminHandler() http.Handler {
 	r := mux.NewRouter()
-	r.HandleFunc("/", as.wrapWithClient("index.html", as.home))
-	r.HandleFunc("/add", as.wrapWithClient("add.html", as.add))
-	r.HandleFunc("/source", as.wrapWithClient("source.html", as.source))
-	r.HandleFunc("/update", as.wrapWithClient("", as.update)) // will redirect back to home
+	r.HandleFunc("/", as.wrapWithClient("index.html", roleViewer, as.home))
+	r.HandleFunc("/add", as.wrapWithClient("add.html", roleAdmin, as.add))
+	r.HandleFunc("/source", as.wrapWithClient("source.html", roleAdmin, as.source))
+	r.HandleFunc("/names", as.wrapWithClient("names.html", roleAdmin, as.names))
+	r.HandleFunc("/revoke", as.wrapWithClient("revoke.html", roleOperator, as.revoke))
+	r.HandleFunc("/import", as.wrapWithClient("import.html", roleAdmin, as.importPage))
+	r.HandleFunc("/history", as.wrapWithClient("history.html", roleViewer, as.history))
+	r.HandleFunc("/tokens", as.wrapWithClient("tokens.html", roleAdmin, as.tokens))
+	r.HandleFunc("/audit", as.wrapWithClient("audit.html", roleViewer, as.auditPage))
+	r.HandleFunc("/jobs", as.wrapWithClient("jobs.html", roleViewer, as.jobs))
+	r.HandleFunc("/update", as.wrapWithClient("", roleViewer, as.update)) // will redirect back to home
 
 	// This URL is not secured, and excluded in the wrapper earlier
 	r.Handle("/metrics", promhttp.Handler())
 
+	as.addAPIRoutes(r)
+
 	// TODO, check whether cast is really the right thing here...
 
 	n := negroni.New()
@@ -435,5 +1321,12 @@ func (as *adminServer) createAdminHandler() http.Handler {
 	n.Use(negroni.NewRecovery())
 	n.UseHandler(r)
 
-	return csrf.Protect([]byte(as.CSRFKey))(n)
+	protected := csrf.Protect([]byte(as.CSRFKey))(n)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Bearer tokens aren't sent automatically by browsers, so CSRF tokens aren't needed
+		if isAPIBearerRequest(r) {
+			r = csrf.UnsafeSkipCheck(r)
+		}
+		protected.ServeHTTP(w, r)
+	})
 }
@@ -0,0 +1,522 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/govau/cf-common/uaa"
+)
+
+// apiError is returned by handlers where the HTTP status code should be something other than 500
+type apiError struct {
+	status  int
+	message string
+}
+
+func (ae *apiError) Error() string {
+	return ae.message
+}
+
+// apiCert is the JSON representation of a managed cert. Private keys are never returned.
+type apiCert struct {
+	Hostname         string        `json:"hostname"`
+	SANs             []string      `json:"sans"`
+	Source           string        `json:"source"`
+	Team             string        `json:"team,omitempty"`
+	KeyType          string        `json:"key_type,omitempty"`
+	SecondaryKeyType string        `json:"secondary_key_type,omitempty"`
+	RenewalPolicy    string        `json:"renewal_policy,omitempty"`
+	CSR              string        `json:"csr,omitempty"`
+	Revoked          *revocation   `json:"revoked,omitempty"`
+	Issued           bool          `json:"issued"`
+	IssuedKeyTypes   []string      `json:"issued_key_types,omitempty"`
+	Serial           string        `json:"serial,omitempty"`
+	Issuer           string        `json:"issuer,omitempty"`
+	NotBefore        *time.Time    `json:"not_before,omitempty"`
+	NotAfter         *time.Time    `json:"not_after,omitempty"`
+	DaysRemaining    int           `json:"days_remaining"`
+	Certificate      string        `json:"certificate,omitempty"`
+	CA               string        `json:"ca,omitempty"`
+	Challenge        *apiChallenge `json:"challenge,omitempty"`
+	Renewal          *renewalState `json:"renewal,omitempty"`
+	CanDelete        bool          `json:"can_delete"`
+}
+
+type apiChallenge struct {
+	Instructions string `json:"instructions"`
+}
+
+// apiCertRequest is accepted when creating a cert or changing its source
+type apiCertRequest struct {
+	Hostname         string   `json:"hostname"`
+	Source           string   `json:"source"`
+	SANs             []string `json:"sans"`
+	KeyType          string   `json:"key_type"`
+	SecondaryKeyType string   `json:"secondary_key_type"`
+	RenewalPolicy    string   `json:"renewal_policy"`
+	CSR              string   `json:"csr"`
+}
+
+// apiImportRequest is accepted when importing a cert issued elsewhere
+type apiImportRequest struct {
+	Hostname string `json:"hostname"`
+	importPEMs
+	Secondary *importPEMs `json:"secondary"` // optional, for a second key type
+}
+
+// maxImportTarballSize limits the size of tarballs uploaded for import
+const maxImportTarballSize = 64 << 20
+
+func (as *adminServer) apiCertFrom(chc *credhubCert) (*apiCert, error) {
+	hostname := hostFromPath(chc.path)
+	rv := &apiCert{
+		Hostname:         hostname,
+		SANs:             chc.SANs,
+		Source:           chc.Source,
+		Team:             as.ownership().teamFor(hostname, chc),
+		KeyType:          chc.KeyType,
+		SecondaryKeyType: chc.SecondaryKeyType,
+		RenewalPolicy:    chc.RenewalPolicy,
+		CSR:              chc.CSR,
+		Revoked:          chc.Revoked,
+		DaysRemaining:    -1,
+		Renewal:          as.certRenewer.RenewalState(hostname),
+		CanDelete:        as.certRenewer.CanDelete(hostname),
+	}
+	if rv.SANs == nil {
+		rv.SANs = []string{}
+	}
+	if chc.Challenge != nil {
+		rv.Challenge = &apiChallenge{
+			Instructions: chc.Challenge.Instructions(),
+		}
+	}
+	if chc.Certificate != "" {
+		pc, err := parseCertificatePEM(chc.Certificate)
+		if err != nil {
+			return nil, err
+		}
+		rv.Issued = true
+		rv.IssuedKeyTypes = []string{keyTypeOf(pc.PublicKey)}
+		if chc.Secondary != nil {
+			rv.IssuedKeyTypes = append(rv.IssuedKeyTypes, chc.Secondary.KeyType)
+		}
+		rv.Serial = pc.SerialNumber.Text(16)
+		rv.Issuer = pc.Issuer.String()
+		rv.NotBefore = &pc.NotBefore
+		rv.NotAfter = &pc.NotAfter
+		rv.DaysRemaining = int(pc.NotAfter.Sub(time.Now()).Hours() / 24)
+		rv.Certificate = chc.Certificate
+		rv.CA = chc.CA
+	}
+	return rv, nil
+}
+
+// apiLoadCert returns the JSON representation of the cert currently stored for hostname
+func (as *adminServer) apiLoadCert(hostname string) (*apiCert, error) {
+	chc, err := as.storage.LoadPath(pathFromHost(hostname))
+	if err != nil {
+		return nil, err
+	}
+	return as.apiCertFrom(chc)
+}
+
+func (as *adminServer) apiList(vars map[string]string, liu *uaa.LoggedInUser, r *http.Request) (int, interface{}, error) {
+	certs, err := as.storage.FetchCerts()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	rv := make([]*apiCert, len(certs))
+	for i, chc := range certs {
+		rv[i], err = as.apiCertFrom(chc)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+
+	sort.Slice(rv, func(i, j int) bool {
+		return rv[i].Hostname < rv[j].Hostname
+	})
+
+	return http.StatusOK, map[string]interface{}{
+		"certs": rv,
+	}, nil
+}
+
+func (as *adminServer) apiGet(vars map[string]string, liu *uaa.LoggedInUser, r *http.Request) (int, interface{}, error) {
+	rv, err := as.apiLoadCert(vars["hostname"])
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, rv, nil
+}
+
+func (as *adminServer) apiCreate(vars map[string]string, liu *uaa.LoggedInUser, r *http.Request) (int, interface{}, error) {
+	var req apiCertRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return 0, nil, &apiError{http.StatusBadRequest, "invalid JSON in request body"}
+	}
+
+	sans, err := sansFor(req.Hostname, parseHostnames(strings.Join(req.SANs, " ")), strings.TrimSpace(req.CSR))
+	if err != nil {
+		return 0, nil, err
+	}
+	err = as.checkCanManage(r, liu, req.Hostname, sans)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	err = as.createCert(liu.EmailAddress, req.Hostname, req.Source, sans, req.KeyType, req.SecondaryKeyType, req.RenewalPolicy, strings.TrimSpace(req.CSR))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	rv, err := as.apiLoadCert(req.Hostname)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusCreated, rv, nil
+}
+
+func (as *adminServer) apiImport(vars map[string]string, liu *uaa.LoggedInUser, r *http.Request) (int, interface{}, error) {
+	var req apiImportRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return 0, nil, &apiError{http.StatusBadRequest, "invalid JSON in request body"}
+	}
+
+	_, sans, err := req.parse(req.Hostname)
+	if err != nil {
+		return 0, nil, &apiError{http.StatusBadRequest, err.Error()}
+	}
+	err = as.checkCanManage(r, liu, req.Hostname, sans)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	err = as.importCert(liu.EmailAddress, req.Hostname, &req.importPEMs, req.Secondary)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	rv, err := as.apiLoadCert(req.Hostname)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusCreated, rv, nil
+}
+
+// apiImportTarball imports every cert in a tarball, reporting how each went rather than failing as a whole
+func (as *adminServer) apiImportTarball(vars map[string]string, liu *uaa.LoggedInUser, r *http.Request) (int, interface{}, error) {
+	rv, err := as.importTarball(r, liu, io.LimitReader(r.Body, maxImportTarballSize))
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, map[string]interface{}{
+		"results": rv,
+	}, nil
+}
+
+func (as *adminServer) apiDelete(vars map[string]string, liu *uaa.LoggedInUser, r *http.Request) (int, interface{}, error) {
+	err := as.checkCanManage(r, liu, vars["hostname"], nil)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// ?revoke=true revokes it first, even if the source doesn't do so by default
+	err = as.deleteCert(liu.EmailAddress, vars["hostname"], r.FormValue("revoke") == "true")
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusNoContent, nil, nil
+}
+
+func (as *adminServer) apiSource(vars map[string]string, liu *uaa.LoggedInUser, r *http.Request) (int, interface{}, error) {
+	var req apiCertRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return 0, nil, &apiError{http.StatusBadRequest, "invalid JSON in request body"}
+	}
+
+	err = as.checkCanManage(r, liu, vars["hostname"], nil)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	err = as.changeSource(liu.EmailAddress, vars["hostname"], req.Source)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	rv, err := as.apiLoadCert(vars["hostname"])
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, rv, nil
+}
+
+// apiCSR sets the CSR that certs are issued for, or if empty, has keys generated here again
+func (as *adminServer) apiCSR(vars map[string]string, liu *uaa.LoggedInUser, r *http.Request) (int, interface{}, error) {
+	var req apiCertRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return 0, nil, &apiError{http.StatusBadRequest, "invalid JSON in request body"}
+	}
+
+	hostname := vars["hostname"]
+	existing, err := as.storage.LoadPath(pathFromHost(hostname))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	csr := strings.TrimSpace(req.CSR)
+	sans, err := sansFor(hostname, existing.SANs, csr)
+	if err != nil {
+		return 0, nil, err
+	}
+	err = as.checkCanManage(r, liu, hostname, sans)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	err = as.changeNames(liu.EmailAddress, hostname, sans, existing.KeyType, existing.SecondaryKeyType, existing.RenewalPolicy, csr)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	rv, err := as.apiLoadCert(hostname)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, rv, nil
+}
+
+func (as *adminServer) apiRenew(vars map[string]string, liu *uaa.LoggedInUser, r *http.Request) (int, interface{}, error) {
+	hostname := vars["hostname"]
+	err := as.checkCanManage(r, liu, hostname, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	chc, err := as.storage.LoadPath(pathFromHost(hostname))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	j, err := as.certRenewer.QueueJob(jobRenew, hostname, chc.Source, liu.EmailAddress)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusAccepted, j, nil
+}
+
+func (as *adminServer) apiRevoke(vars map[string]string, liu *uaa.LoggedInUser, r *http.Request) (int, interface{}, error) {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return 0, nil, &apiError{http.StatusBadRequest, "invalid JSON in request body"}
+	}
+	if req.Reason == "" {
+		req.Reason = revocationReasonUnspecified
+	}
+
+	hostname := vars["hostname"]
+	err = as.checkCanManage(r, liu, hostname, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	_, err = as.revokeCert(liu.EmailAddress, hostname, req.Reason)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	rv, err := as.apiLoadCert(hostname)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, rv, nil
+}
+
+func (as *adminServer) apiCompromise(vars map[string]string, liu *uaa.LoggedInUser, r *http.Request) (int, interface{}, error) {
+	hostname := vars["hostname"]
+	err := as.checkCanManage(r, liu, hostname, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	j, err := as.queueKeyCompromise(liu.EmailAddress, hostname)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusAccepted, j, nil
+}
+
+func (as *adminServer) apiManual(vars map[string]string, liu *uaa.LoggedInUser, r *http.Request) (int, interface{}, error) {
+	hostname := vars["hostname"]
+	err := as.checkCanManage(r, liu, hostname, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	chc, err := as.storage.LoadPath(pathFromHost(hostname))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if !as.certRenewer.SourceCanManual(chc.Source) {
+		return 0, nil, &apiError{http.StatusConflict, "source does not support manual challenges"}
+	}
+
+	err = as.certRenewer.StartManualChallenge(hostname, liu.EmailAddress)
+	if err != nil {
+		return 0, nil, &apiError{http.StatusBadGateway, err.Error()}
+	}
+
+	rv, err := as.apiLoadCert(hostname)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, rv, nil
+}
+
+func (as *adminServer) apiComplete(vars map[string]string, liu *uaa.LoggedInUser, r *http.Request) (int, interface{}, error) {
+	hostname := vars["hostname"]
+	err := as.checkCanManage(r, liu, hostname, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	chc, err := as.storage.LoadPath(pathFromHost(hostname))
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if chc.Challenge == nil {
+		return 0, nil, &apiError{http.StatusConflict, "challenge not set"}
+	}
+
+	j, err := as.certRenewer.QueueJob(jobComplete, hostname, "", liu.EmailAddress)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusAccepted, j, nil
+}
+
+func (as *adminServer) apiJobs(vars map[string]string, liu *uaa.LoggedInUser, r *http.Request) (int, interface{}, error) {
+	return http.StatusOK, map[string]interface{}{
+		"jobs": as.certRenewer.Jobs(),
+	}, nil
+}
+
+func (as *adminServer) apiJob(vars map[string]string, liu *uaa.LoggedInUser, r *http.Request) (int, interface{}, error) {
+	j := as.certRenewer.Job(vars["id"])
+	if j == nil {
+		return 0, nil, &apiError{http.StatusNotFound, "cannot find job"}
+	}
+	return http.StatusOK, j, nil
+}
+
+func (as *adminServer) apiSources(vars map[string]string, liu *uaa.LoggedInUser, r *http.Request) (int, interface{}, error) {
+	return http.StatusOK, map[string]interface{}{
+		"sources":            as.certRenewer.Sources(),
+		"key_types":          keyTypes,
+		"renewal_policies":   renewalPolicies,
+		"revocation_reasons": revocationReasons,
+	}, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if v != nil {
+		err := json.NewEncoder(w).Encode(v)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// wrapAPI is like wrapWithClient, but writes the value returned as JSON, and errors as a JSON body with an appropriate status code
+func (as *adminServer) wrapAPI(minRole role, f func(vars map[string]string, liu *uaa.LoggedInUser, r *http.Request) (int, interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		liu, ok := r.Context().Value(uaa.KeyLoggedInUser).(*uaa.LoggedInUser)
+		if !ok {
+			log.Println("bad type")
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+			return
+		}
+
+		if as.roleForRequest(r, liu) < minRole {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "requires role: " + minRole.String()})
+			return
+		}
+
+		status, rv, err := f(mux.Vars(r), liu, r)
+		if err != nil {
+			switch e := err.(type) {
+			case *apiError:
+				writeJSON(w, e.status, map[string]string{"error": e.message})
+			default:
+				if err == errCertNotFound {
+					writeJSON(w, http.StatusNotFound, map[string]string{"error": "cannot find cert"})
+					return
+				}
+				log.Println(err)
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			}
+			return
+		}
+
+		writeJSON(w, status, rv)
+	}
+}
+
+func (as *adminServer) serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	data, err := Asset("data/openapi.json")
+	if err != nil {
+		log.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// isAPIBearerRequest returns true if this is an API call made with a bearer token, rather than from a browser session
+func isAPIBearerRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/api/") && r.Header.Get("Authorization") != ""
+}
+
+func (as *adminServer) addAPIRoutes(r *mux.Router) {
+	// This URL is not secured, and excluded in the wrapper earlier
+	r.HandleFunc("/api/v1/openapi.json", as.serveOpenAPI).Methods(http.MethodGet)
+
+	api := r.PathPrefix("/api/v1").Subrouter()
+	api.HandleFunc("/sources", as.wrapAPI(roleViewer, as.apiSources)).Methods(http.MethodGet)
+	api.HandleFunc("/certs", as.wrapAPI(roleViewer, as.apiList)).Methods(http.MethodGet)
+	api.HandleFunc("/certs", as.wrapAPI(roleAdmin, as.apiCreate)).Methods(http.MethodPost)
+	api.HandleFunc("/certs/{hostname}", as.wrapAPI(roleViewer, as.apiGet)).Methods(http.MethodGet)
+	api.HandleFunc("/import", as.wrapAPI(roleAdmin, as.apiImport)).Methods(http.MethodPost)
+	api.HandleFunc("/import/tarball", as.wrapAPI(roleAdmin, as.apiImportTarball)).Methods(http.MethodPost)
+	api.HandleFunc("/certs/{hostname}", as.wrapAPI(roleAdmin, as.apiDelete)).Methods(http.MethodDelete)
+	api.HandleFunc("/certs/{hostname}/source", as.wrapAPI(roleAdmin, as.apiSource)).Methods(http.MethodPut)
+	api.HandleFunc("/certs/{hostname}/csr", as.wrapAPI(roleAdmin, as.apiCSR)).Methods(http.MethodPut)
+	api.HandleFunc("/certs/{hostname}/renew", as.wrapAPI(roleOperator, as.apiRenew)).Methods(http.MethodPost)
+	api.HandleFunc("/certs/{hostname}/manual", as.wrapAPI(roleOperator, as.apiManual)).Methods(http.MethodPost)
+	api.HandleFunc("/certs/{hostname}/complete", as.wrapAPI(roleOperator, as.apiComplete)).Methods(http.MethodPost)
+	api.HandleFunc("/certs/{hostname}/revoke", as.wrapAPI(roleAdmin, as.apiRevoke)).Methods(http.MethodPost)
+	api.HandleFunc("/certs/{hostname}/compromise", as.wrapAPI(roleOperator, as.apiCompromise)).Methods(http.MethodPost)
+	api.HandleFunc("/jobs", as.wrapAPI(roleViewer, as.apiJobs)).Methods(http.MethodGet)
+	api.HandleFunc("/jobs/{id}", as.wrapAPI(roleViewer, as.apiJob)).Methods(http.MethodGet)
+}
@@ -1,8 +1,15 @@
 package main
 
 import (
+	"context"
+	"crypto"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -10,33 +17,222 @@ import (
 	"github.com/meatballhat/negroni-logrus"
 	log "github.com/sirupsen/logrus"
 	"github.com/urfave/negroni"
+	"golang.org/x/crypto/acme"
 )
 
 type serverResponder struct {
 	Port int `yaml:"port"`
 
-	uiManager         string
-	challengeMutex    sync.RWMutex
-	challengeResponse map[string][]byte
+	// TLSALPNPort, if set, is where tls-alpn-01 challenges are answered, e.g. for edges that only forward 443
+	TLSALPNPort int `yaml:"tls_alpn_port"`
+
+	// ChallengeStore holds http-01 responses and tls-alpn-01 certs. Use storage if several instances run behind a load balancer.
+	ChallengeStore challengeStoreConf `yaml:"challenge_store"`
+
+	uiManager  string
+	crls       crlPublisher
+	challenges challengeStore
+
+	serverMutex  sync.Mutex
+	server       *http.Server
+	alpnListener net.Listener
+	stopped      bool
+}
+
+type crlPublisher interface {
+	// CRL returns the current DER CRL for a source, or errCertNotFound if it doesn't publish one
+	CRL(source string) ([]byte, error)
 }
 
-func (sr *serverResponder) Init(extUrlForConvenience string) error {
-	sr.challengeResponse = make(map[string][]byte)
+func (sr *serverResponder) Init(extUrlForConvenience string, crls crlPublisher, storage certStorage) error {
+	cs, err := sr.ChallengeStore.Init(storage)
+	if err != nil {
+		return err
+	}
+	sr.challenges = cs
 	sr.uiManager = extUrlForConvenience
+	sr.crls = crls
 	return nil
 }
 
+// serveCRL serves /crl/<source>.crl for sources with their own CA, over plain HTTP as clients expect
+func (sr *serverResponder) serveCRL(w http.ResponseWriter, r *http.Request) {
+	source := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/crl/"), ".crl")
+	crl, err := sr.crls.CRL(source)
+	if err != nil {
+		if err != errCertNotFound {
+			log.Println("error generating CRL:", err)
+			http.Error(w, "error generating CRL", http.StatusInternalServerError)
+			return
+		}
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pkix-crl")
+	w.Write(crl)
+}
+
 func (sr *serverResponder) SetChallengeValue(k string, v []byte) error {
-	sr.challengeMutex.Lock()
-	sr.challengeResponse[k] = v
-	sr.challengeMutex.Unlock()
-	return nil
+	return sr.challenges.Set(k, v, challengeTTL)
 }
 
 func (sr *serverResponder) ClearChallengeValue(k string) {
-	sr.challengeMutex.Lock()
-	delete(sr.challengeResponse, k)
-	sr.challengeMutex.Unlock()
+	err := sr.challenges.Delete(k)
+	if err != nil {
+		metricErrors.WithLabelValues("challenge_store").Inc()
+		log.Printf("error clearing challenge %s, it will expire: %s", k, err)
+	}
+}
+
+// SweepChallenges deletes expired challenge responses, whichever instance set them
+func (sr *serverResponder) SweepChallenges() {
+	err := sr.challenges.Sweep()
+	if err != nil {
+		metricErrors.WithLabelValues("challenge_store").Inc()
+		log.Println("error sweeping expired challenges, will try again next scan:", err)
+	}
+}
+
+// serveChallenge serves an http-01 response, from whichever instance set it
+func (sr *serverResponder) serveChallenge(w http.ResponseWriter, r *http.Request) {
+	v, err := sr.challenges.Get(r.URL.Path)
+	if err != nil {
+		if err != errCertNotFound {
+			metricErrors.WithLabelValues("challenge_store").Inc()
+			log.Println("error fetching challenge:", err)
+			http.Error(w, "error fetching challenge", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("404 %s", r.URL.String())
+		http.NotFound(w, r)
+		return
+	}
+	w.Write(v)
+}
+
+// SupportsTLSALPN returns true if a port is configured to answer tls-alpn-01 challenges on
+func (sr *serverResponder) SupportsTLSALPN() bool {
+	return sr.TLSALPNPort != 0
+}
+
+// storedChallengeCert is a tls-alpn-01 cert as kept in the challenge store
+type storedChallengeCert struct {
+	Certificate [][]byte `json:"certificate"` // DER chain
+	PrivateKey  string   `json:"private_key"` // PEM, per marshalPrivateKey
+}
+
+// challengeCertKey is the key in the challenge store for the tls-alpn-01 cert for hostname.
+// http-01 keys are URL paths, so can't clash.
+func challengeCertKey(hostname string) string {
+	return "tls-alpn-01:" + strings.ToLower(hostname)
+}
+
+func (sr *serverResponder) SetChallengeCert(hostname string, cert tls.Certificate) error {
+	if !sr.SupportsTLSALPN() {
+		return errors.New("no tls_alpn_port configured for the acme responder")
+	}
+	key, ok := cert.PrivateKey.(crypto.Signer)
+	if !ok {
+		return errors.New("unsupported tls-alpn-01 challenge key")
+	}
+	pk, err := marshalPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	v, err := json.Marshal(&storedChallengeCert{
+		Certificate: cert.Certificate,
+		PrivateKey:  pk,
+	})
+	if err != nil {
+		return err
+	}
+	return sr.challenges.Set(challengeCertKey(hostname), v, challengeTTL)
+}
+
+func (sr *serverResponder) ClearChallengeCert(hostname string) {
+	err := sr.challenges.Delete(challengeCertKey(hostname))
+	if err != nil {
+		metricErrors.WithLabelValues("challenge_store").Inc()
+		log.Printf("error clearing tls-alpn-01 challenge for %s, it will expire: %s", hostname, err)
+	}
+}
+
+// getChallengeCert returns the cert for a tls-alpn-01 challenge. Nothing else is served on this port.
+func (sr *serverResponder) getChallengeCert(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	acmeProto := false
+	for _, p := range hello.SupportedProtos {
+		if p == acme.ALPNProto {
+			acmeProto = true
+		}
+	}
+	if !acmeProto {
+		return nil, fmt.Errorf("client did not ask for %s", acme.ALPNProto)
+	}
+
+	v, err := sr.challenges.Get(challengeCertKey(hello.ServerName))
+	if err != nil {
+		if err != errCertNotFound {
+			metricErrors.WithLabelValues("challenge_store").Inc()
+			return nil, err
+		}
+		return nil, fmt.Errorf("no tls-alpn-01 challenge for %q", hello.ServerName)
+	}
+	var scc storedChallengeCert
+	err = json.Unmarshal(v, &scc)
+	if err != nil {
+		return nil, err
+	}
+	key, err := parsePrivateKey(scc.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{
+		Certificate: scc.Certificate,
+		PrivateKey:  key,
+	}, nil
+}
+
+// runTLSALPN answers tls-alpn-01 challenges. The CA only needs to complete a handshake, so no requests are read.
+func (sr *serverResponder) runTLSALPN() {
+	l, err := tls.Listen("tcp", fmt.Sprintf(":%d", sr.TLSALPNPort), &tls.Config{
+		NextProtos:     []string{acme.ALPNProto},
+		GetCertificate: sr.getChallengeCert,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	sr.serverMutex.Lock()
+	if sr.stopped {
+		sr.serverMutex.Unlock()
+		l.Close()
+		return
+	}
+	sr.alpnListener = l
+	sr.serverMutex.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if sr.isStopped() {
+				return
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			log.Fatal(err)
+		}
+		go func(conn *tls.Conn) {
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(10 * time.Second))
+			err := conn.Handshake()
+			if err != nil {
+				log.Printf("tls-alpn-01 handshake from %s failed: %s", conn.RemoteAddr(), err)
+				return
+			}
+			log.Printf("tls-alpn-01 challenge served for %s", conn.ConnectionState().ServerName)
+		}(conn.(*tls.Conn))
+	}
 }
 
 func (sr *serverResponder) RunForever() {
@@ -60,15 +256,16 @@ func (sr *serverResponder) RunForever() {
 			http.Redirect(w, r, sr.uiManager, http.StatusMovedPermanently)
 			return
 		}
-		sr.challengeMutex.RLock()
-		v, ok := sr.challengeResponse[r.URL.Path]
-		sr.challengeMutex.RUnlock()
-		if !ok {
-			log.Printf("404 %s", r.URL.String())
-			http.NotFound(w, r)
+		if strings.HasPrefix(r.URL.Path, "/crl/") && strings.HasSuffix(r.URL.Path, ".crl") {
+			sr.serveCRL(w, r)
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, "/.well-known/acme-challenge/") {
+			sr.serveChallenge(w, r)
 			return
 		}
-		w.Write(v)
+		log.Printf("404 %s", r.URL.String())
+		http.NotFound(w, r)
 	}))
 
 	// logging setup
@@ -82,11 +279,47 @@ func (sr *serverResponder) RunForever() {
 	hook.SetSync(log.PanicLevel, log.FatalLevel) // sync (blocking) for fatal stuff
 	log.AddHook(hook)
 
-	log.Fatal((&http.Server{
+	if sr.SupportsTLSALPN() {
+		go sr.runTLSALPN()
+	}
+
+	server := &http.Server{
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  10 * time.Second, // we recently ended up with a huge amount of conns left open
 		Addr:         fmt.Sprintf(":%d", sr.Port),
 		Handler:      n,
-	}).ListenAndServe())
+	}
+	sr.serverMutex.Lock()
+	sr.server = server
+	sr.serverMutex.Unlock()
+
+	err := server.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return // Shutdown was called
+	}
+	log.Fatal(err)
+}
+
+func (sr *serverResponder) isStopped() bool {
+	sr.serverMutex.Lock()
+	defer sr.serverMutex.Unlock()
+	return sr.stopped
+}
+
+// Shutdown stops answering challenges, waiting for requests in progress to finish until ctx is done.
+// It should be called last, as orders still in progress may need it.
+func (sr *serverResponder) Shutdown(ctx context.Context) error {
+	sr.serverMutex.Lock()
+	sr.stopped = true
+	server, l := sr.server, sr.alpnListener
+	sr.serverMutex.Unlock()
+
+	if l != nil {
+		l.Close()
+	}
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
 }
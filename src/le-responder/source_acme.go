@@ -2,15 +2,17 @@ package main
 
 import (
 	"context"
+	"crypto"
 	"crypto/rand"
-	"crypto/rsa"
+	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
-	"encoding/pem"
 	"errors"
 	"fmt"
 	log "github.com/sirupsen/logrus"
+	"strings"
 	"sync"
+	"time"
 
 	"golang.org/x/crypto/acme"
 )
@@ -18,30 +20,44 @@ import (
 type responder interface {
 	SetChallengeValue(k string, v []byte) error
 	ClearChallengeValue(k string)
+
+	SupportsTLSALPN() bool
+	SetChallengeCert(hostname string, cert tls.Certificate) error
+	ClearChallengeCert(hostname string)
+
+	// SweepChallenges deletes expired responses, e.g. left by an instance that stopped mid order
+	SweepChallenges()
 }
 
+// defaultChallengeOrder is used if a source doesn't set its own, skipping any it can't solve.
+// http-01 is preferred as it needs no external system.
+var defaultChallengeOrder = []string{"http-01", "tls-alpn-01", "dns-01"}
+
 type acmeCertSource struct {
 	PrivateKey   string
 	URL          string
 	EmailContact string
+	Challenges   []string // in order of preference, defaults to defaultChallengeOrder
+
+	name string // as configured, for metrics
 
 	responderServer responder
+	dnsProvider     dnsProvider // optional, used for dns-01 challenges if set
+
+	acmeClient *acme.Client // safe for concurrent use, so orders for different hosts can run at the same time
+	dnsLocks   keyedMutex   // by dns record name, held from creating a record until it is removed
+	alpnLocks  keyedMutex   // by hostname, held from serving a tls-alpn-01 cert until it is removed
 
-	lock                sync.Mutex
-	acmeClient          *acme.Client
+	registerLock        sync.Mutex
 	acmeKnownRegistered bool
+	acmeAccountURL      string // fetched when first needed for requests the acme client can't make
+
+	dirLock sync.Mutex
+	dir     *acmeDirectory
 }
 
 func (acs *acmeCertSource) Init() error {
-	block, _ := pem.Decode([]byte(acs.PrivateKey))
-	if block == nil {
-		return errors.New("no private key found in pem")
-	}
-	if block.Type != "RSA PRIVATE KEY" || len(block.Headers) != 0 {
-		return errors.New("invalid private key found in pem for acme")
-	}
-
-	acmeKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	acmeKey, err := parsePrivateKey(acs.PrivateKey)
 	if err != nil {
 		return err
 	}
@@ -51,69 +67,157 @@ func (acs *acmeCertSource) Init() error {
 		DirectoryURL: acs.URL,
 	}
 
+	if len(acs.Challenges) == 0 {
+		for _, ct := range defaultChallengeOrder {
+			if acs.canSolve(ct) == nil {
+				acs.Challenges = append(acs.Challenges, ct)
+			}
+		}
+	} else {
+		for _, ct := range acs.Challenges {
+			err = acs.canSolve(ct)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// canSolve returns an error if we can't automatically satisfy challenges of this type
+func (acs *acmeCertSource) canSolve(chalType string) error {
+	switch chalType {
+	case "http-01":
+		return nil
+	case "tls-alpn-01":
+		if acs.responderServer == nil || !acs.responderServer.SupportsTLSALPN() {
+			return errors.New("tls-alpn-01 challenges need tls_alpn_port set on the acme responder")
+		}
+		return nil
+	case "dns-01":
+		if acs.dnsProvider == nil {
+			return errors.New("dns-01 challenges need a dns provider")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown challenge type: %s", chalType)
+	}
+}
+
+// pickChallenge returns the first challenge offered in our order of preference, or nil if none are
+func (acs *acmeCertSource) pickChallenge(z *acme.Authorization) *acme.Challenge {
+	for _, ct := range acs.Challenges {
+		// Wildcards can only be validated by dns-01
+		if z.Wildcard && ct != "dns-01" {
+			continue
+		}
+		chal := findChallenge(z, ct)
+		if chal != nil {
+			return chal
+		}
+	}
 	return nil
 }
 
 type acmeChallenge struct {
-	Message   string          `json:"message"`
-	Challenge *acme.Challenge `json:"challenge"`
-	Order     *acme.Order     `json:"order"`
+	Message    string            `json:"message"`
+	Challenge  *acme.Challenge   `json:"challenge"` // only set by older versions, see Challenges
+	Challenges []*acme.Challenge `json:"challenges"`
+	Order      *acme.Order       `json:"order"`
 }
 
-func newDNSChallenge(client *acme.Client, o *acme.Order, chal *acme.Challenge, hostname string) (*acmeChallenge, error) {
-	val, err := client.DNS01ChallengeRecord(chal.Token)
-	if err != nil {
-		return nil, err
+// pendingChallenges returns all challenges that must be accepted, including those
+// persisted by older versions that only supported a single challenge
+func (ac *acmeChallenge) pendingChallenges() []*acme.Challenge {
+	if len(ac.Challenges) == 0 && ac.Challenge != nil {
+		return []*acme.Challenge{ac.Challenge}
 	}
-	msg := fmt.Sprintf(`Create DNS TXT record:
-Name:  _acme-challenge.%s.
-Value: %s`, hostname, val)
-	return &acmeChallenge{
-		Message:   msg,
-		Challenge: chal,
-		Order:     o,
-	}, nil
+	return ac.Challenges
 }
 
 func (ac *acmeChallenge) Instructions() string {
 	return ac.Message
 }
 
-func (acs *acmeCertSource) ManualStartChallenge(ctx context.Context, hostname string) (*acmeChallenge, error) {
-	acs.lock.Lock()
-	defer acs.lock.Unlock()
+func findChallenge(z *acme.Authorization, chalType string) *acme.Challenge {
+	for _, c := range z.Challenges {
+		if c.Type == chalType {
+			return c
+		}
+	}
+	return nil
+}
+
+// observeStep records how long a step of an order took, and the type of error if it failed.
+// Intended to be deferred, hence errp.
+func (acs *acmeCertSource) observeStep(step string, start time.Time, errp *error) {
+	metricACMEStepDuration.WithLabelValues(acs.name, step).Observe(time.Since(start).Seconds())
+	if *errp != nil {
+		metricACMEErrors.WithLabelValues(acs.name, acmeProblemType(*errp)).Inc()
+	}
+}
 
+func (acs *acmeCertSource) ManualStartChallenge(ctx context.Context, hostnames []string) (_ *acmeChallenge, err error) {
 	acs.ensureRegistered(ctx)
-	o, err := acs.acmeClient.AuthorizeOrder(ctx, acme.DomainIDs(hostname))
+	defer acs.observeStep("authorize", time.Now(), &err)
+
+	o, err := acs.acmeClient.AuthorizeOrder(ctx, acme.DomainIDs(hostnames...))
 	if err != nil {
 		return nil, err
 	}
-	var chal *acme.Challenge
 	if o.Status == acme.StatusReady {
 		return nil, errors.New("already authorized, no challenge needed")
-	} else if o.Status == acme.StatusPending {
-		// Satisfy all pending authorizations.
-		for _, zurl := range o.AuthzURLs {
-			z, err := acs.acmeClient.GetAuthorization(ctx, zurl)
-			if err != nil {
-				return nil, err
-			}
+	} else if o.Status != acme.StatusPending {
+		return nil, fmt.Errorf("invalid new order status %q", o.Status)
+	}
 
-			for _, c := range z.Challenges {
-				if c.Type == "dns-01" {
-					chal = c
-					break
-				}
-			}
-			if chal == nil {
-				return nil, errors.New("no supported challenge type found")
-			}
+	rv := &acmeChallenge{
+		Order: o,
+	}
+	var records []string
+
+	// Satisfy all pending authorizations, some may already be valid from previous orders.
+	for _, zurl := range o.AuthzURLs {
+		z, err := acs.acmeClient.GetAuthorization(ctx, zurl)
+		if err != nil {
+			return nil, err
+		}
+		if z.Status != acme.StatusPending {
+			continue
+		}
+
+		chal := findChallenge(z, "dns-01")
+		if chal == nil {
+			return nil, errors.New("no supported challenge type found")
 		}
+
+		val, err := acs.acmeClient.DNS01ChallengeRecord(chal.Token)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, fmt.Sprintf(`Name:  _acme-challenge.%s.
+Value: %s`, z.Identifier.Value, val))
+		rv.Challenges = append(rv.Challenges, chal)
 	}
-	return newDNSChallenge(acs.acmeClient, o, chal, hostname)
+
+	if len(rv.Challenges) == 0 {
+		return nil, errors.New("no pending authorizations found, no challenge needed")
+	}
+
+	rv.Message = "Create DNS TXT record:\n" + strings.Join(records, "\n\n")
+	if len(records) > 1 {
+		rv.Message = "Create DNS TXT records:\n" + strings.Join(records, "\n\n")
+	}
+
+	return rv, nil
 }
 
 func (acs *acmeCertSource) ensureRegistered(ctx context.Context) {
+	acs.registerLock.Lock()
+	defer acs.registerLock.Unlock()
+
 	if acs.acmeKnownRegistered {
 		return
 	}
@@ -130,49 +234,151 @@ func (acs *acmeCertSource) ensureRegistered(ctx context.Context) {
 	acs.acmeKnownRegistered = true
 }
 
+func (acs *acmeCertSource) Revoke(ctx context.Context, der []byte, key crypto.Signer, reason int) error {
+	acs.ensureRegistered(ctx)
+	err := acs.acmeClient.RevokeCert(ctx, key, der, acme.CRLReasonCode(reason))
+	if err != nil {
+		metricACMEErrors.WithLabelValues(acs.name, acmeProblemType(err)).Inc()
+	}
+	return err
+}
+
+func (acs *acmeCertSource) SupportsRevoke() bool {
+	return true
+}
+
 func (acs *acmeCertSource) SupportsManual() bool {
 	return true
 }
 
-func (acs *acmeCertSource) CompleteChallenge(ctx context.Context, pkey *rsa.PrivateKey, hostname string, ac *acmeChallenge) ([][]byte, error) {
-	acs.lock.Lock()
-	defer acs.lock.Unlock()
+func (acs *acmeCertSource) SupportsCSR() bool {
+	return true
+}
 
-	acs.ensureRegistered(ctx)
+func (acs *acmeCertSource) SupportsKeyType(kt string) error {
+	if kt == keyTypeEd25519 {
+		return errors.New("ed25519 keys are not supported by acme sources")
+	}
+	return validateKeyType(kt)
+}
 
-	log.Println("accepting dns challenge...")
+func (acs *acmeCertSource) CompleteChallenge(ctx context.Context, pkey crypto.Signer, hostnames []string, ac *acmeChallenge) ([][]byte, error) {
+	acs.ensureRegistered(ctx)
 
-	c, err := acs.acmeClient.Accept(ctx, ac.Challenge)
-	if err != nil {
-		return nil, err
+	// The challenge URL is polled, as older versions didn't record the authorization URL
+	chals := ac.pendingChallenges()
+	var waitURLs []string
+	for _, chal := range chals {
+		waitURLs = append(waitURLs, chal.URI)
 	}
-	log.Println(c)
-	log.Println("waiting authorization...")
-	_, err = acs.acmeClient.WaitAuthorization(ctx, c.URI)
+	o, err := acs.acceptChallenges(ctx, chals, waitURLs, ac.Order.URI)
 	if err != nil {
 		return nil, err
 	}
+	log.Println(o)
+	return acs.issueCert(ctx, o, hostnames, pkey)
+}
+
+// acceptChallenges asks the CA to check each challenge, polling the matching URL in waitURLs until it is valid,
+// then waits for the order to be ready
+func (acs *acmeCertSource) acceptChallenges(ctx context.Context, chals []*acme.Challenge, waitURLs []string, orderURL string) (_ *acme.Order, err error) {
+	defer acs.observeStep("validate", time.Now(), &err)
+
+	for i, chal := range chals {
+		log.Printf("accepting %s challenge...\n", chal.Type)
+		reportStep(ctx, "accepting %s challenge", chal.Type)
+
+		_, err = acs.acmeClient.Accept(ctx, chal)
+		if err != nil {
+			return nil, err
+		}
+
+		log.Println("waiting authorization...")
+		reportStep(ctx, "waiting for authorization")
+		_, err = acs.acmeClient.WaitAuthorization(ctx, waitURLs[i])
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	// All authorizations are satisfied.
 	// Wait for the CA to update the order status.
 	log.Println("waiting order...")
-	o, err := acs.acmeClient.WaitOrder(ctx, ac.Order.URI)
+	reportStep(ctx, "waiting for order")
+	return acs.acmeClient.WaitOrder(ctx, orderURL)
+}
+
+func (acs *acmeCertSource) AutoFetchCert(ctx context.Context, pkey crypto.Signer, hostnames []string) ([][]byte, error) {
+	acs.ensureRegistered(ctx)
+
+	ao := &acmeOrder{
+		acs:        acs,
+		dnsRecords: make(map[string][]string),
+		alpnCerts:  make(map[string]tls.Certificate),
+	}
+	defer ao.cleanUp()
+
+	reportStep(ctx, "creating order")
+	err := ao.authorize(ctx, hostnames)
 	if err != nil {
 		return nil, err
 	}
-	log.Println(o)
-	return acs.issueCert(ctx, o, hostname, pkey)
+
+	if ao.order.Status == acme.StatusReady {
+		log.Println("order already validated!")
+	} else {
+		err = ao.present(ctx)
+		if err != nil {
+			return nil, err
+		}
+		err = ao.validate(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	log.Println(ao.order.FinalizeURL)
+	return acs.issueCert(ctx, ao.order, hostnames, pkey)
 }
 
-func (acs *acmeCertSource) AutoFetchCert(ctx context.Context, pkey *rsa.PrivateKey, hostname string) ([][]byte, error) {
-	acs.lock.Lock()
-	defer acs.lock.Unlock()
+// acmeOrder is the state of a single order, so that orders for different hosts can proceed at the same time
+type acmeOrder struct {
+	acs   *acmeCertSource
+	order *acme.Order
+
+	toAccept   []*acme.Challenge
+	toWait     []string            // authorization URLs, in the same order as toAccept
+	httpKeys   []string            // set in the responder, to clear when done
+	dnsRecords map[string][]string // record name to values
+	presented  []string            // dns records created, to remove when done
+	unlockDNS  func()
+
+	alpnCerts     map[string]tls.Certificate // hostname to tls-alpn-01 cert
+	alpnPresented []string                   // hostnames set in the responder, to clear when done
+	unlockALPN    func()
+}
 
-	acs.ensureRegistered(ctx)
-	o, err := acs.acmeClient.AuthorizeOrder(ctx, acme.DomainIDs(hostname))
-	if err != nil {
-		return nil, err
+// authorize creates the order, and picks a challenge for each pending authorization. Some may already be valid.
+func (ao *acmeOrder) authorize(ctx context.Context, hostnames []string) (err error) {
+	acs := ao.acs
+	defer acs.observeStep("authorize", time.Now(), &err)
+
+	var o *acme.Order
+	replaces := replacesFrom(ctx)
+	if replaces != "" {
+		o, err = acs.authorizeOrderReplacing(ctx, hostnames, replaces)
+		if err != nil {
+			// e.g. if the cert has already been replaced, or the CA doesn't support ARI
+			log.Printf("error creating order replacing %s, will try without: %s\n", replaces, err)
+		}
+	}
+	if o == nil {
+		o, err = acs.acmeClient.AuthorizeOrder(ctx, acme.DomainIDs(hostnames...))
+		if err != nil {
+			return err
+		}
 	}
+	ao.order = o
+
 	// Remove all hanging authorizations to reduce rate limit quotas
 	// after we're done.
 	//defer func() {
@@ -182,71 +388,174 @@ func (acs *acmeCertSource) AutoFetchCert(ctx context.Context, pkey *rsa.PrivateK
 	//if err == nil && z.Status == acme.StatusPending {
 	//	client.RevokeAuthorization(ctx, u)
 	//}
-	if o.Status == acme.StatusReady {
-		log.Println("order already validated!")
-	} else if o.Status == acme.StatusPending {
-		// Satisfy all pending authorizations.
-		for _, zurl := range o.AuthzURLs {
-			z, err := acs.acmeClient.GetAuthorization(ctx, zurl)
-			var chal *acme.Challenge
-			for _, c := range z.Challenges {
-				if c.Type == "http-01" {
-					chal = c
-					break
-				}
-			}
-			if chal == nil {
-				return nil, errors.New("no supported challenge type found")
-			}
+	switch o.Status {
+	case acme.StatusReady:
+		return nil
+	case acme.StatusPending:
+	default:
+		return fmt.Errorf("invalid new order status %q", o.Status)
+	}
 
+	reportStep(ctx, "fetching authorizations")
+	for _, zurl := range o.AuthzURLs {
+		z, err := acs.acmeClient.GetAuthorization(ctx, zurl)
+		if err != nil {
+			return err
+		}
+		if z.Status == acme.StatusValid {
+			continue
+		}
+
+		chal := acs.pickChallenge(z)
+		if chal == nil {
+			return fmt.Errorf("no supported challenge type found for %s, we can use: %s", z.Identifier.Value, strings.Join(acs.Challenges, ", "))
+		}
+
+		switch chal.Type {
+		case "http-01":
 			k := acs.acmeClient.HTTP01ChallengePath(chal.Token)
 			v, err := acs.acmeClient.HTTP01ChallengeResponse(chal.Token)
 			if err != nil {
-				return nil, err
+				return err
 			}
 
-			defer acs.responderServer.ClearChallengeValue(k)
-			acs.responderServer.SetChallengeValue(k, []byte(v))
-
-			log.Println("accepting http challenge...")
+			err = acs.responderServer.SetChallengeValue(k, []byte(v))
+			if err != nil {
+				return err
+			}
+			ao.httpKeys = append(ao.httpKeys, k)
+		case "tls-alpn-01":
+			cert, err := acs.acmeClient.TLSALPN01ChallengeCert(chal.Token, z.Identifier.Value)
+			if err != nil {
+				return err
+			}
 
-			_, err = acs.acmeClient.Accept(ctx, chal)
+			ao.alpnCerts[z.Identifier.Value] = cert
+		case "dns-01":
+			v, err := acs.acmeClient.DNS01ChallengeRecord(chal.Token)
 			if err != nil {
-				return nil, err
+				return err
 			}
 
-			log.Println("waiting authorization...")
-			_, err = acs.acmeClient.WaitAuthorization(ctx, z.URI)
+			// a wildcard and its base domain share the same record name
+			name := dns01RecordName(z.Identifier.Value)
+			ao.dnsRecords[name] = append(ao.dnsRecords[name], v)
+		}
+
+		ao.toAccept = append(ao.toAccept, chal)
+		ao.toWait = append(ao.toWait, z.URI)
+	}
+
+	return nil
+}
+
+// present serves any tls-alpn-01 certs, and creates any dns records needed. Other orders needing the same
+// hostnames or records wait until we are done with them.
+func (ao *acmeOrder) present(ctx context.Context) (err error) {
+	if len(ao.alpnCerts) == 0 && len(ao.dnsRecords) == 0 {
+		return nil
+	}
+	defer ao.acs.observeStep("present", time.Now(), &err)
+
+	if len(ao.alpnCerts) != 0 {
+		var hostnames []string
+		for hn := range ao.alpnCerts {
+			hostnames = append(hostnames, hn)
+		}
+		reportStep(ctx, "waiting for other orders using the same hostnames")
+		ao.unlockALPN = ao.acs.alpnLocks.Lock(hostnames...)
+
+		for _, hn := range hostnames {
+			err = ao.acs.responderServer.SetChallengeCert(hn, ao.alpnCerts[hn])
 			if err != nil {
-				return nil, err
+				return err
 			}
+			ao.alpnPresented = append(ao.alpnPresented, hn)
 		}
-		// All authorizations are satisfied.
-		// Wait for the CA to update the order status.
-		o, err = acs.acmeClient.WaitOrder(ctx, o.URI)
+	}
+
+	if len(ao.dnsRecords) == 0 {
+		return nil
+	}
+
+	var names []string
+	for name := range ao.dnsRecords {
+		names = append(names, name)
+	}
+	reportStep(ctx, "waiting for other orders using the same dns records")
+	ao.unlockDNS = ao.acs.dnsLocks.Lock(names...)
+
+	for _, name := range names {
+		log.Printf("creating dns record %s...\n", name)
+		reportStep(ctx, "creating dns record %s", name)
+		err = ao.acs.dnsProvider.Present(ctx, name, ao.dnsRecords[name])
 		if err != nil {
-			return nil, err
+			return err
 		}
-		log.Println(o.FinalizeURL)
-	} else {
-		return nil, fmt.Errorf("invalid new order status %q", o.Status)
+		ao.presented = append(ao.presented, name)
 	}
-	log.Println(o.FinalizeURL)
-	return acs.issueCert(ctx, o, hostname, pkey)
+
+	return nil
 }
 
-func (acs *acmeCertSource) issueCert(ctx context.Context, o *acme.Order, hostname string, pkey *rsa.PrivateKey) ([][]byte, error) {
-	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
-		Subject: pkix.Name{
-			CommonName: hostname,
-		},
-	}, pkey)
+// validate asks the CA to check each challenge, and waits for the order to be ready
+func (ao *acmeOrder) validate(ctx context.Context) error {
+	o, err := ao.acs.acceptChallenges(ctx, ao.toAccept, ao.toWait, ao.order.URI)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	ao.order = o
+	return nil
+}
+
+// cleanUp removes anything published for challenges, whether or not the order succeeded
+func (ao *acmeOrder) cleanUp() {
+	for _, k := range ao.httpKeys {
+		ao.acs.responderServer.ClearChallengeValue(k)
+	}
+	for _, hn := range ao.alpnPresented {
+		ao.acs.responderServer.ClearChallengeCert(hn)
+	}
+	if ao.unlockALPN != nil {
+		ao.unlockALPN()
+	}
+
+	// Use a fresh context, as we want to clean up even if we have timed out
+	for _, name := range ao.presented {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+		err := ao.acs.dnsProvider.CleanUp(ctx, name, ao.dnsRecords[name])
+		cancel()
+		if err != nil {
+			log.Printf("error removing dns record %s, ignoring: %s\n", name, err)
+		}
+	}
+
+	if ao.unlockDNS != nil {
+		ao.unlockDNS()
+	}
+}
+
+func (acs *acmeCertSource) issueCert(ctx context.Context, o *acme.Order, hostnames []string, pkey crypto.Signer) (der [][]byte, err error) {
+	defer acs.observeStep("finalize", time.Now(), &err)
+
+	var csr []byte
+	if cs, ok := pkey.(*csrSigner); ok {
+		csr = cs.csr.Raw
+	} else {
+		csr, err = x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+			Subject: pkix.Name{
+				CommonName: hostnames[0],
+			},
+			DNSNames: hostnames,
+		}, pkey)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	log.Println("creating cert...")
-	der, _, err := acs.acmeClient.CreateOrderCert(ctx, o.FinalizeURL, csr, true)
+	reportStep(ctx, "finalizing order")
+	der, _, err = acs.acmeClient.CreateOrderCert(ctx, o.FinalizeURL, csr, true)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,500 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// caKeysPath is where CAs generated for ca sources are kept in storage, by source name
+	caKeysPath = "/ca-keys"
+
+	// caRevocationsPath is where certs revoked by ca sources are kept in storage, by source name
+	caRevocationsPath = "/ca-revocations"
+
+	// CRLs are valid for crlValidity, and regenerated after crlCacheTime, or when a cert is revoked
+	crlValidity  = 7 * 24 * time.Hour
+	crlCacheTime = time.Hour
+
+	defaultCAValidityDays = 90
+	defaultCAKeyType      = keyTypeECDSAP256
+
+	// generatedCAValidity is how long a CA generated for a ca source is valid for
+	generatedCAValidity = 10 * 365 * 24 * time.Hour
+)
+
+// caConf configures a ca source, which signs certs for internal hostnames with its own CA
+type caConf struct {
+	// PEM CA cert and key. If not set, a CA is generated when first needed and kept in storage.
+	Certificate string `yaml:"certificate"`
+	PrivateKey  string `yaml:"private_key"`
+	KeyType     string `yaml:"key_type"` // for a generated CA, defaults to ecdsa-p256
+
+	ValidityDays int      `yaml:"validity_days"`  // for certs issued, defaults to defaultCAValidityDays
+	ExtKeyUsages []string `yaml:"ext_key_usages"` // "server_auth" and/or "client_auth", defaults to server_auth
+
+	// Name constraints, which are also included in a generated CA so that clients enforce them
+	PermittedDNSDomains []string `yaml:"permitted_dns_domains"` // if set, only names within these are signed
+	ExcludedDNSDomains  []string `yaml:"excluded_dns_domains"`  // names within these are never signed
+
+	// CRLURL, if set, is included in certs issued as where to fetch the CRL from,
+	// e.g. http://le-responder.example.com/crl/<source>.crl as served by the ACME responder
+	CRLURL string `yaml:"crl_url"`
+}
+
+// caDataLock is held while reading and updating CA data in storage, which is shared by all ca sources
+var caDataLock sync.Mutex
+
+// caRevocation is a cert revoked by a ca source, kept until it expires
+type caRevocation struct {
+	Serial    string    `json:"serial"` // hex
+	RevokedAt time.Time `json:"revoked_at"`
+	Reason    int       `json:"reason"`
+	NotAfter  time.Time `json:"not_after"`
+}
+
+// oidCRLReason is the CRL entry extension holding the reason code
+var oidCRLReason = asn1.ObjectIdentifier{2, 5, 29, 21}
+
+// caCertSource is implemented by sources that sign with their own CA, which clients must be told to trust
+type caCertSource interface {
+	CACertificate() (string, error)
+}
+
+// crlSource is implemented by sources that publish their own CRL
+type crlSource interface {
+	CRL() ([]byte, error)
+}
+
+// storedCA is a CA generated for a ca source
+type storedCA struct {
+	Certificate string `json:"certificate"`
+	PrivateKey  string `json:"private_key"`
+}
+
+var caExtKeyUsages = map[string]x509.ExtKeyUsage{
+	"server_auth": x509.ExtKeyUsageServerAuth,
+	"client_auth": x509.ExtKeyUsageClientAuth,
+}
+
+type caSource struct {
+	caConf
+
+	name    string // as configured, to find a generated CA in storage
+	storage certStorage
+
+	extKeyUsages []x509.ExtKeyUsage
+
+	caLock sync.Mutex
+	caCert *x509.Certificate // nil until loaded
+	caPEM  string
+	caKey  crypto.Signer
+
+	crlLock  sync.Mutex
+	crl      []byte // DER, cached until crlUntil
+	crlUntil time.Time
+}
+
+func (cas *caSource) Init() error {
+	if cas.ValidityDays == 0 {
+		cas.ValidityDays = defaultCAValidityDays
+	}
+	if cas.ValidityDays < 0 {
+		return errors.New("validity_days must not be negative")
+	}
+
+	if len(cas.ExtKeyUsages) == 0 {
+		cas.ExtKeyUsages = []string{"server_auth"}
+	}
+	for _, eku := range cas.ExtKeyUsages {
+		v, ok := caExtKeyUsages[eku]
+		if !ok {
+			return fmt.Errorf("unknown ext key usage: %s", eku)
+		}
+		cas.extKeyUsages = append(cas.extKeyUsages, v)
+	}
+
+	switch {
+	case cas.Certificate != "" && cas.PrivateKey != "":
+		// Check a configured CA now, so that mistakes are found at startup
+		return cas.setCA(&storedCA{
+			Certificate: cas.Certificate,
+			PrivateKey:  cas.PrivateKey,
+		})
+	case cas.Certificate != "" || cas.PrivateKey != "":
+		return errors.New("both certificate and private_key must be set, or neither to generate a CA")
+	default:
+		// A generated CA is loaded when first needed, as storage may not be up when we start
+		return nil
+	}
+}
+
+// loadCA loads or generates the CA the first time it's needed, so that only calls that
+// need it fail if storage is down
+func (cas *caSource) loadCA() error {
+	cas.caLock.Lock()
+	defer cas.caLock.Unlock()
+
+	if cas.caCert != nil {
+		return nil
+	}
+	stored, err := cas.loadOrGenerate()
+	if err != nil {
+		return err
+	}
+	return cas.setCA(stored)
+}
+
+// setCA parses and checks the CA, then uses it. Must be called with caLock held, or from Init.
+func (cas *caSource) setCA(stored *storedCA) error {
+	caCert, err := parseCertificatePEM(stored.Certificate)
+	if err != nil {
+		return err
+	}
+	if !caCert.IsCA {
+		return errors.New("certificate is not a CA")
+	}
+	caKey, err := parsePrivateKey(stored.PrivateKey)
+	if err != nil {
+		return err
+	}
+	if !publicKeysEqual(caCert.PublicKey, caKey.Public()) {
+		return errors.New("private key does not match the CA certificate")
+	}
+	cas.caCert, cas.caKey = caCert, caKey
+	cas.caPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caCert.Raw}))
+	return nil
+}
+
+// loadOrGenerate returns the CA kept in storage for this source, generating it if there isn't one yet
+func (cas *caSource) loadOrGenerate() (*storedCA, error) {
+	caDataLock.Lock()
+	defer caDataLock.Unlock()
+
+	all := make(map[string]*storedCA)
+	err := cas.storage.LoadData(caKeysPath, &all)
+	switch err {
+	case nil:
+		rv, ok := all[cas.name]
+		if ok {
+			return rv, nil
+		}
+	case errCertNotFound:
+	default:
+		return nil, err
+	}
+
+	kt := cas.KeyType
+	if kt == "" {
+		kt = defaultCAKeyType
+	}
+	key, err := generateKey(kt)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName: "le-responder " + cas.name + " CA",
+		},
+		NotBefore:             time.Now().Add(-5 * time.Minute),
+		NotAfter:              time.Now().Add(generatedCAValidity),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		MaxPathLenZero:        true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		PermittedDNSDomains:   cas.PermittedDNSDomains,
+		ExcludedDNSDomains:    cas.ExcludedDNSDomains,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	pk, err := marshalPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := &storedCA{
+		Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		PrivateKey:  pk,
+	}
+	all[cas.name] = rv
+	err = cas.storage.SaveData(caKeysPath, all)
+	if err != nil {
+		return nil, err
+	}
+	return rv, nil
+}
+
+// randomSerial returns a random 128-bit serial number, so that certs from the same CA never share one
+func randomSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// dnsNameWithin returns true if hostname is domain, or a name below it
+func dnsNameWithin(hostname, domain string) bool {
+	domain = strings.TrimPrefix(strings.ToLower(domain), ".")
+	return hostname == domain || strings.HasSuffix(hostname, "."+domain)
+}
+
+// checkNameConstraints returns an error if hostname is not permitted by the constraints
+func checkNameConstraints(hostname string, permitted, excluded []string) error {
+	for _, d := range excluded {
+		if dnsNameWithin(hostname, d) {
+			return fmt.Errorf("%s is excluded by this CA", hostname)
+		}
+	}
+	if len(permitted) == 0 {
+		return nil
+	}
+	for _, d := range permitted {
+		if dnsNameWithin(hostname, d) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is not permitted by this CA", hostname)
+}
+
+func (cas *caSource) AutoFetchCert(ctx context.Context, pkey crypto.Signer, hostnames []string) ([][]byte, error) {
+	err := cas.loadCA()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hn := range hostnames {
+		err = checkNameConstraints(hn, cas.PermittedDNSDomains, cas.ExcludedDNSDomains)
+		if err != nil {
+			return nil, err
+		}
+		// and those in the CA, which clients would otherwise reject
+		err = checkNameConstraints(hn, cas.caCert.PermittedDNSDomains, cas.caCert.ExcludedDNSDomains)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	reportStep(ctx, "signing")
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+
+	// Never valid for longer than the CA
+	notAfter := time.Now().Add(time.Duration(cas.ValidityDays) * 24 * time.Hour)
+	if notAfter.After(cas.caCert.NotAfter) {
+		notAfter = cas.caCert.NotAfter
+	}
+
+	keyUsage := x509.KeyUsageDigitalSignature
+	if _, ok := pkey.Public().(*rsa.PublicKey); ok {
+		keyUsage |= x509.KeyUsageKeyEncipherment
+	}
+
+	tmpl := &x509.Certificate{
+		DNSNames:     hostnames,
+		NotBefore:    time.Now().Add(-5 * time.Minute),
+		NotAfter:     notAfter,
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName: hostnames[0],
+		},
+		ExtKeyUsage:           cas.extKeyUsages,
+		KeyUsage:              keyUsage,
+		BasicConstraintsValid: true,
+	}
+	if cas.CRLURL != "" {
+		tmpl.CRLDistributionPoints = []string{cas.CRLURL}
+	}
+
+	// We only need the public key, so this works for a CSR too
+	cert, err := x509.CreateCertificate(rand.Reader, tmpl, cas.caCert, pkey.Public(), cas.caKey)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{cert, cas.caCert.Raw}, nil
+}
+
+func (cas *caSource) ManualStartChallenge(ctx context.Context, hostnames []string) (*acmeChallenge, error) {
+	return nil, errors.New("manual challenge not needed or supported for ca")
+}
+
+func (cas *caSource) CompleteChallenge(ctx context.Context, pkey crypto.Signer, hostnames []string, chal *acmeChallenge) ([][]byte, error) {
+	return nil, errors.New("manual challenge not needed or supported for ca")
+}
+
+func (cas *caSource) SupportsManual() bool {
+	return false
+}
+
+func (cas *caSource) SupportsCSR() bool {
+	return true
+}
+
+func (cas *caSource) SupportsKeyType(kt string) error {
+	return validateKeyType(kt)
+}
+
+// Revoke adds the cert to the CRL. The key isn't needed.
+func (cas *caSource) Revoke(ctx context.Context, der []byte, key crypto.Signer, reason int) error {
+	err := cas.loadCA()
+	if err != nil {
+		return err
+	}
+	pc, err := x509.ParseCertificate(der)
+	if err != nil {
+		return err
+	}
+	err = pc.CheckSignatureFrom(cas.caCert)
+	if err != nil {
+		return errors.New("cert was not issued by this CA")
+	}
+
+	caDataLock.Lock()
+	defer caDataLock.Unlock()
+
+	all, err := loadCARevocations(cas.storage)
+	if err != nil {
+		return err
+	}
+
+	// Forget those that have expired, as they don't need to be in the CRL
+	now := time.Now()
+	var revoked []*caRevocation
+	for _, r := range all[cas.name] {
+		if r.NotAfter.After(now) {
+			revoked = append(revoked, r)
+		}
+	}
+	all[cas.name] = append(revoked, &caRevocation{
+		Serial:    pc.SerialNumber.Text(16),
+		RevokedAt: now.UTC(),
+		Reason:    reason,
+		NotAfter:  pc.NotAfter,
+	})
+	err = cas.storage.SaveData(caRevocationsPath, all)
+	if err != nil {
+		return err
+	}
+
+	cas.crlLock.Lock()
+	cas.crl = nil
+	cas.crlLock.Unlock()
+
+	return nil
+}
+
+// IsRevoked returns true if pc is in our CRL
+func (cas *caSource) IsRevoked(pc *x509.Certificate) (bool, error) {
+	caDataLock.Lock()
+	all, err := loadCARevocations(cas.storage)
+	caDataLock.Unlock()
+	if err != nil {
+		return false, err
+	}
+	serial := pc.SerialNumber.Text(16)
+	for _, r := range all[cas.name] {
+		if r.Serial == serial {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func loadCARevocations(storage certStorage) (map[string][]*caRevocation, error) {
+	rv := make(map[string][]*caRevocation)
+	err := storage.LoadData(caRevocationsPath, &rv)
+	switch err {
+	case nil, errCertNotFound:
+		return rv, nil
+	default:
+		return nil, err
+	}
+}
+
+func (cas *caSource) SupportsRevoke() bool {
+	return true
+}
+
+// CRL returns the current DER CRL, signed by the CA
+func (cas *caSource) CRL() ([]byte, error) {
+	cas.crlLock.Lock()
+	defer cas.crlLock.Unlock()
+
+	now := time.Now()
+	if cas.crl != nil && now.Before(cas.crlUntil) {
+		return cas.crl, nil
+	}
+
+	err := cas.loadCA()
+	if err != nil {
+		return nil, err
+	}
+
+	// Read from storage each time, in case another instance has revoked certs
+	caDataLock.Lock()
+	all, err := loadCARevocations(cas.storage)
+	caDataLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var revoked []pkix.RevokedCertificate
+	for _, r := range all[cas.name] {
+		if r.NotAfter.Before(now) {
+			continue
+		}
+		serial, ok := new(big.Int).SetString(r.Serial, 16)
+		if !ok {
+			return nil, fmt.Errorf("bad serial in revocations: %s", r.Serial)
+		}
+		rc := pkix.RevokedCertificate{
+			SerialNumber:   serial,
+			RevocationTime: r.RevokedAt,
+		}
+		// RFC 5280 5.3.1 says to leave out the reason code rather than use unspecified
+		if r.Reason != 0 {
+			reason, err := asn1.Marshal(asn1.Enumerated(r.Reason))
+			if err != nil {
+				return nil, err
+			}
+			rc.Extensions = []pkix.Extension{{
+				Id:    oidCRLReason,
+				Value: reason,
+			}}
+		}
+		revoked = append(revoked, rc)
+	}
+
+	crl, err := cas.caCert.CreateCRL(rand.Reader, cas.caKey, revoked, now, now.Add(crlValidity))
+	if err != nil {
+		return nil, err
+	}
+	cas.crl, cas.crlUntil = crl, now.Add(crlCacheTime)
+	return crl, nil
+}
+
+// CACertificate returns the PEM CA cert, which clients need to trust
+func (cas *caSource) CACertificate() (string, error) {
+	err := cas.loadCA()
+	if err != nil {
+		return "", err
+	}
+	return cas.caPEM, nil
+}
@@ -2,44 +2,66 @@ package main
 
 import (
 	"context"
+	"crypto"
 	"crypto/rand"
-	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"errors"
-	"math/big"
 	"time"
 )
 
 type selfSignedSource struct{}
 
-func (sss *selfSignedSource) AutoFetchCert(ctx context.Context, pkey *rsa.PrivateKey, hostname string) ([][]byte, error) {
+func (sss *selfSignedSource) AutoFetchCert(ctx context.Context, pkey crypto.Signer, hostnames []string) ([][]byte, error) {
+	reportStep(ctx, "signing")
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
 	tmpl := &x509.Certificate{
-		DNSNames:     []string{hostname},
+		DNSNames:     hostnames,
 		NotBefore:    time.Now().Add(-5 * time.Minute),
 		NotAfter:     time.Now().Add(365 * 24 * time.Hour),
-		SerialNumber: big.NewInt(1),
+		SerialNumber: serial,
 		Subject: pkix.Name{
-			CommonName: hostname,
+			CommonName: hostnames[0],
 		},
 		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
 		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
 	}
-	cert, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &pkey.PublicKey, pkey)
+	cert, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, pkey.Public(), pkey)
 	if err != nil {
 		return nil, err
 	}
 	return [][]byte{cert}, nil
 }
 
-func (sss *selfSignedSource) ManualStartChallenge(ctx context.Context, hostname string) (*acmeChallenge, error) {
+func (sss *selfSignedSource) ManualStartChallenge(ctx context.Context, hostnames []string) (*acmeChallenge, error) {
 	return nil, errors.New("manual challenge not needed or supported for self-signed")
 }
 
-func (sss *selfSignedSource) CompleteChallenge(ctx context.Context, pkey *rsa.PrivateKey, hostname string, chal *acmeChallenge) ([][]byte, error) {
+func (sss *selfSignedSource) CompleteChallenge(ctx context.Context, pkey crypto.Signer, hostnames []string, chal *acmeChallenge) ([][]byte, error) {
 	return nil, errors.New("manual challenge not needed or supported for self-signed")
 }
 
 func (sss *selfSignedSource) SupportsManual() bool {
 	return false
 }
+
+// SupportsCSR is false, as a self-signed cert must be signed by its own private key
+func (sss *selfSignedSource) SupportsCSR() bool {
+	return false
+}
+
+func (sss *selfSignedSource) SupportsKeyType(kt string) error {
+	return validateKeyType(kt)
+}
+
+func (sss *selfSignedSource) Revoke(ctx context.Context, der []byte, key crypto.Signer, reason int) error {
+	return errors.New("self-signed certs cannot be revoked")
+}
+
+// SupportsRevoke is false, as there is no CA to publish a revocation
+func (sss *selfSignedSource) SupportsRevoke() bool {
+	return false
+}
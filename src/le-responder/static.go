@@ -1,8 +1,16 @@
 // Code generated by go-bindata.
 // sources:
 // data/add.html
+// data/audit.html
+// data/history.html
+// data/import.html
 // data/index.html
+// data/jobs.html
+// data/names.html
+// data/openapi.json
+// data/revoke.html
 // data/source.html
+// data/tokens.html
 // DO NOT EDIT!
 
 package main
@@ -70,7 +78,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var _dataAddHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x84\x52\xcd\x6a\x84\x30\x10\xbe\xf7\x29\x86\x79\x80\x0d\x74\x0f\x85\x12\xbd\x14\x4a\x6f\x2d\x6c\x5f\x20\x9b\x8c\x26\xa0\x89\x98\x49\xdb\xad\xf8\xee\x45\xb3\xff\xeb\x52\x4f\x33\xfa\xfd\x8c\x1f\x9f\xb4\xdc\x36\xe5\x03\x00\x80\xb4\xa4\x4c\x1e\xe7\x95\x1d\x37\x54\xbe\xf4\xa4\x98\xc0\xd3\x37\x68\xea\xd9\x55\x4e\x2b\x26\x29\xf2\xd7\x4c\x14\x27\xa6\xdc\x06\xb3\x3b\x13\xb1\xeb\xbb\x0a\x76\x7d\x86\xab\x42\xdf\x42\x4b\x6c\x83\x29\xf0\xe3\x7d\xf3\x89\xa0\x34\xbb\xe0\x0b\x14\xa9\x33\x8a\x09\x4f\xe8\x99\xe1\x7c\x97\x18\x78\xd7\x51\x81\xd6\x19\x43\x1e\xc1\xab\x96\x0a\xcc\x44\x84\x2f\xd5\x24\x2a\x50\xcf\xfe\x08\xe2\x4a\xa0\x2b\xdf\x42\x64\xe0\x00\x5b\xaa\x9d\x87\x56\x79\x55\x3b\x5f\x3f\x4b\xd1\xdd\x40\x2f\xec\x98\x7e\xf8\x60\x66\x43\xe4\xa3\x15\x42\x74\xbf\x54\xe0\xd3\x23\x82\x4a\x1c\xaa\xa0\x53\x2c\xf0\x38\x4e\x47\x2c\xa9\x6f\x42\xea\x35\x2d\x1a\x5f\xec\xf3\xbb\x48\x0d\x69\xde\xdb\xc7\x99\x89\xb7\xb0\xe9\x19\x06\xe8\x95\xaf\x09\x56\x19\x16\x61\x1c\x17\x81\xb3\x6e\xe8\xa6\xd8\xca\x61\x80\x15\x8c\xa3\x14\xfb\xfd\x9e\x32\x79\xb3\x24\x27\x45\xbe\xef\xea\x47\xfe\x8b\x34\xa6\x6d\xeb\x4e\x41\x6e\xf6\xeb\x42\x5c\xd3\x7d\x3a\xf6\xd5\xab\xa3\xe6\xe2\x02\x29\xa6\x12\x1d\x2a\x99\x7b\x28\x45\xee\xf7\x5f\x00\x00\x00\xff\xff\x9f\x64\x44\xd5\xe7\x02\x00\x00")
+var _dataAddHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xe5\x56\x6d\x6b\xdb\x30\x10\xfe\xbe\x5f\x71\xf8\x53\x02\xa5\xde\x0b\x63\x30\x9c\x40\x28\x1b\x1b\x63\x6b\x68\xfa\x3d\x28\xd2\x39\x16\x95\x25\xa3\x97\x64\x59\xe9\x7f\xdf\xc9\xb2\x9b\xa6\x75\xc8\x56\x18\x14\xe6\x2f\xd6\x25\x77\xcf\x3d\x77\xba\x3c\x97\xa2\xf2\xb5\x9a\xbe\x02\x7a\x8a\x0a\x99\x48\xc7\xd6\xf4\xd2\x2b\x9c\x5e\x58\x64\x1e\x41\xe3\x16\x38\x5a\x2f\x4b\xc9\xc9\x2e\xf2\xf4\x6d\x0a\xcc\xf7\x91\xc5\xca\x88\xdd\x03\x90\xea\xdd\x51\x04\xfa\x6a\xef\x57\x1a\x5b\x43\x8d\xbe\x32\x62\x92\xcd\x2f\x17\xd7\x19\x30\xee\xa5\xd1\x93\x2c\x0f\x8d\xa0\x80\x6c\xef\xdd\x46\x48\xdd\x04\x0f\x7e\xd7\xe0\x24\xab\xa4\x10\xa8\x33\xd0\xac\x26\x2b\x05\x66\xb0\x61\x2a\x90\xc9\xdb\xfc\x19\xe4\x8f\x00\x9a\xe9\x17\xe3\x08\xc1\xc0\x0a\xd7\x52\x43\xcd\x34\xa3\xf7\xfa\x63\x91\x37\x4f\x5c\x0f\xd2\x79\xfc\xe9\xfb\x64\x15\x61\xdc\xa7\xca\xc0\xc9\x5f\xf4\xfe\xf0\x96\xe8\x07\x6f\x4a\xc3\x83\x23\x42\xfd\x31\x92\x18\x42\x9f\x09\x21\x23\x67\xa6\x20\xe2\x45\x64\x07\xa3\xc5\xec\x87\x1b\x47\x7e\x52\x73\x15\x04\x9e\x81\xd1\x08\x0d\x5a\x50\x92\x0e\x23\xd3\xa4\x98\xf1\x30\xe3\xc8\x92\x51\xed\x1d\x51\xc7\x34\xe5\xb7\x66\x4b\x84\xde\x67\xc0\x8d\x72\x2d\x51\x22\xd4\x7b\x0e\x72\x5b\x98\x60\x39\x0e\xa6\x38\xb0\xdb\xcf\x1c\x2a\xe4\xbe\xcf\xd8\x46\x66\x4f\xdd\xe2\x73\x7b\x0b\x96\xe9\x35\xc2\x79\x72\x73\x70\x77\x37\xe8\xd8\xe2\xa6\x52\xa7\x14\x74\x4e\x7e\x45\xde\xd9\xc7\x90\x51\x8b\x21\xb8\x22\x4f\xfc\x1e\x15\x32\x50\xd9\x37\xdc\xb5\x77\xfd\x9c\xb2\x6f\x70\xb7\x8c\xb1\x47\x0a\xef\x6a\xb9\x1f\x99\xe9\x28\x35\x00\x04\x96\x2c\x28\x3f\x3e\x59\x5d\xd7\x37\xca\x73\x4d\x69\x5e\x56\xe3\x66\xca\xd1\xbc\x3a\x17\x10\x18\x38\xe4\x86\x00\xe3\xaf\x1e\xb6\xd2\x57\x70\xd3\xb5\xf5\xd4\xec\x9e\x1a\xac\x16\x97\xd9\xdd\xf2\xbf\xee\xf5\xa5\x3d\x03\x59\x82\xaf\x48\x14\xac\xdc\x44\x91\x8d\x0d\xae\x03\xa9\xda\x2a\x9e\x1b\x0f\xa8\x1c\x6e\x2b\xb4\x24\x1e\x0c\x2e\x16\x57\x30\x9a\x7f\xfa\x9e\x44\xa5\xbd\xa4\x78\x37\x0e\x48\x7d\xcf\xe1\x2b\x1d\x92\xf2\x30\x62\x16\x91\x48\x13\x20\x38\x14\x24\x40\xce\x93\xc4\x83\x89\xd9\x8c\xa3\xbb\x5d\x99\x0d\xfe\x91\xec\x70\x67\x7b\xd5\x79\xf3\xfa\x2f\x64\xe7\x0a\x69\x61\x90\x1e\x36\x46\x49\xbe\x7b\xce\x94\xd8\x84\xb0\x4c\x08\xff\x7a\x42\xba\x6c\xf3\x98\x4c\xbe\xb0\x41\x39\x58\x5e\x2e\xac\x6a\xb9\x5f\x59\x8b\xce\x1c\x58\x4c\x91\x1f\x5d\x5f\xf9\x59\xa2\x3a\x60\x50\xe4\x71\x5d\xf7\xcb\x3f\x6d\x7c\xda\xe7\xed\x3f\x89\xdf\x50\xa7\xce\x8e\x51\x08\x00\x00")
 
 func dataAddHtmlBytes() ([]byte, error) {
 	return bindataRead(
@@ -85,12 +93,72 @@ func dataAddHtml() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "data/add.html", size: 743, mode: os.FileMode(420), modTime: time.Unix(1541473444, 0)}
+	info := bindataFileInfo{name: "data/add.html", size: 2129, mode: os.FileMode(420), modTime: time.Unix(1792104001, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
 
-var _dataIndexHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xb4\x56\x49\x8f\xdb\x36\x14\xbe\xf7\x57\xbc\xb2\x07\xdb\x80\x61\x75\x3a\x87\x04\x1e\x49\xc5\xd4\x49\x90\x01\xba\x04\x51\x73\x2a\x7a\xa0\xc5\x27\x89\x0d\x45\x0a\xd4\x53\xa6\x86\xab\xff\x5e\x50\xd4\x78\x95\x5c\x77\x06\xd1\x45\xcb\x5b\xbf\xef\x2d\x62\x58\x50\xa9\xe2\x6f\x00\x00\xc2\x02\xb9\xf0\x8f\xdd\x2b\x49\x52\x18\xaf\xd0\x12\x90\x31\x2a\x0c\xfc\x87\xbd\x42\x9d\x5a\x59\x11\xd0\xa6\xc2\x88\x11\xfe\x4d\xc1\x5f\xfc\x0b\xf7\x5f\xd9\x5e\xcf\x5d\x59\xa3\x53\x92\x46\x83\x30\x0f\xf4\x69\xca\x53\x9a\x43\xc5\xa9\x98\xc1\xf6\x48\xcf\x5d\xc2\xa4\x4d\x89\x9a\x16\x39\xd2\x5b\x85\xee\xf1\xa7\xcd\x83\x98\x32\x67\xc0\x66\x8b\x2f\x5c\x35\x08\x51\x67\x7f\x77\xbd\x35\xef\x12\x38\xb0\xe7\x29\xfd\x0f\xf3\x8c\xcd\x16\x75\xb3\x2e\x25\x4d\x67\xe7\x66\x16\xa9\xb1\x1a\x32\xae\x6a\x3c\x96\xb6\xe3\x3c\x24\xcf\xe4\x21\x79\x19\x11\xc9\x0b\x99\x48\x5e\x4e\x45\x18\xf8\x2e\xe9\x1b\x2f\xd8\x77\x5e\xb8\x36\x62\x73\xd0\x63\x55\xfc\x5e\xc2\x76\x0b\x8b\xa6\x46\xbb\x78\x5b\x72\xa9\xee\x85\xb0\x58\xd7\xd0\xb6\x73\xd8\x98\x06\xb8\x45\x50\x26\xcf\x51\x80\xd4\xdf\xc2\x1f\x10\x72\x28\x2c\x66\x11\x0b\x94\xc9\x4d\x43\x2c\xfe\xb9\xbb\xc3\x94\x0a\x59\xcf\xc2\x80\xc7\xf0\xcf\x99\xd6\x8f\x69\x16\xdd\xec\x54\x57\xef\x3a\xb5\x3f\xc3\xa0\xda\x67\xb3\xdd\x82\xe5\x3a\x47\x58\x94\x58\xd7\x3c\x47\x97\xc4\x11\xc4\xb0\x82\x9a\x36\x0a\x23\x56\x71\x21\xa4\xce\x97\x37\x58\xde\xc1\xda\x58\x81\xb6\x7f\xe6\xe9\xe7\xdc\x9a\x46\x8b\x25\xd8\x7c\x3d\xfd\xe1\xf6\xf5\x1c\x6e\x5e\xdf\xce\xe1\xe6\xd5\xab\xd9\x1d\x8b\x1d\x5a\x68\xdb\xd3\xc8\xa8\xc5\x61\xb4\x30\x33\xb6\x04\x29\x22\x96\x31\x28\x91\x0a\x23\x22\xf6\xe1\xb7\xe4\x77\x06\xbe\xbe\x11\x0b\x9a\x4a\x70\xc2\x93\x51\x0c\xa5\xae\x1a\xea\x2c\xbb\x3e\xea\xe7\xb7\x90\x42\xa0\x66\xa0\x79\x89\x4f\x92\x60\xd4\xb2\x6f\xa1\x41\xdb\x27\xd9\x89\xb5\x83\x95\xd6\x36\x7b\x27\x51\x1d\x23\x09\x1c\x94\x78\x00\x59\x32\x0e\xad\x36\x8d\x4d\x2f\x40\x4b\x9e\x8f\x2d\xf9\xaa\xe0\xaa\x6e\xa3\xca\x4c\xa6\x9c\xb0\x86\x92\x6b\x9e\xa3\x58\x1e\x55\x3b\x24\xbe\x56\xd8\x77\x4d\xc4\xfc\xfd\x14\x2b\xd9\xf8\x6c\xf6\x42\x2a\xe2\x5f\x79\x89\x61\x40\xc5\xb0\xf4\x0d\xdf\xd4\xf0\x11\x4b\x2e\xb5\xd4\xf9\xb8\x5e\xd2\xf1\x3b\x2e\x5f\x15\x5c\x29\xd4\xf9\x05\x95\xfb\x8e\xaa\xfa\x5c\x21\x0c\x4e\x73\xdf\x0f\x56\x8a\x96\xce\xa6\x6a\x14\xaf\x17\x88\x78\x37\xcb\x05\x51\x55\x2f\x83\xc0\xd5\xc3\x11\x01\x6d\xeb\x07\xaa\x7f\x71\x43\x1d\x06\x24\x46\x5d\xb9\x54\x64\x06\x8a\x60\xe1\xa8\xda\x31\x05\xb7\xdf\x43\xdb\x3e\x0d\x77\x6a\x94\xb1\x4b\x8b\x82\xed\x27\xb3\x0b\x73\x6c\xe3\xe2\x5d\x88\xd5\x59\xac\x2c\x8a\xf7\xcd\xda\x35\xc5\xc2\x93\xee\xe2\x1c\x2c\xb1\xef\x18\x18\x9d\x2a\x99\x7e\x8e\x58\xbf\x5b\xfd\xcf\x63\xe2\x67\x60\x32\x9f\x38\x3f\x1f\x38\x15\xd0\xb6\x13\xb7\x41\x56\x05\xef\x2a\xe3\x17\xd8\xa5\x0c\x06\x05\x7d\x45\x64\x76\x9c\xdd\xae\xe4\x43\xe5\x39\x72\x5c\x59\x3c\xc3\xb6\xb3\x5e\x3c\xe8\x9a\x6c\xe3\x9b\xc3\xef\x39\x8b\xe3\x89\xb8\xeb\xbf\xd9\xf8\x34\x9d\xa4\xa6\xac\x14\xd2\x20\x1f\xbd\xc8\x33\x72\x09\xf3\xc9\x92\x3d\x42\xf5\x22\x22\x93\xc2\x3c\xbe\x41\x97\x04\xb4\xed\x55\x78\x04\x8e\xa0\xf1\x6e\x3c\x96\xcb\x29\x9f\xc4\xff\x88\x1a\x1f\xaf\x0d\xcf\x1b\x32\x03\xc1\x3b\x1f\xcf\x88\xfd\x0b\xd7\x0d\x57\xd7\x06\x2f\x3b\xed\x81\xf0\xde\xcd\x35\xf1\x87\xcb\x35\xb8\x7a\x4e\xff\xac\x41\xb7\x7e\xf7\x6a\x87\x27\x0a\x2e\x04\x8b\xef\x85\xf0\x29\x00\x40\x7f\x82\xf1\xc7\x96\x30\xf0\xc7\xe9\x7f\x03\x00\x00\xff\xff\xf4\x0b\x8d\xbe\x56\x0b\x00\x00")
+var _dataAuditHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xa5\x55\xdf\x6f\xd3\x30\x10\x7e\xe7\xaf\x38\x59\x88\xb7\x35\xd9\xc6\x10\xaa\x9c\xa0\x22\x5a\x10\x12\xdb\x43\xe1\x09\xf1\xe0\xd6\x97\xc5\xc2\x89\x33\xdb\x41\x2b\x53\xff\xf7\xf9\x92\x96\xa6\x25\xc9\x86\xc8\x4b\xe2\xfb\xf1\xf9\xcb\xdd\x77\x36\xcf\x7d\xa1\xd3\x17\x10\x1e\x9e\xa3\x90\xed\x67\xb3\xf4\xca\x6b\x4c\x67\xb5\x54\x1e\xb4\xb9\xe5\x51\x6b\x68\x63\xa3\x43\x30\x5f\x19\xb9\xe9\xe4\xe5\x97\xdd\xa4\xb0\x3a\xb8\x32\x63\x0b\x28\xd0\xe7\x46\x26\xec\xe3\xfc\x2b\x03\xb1\xf6\xca\x94\x09\x8b\x04\xa5\xb0\x43\x2c\x3d\x9f\x8c\xf3\x53\xe0\xaa\xac\x6a\x0f\x7e\x53\x61\xc2\x3c\xde\x7b\x06\xa5\x28\xc2\x77\x1e\xdc\x0c\x7e\x09\x5d\x87\xc5\xc3\x03\x4c\xc8\x00\xdb\x2d\x83\xe8\x18\xe7\x9b\x43\x3b\x82\x53\x07\xf7\x11\x4e\x20\x65\x6c\x1f\xd0\x07\xb1\x71\x23\x40\x32\xb8\x8f\x80\xc8\xd0\xe0\x38\xf5\x3b\x98\x2e\xff\x02\x3c\x82\x72\xf5\xaa\x50\x87\x3f\x5a\x28\xed\x89\x58\x27\x85\x47\x54\xc1\xce\xba\x4a\xbf\x03\x17\x90\x5b\xcc\xf6\x35\x7c\x47\x1b\xdf\xd5\x68\x37\xb4\x73\x3a\xbf\xaf\x8c\xf5\x20\x1c\x7c\x5e\xde\x5c\x83\x56\x25\x3a\x1e\x89\x14\x7e\xf0\xa8\xea\x76\x5b\xac\x34\xc2\xca\x58\x89\x36\x61\xed\xfb\xa4\x1d\xdc\xdb\x63\x43\x6b\xcc\xd3\xa5\x17\xd6\xa3\x0c\x0a\xc9\xfb\x03\x16\xaa\x54\x2e\x1f\x8b\xa0\x16\x0d\x7b\x67\x8d\x4a\x86\xfd\x24\x94\x61\xef\xd2\xd4\x76\x8d\xc3\xfe\x9b\xda\xaf\x4d\x31\x12\x30\xb7\xd6\xf4\xb0\x0b\x96\x93\x82\x84\xd2\x5b\x51\xde\x22\x4c\xb0\xf4\x56\x21\x35\xbf\x07\xb1\xa7\x8c\xad\x43\xa6\xd4\xbc\x5d\x39\x27\x8b\xd0\x6b\xe1\x81\x5d\xc4\xf1\x9b\xb3\xf8\xfc\x2c\xbe\x80\xf3\xab\x69\xfc\x7a\x1a\x5f\xc1\x97\x65\x18\x9e\xed\x36\x50\x90\xe3\x60\xfb\xd2\xff\x3f\xda\xa9\xce\x68\x6c\x12\xda\x62\xb6\x1b\x97\x57\x24\x77\xb2\xbc\xfc\x23\xfc\xb4\xeb\x27\xd9\x3d\x4d\xb8\xed\xf5\x3f\xb3\xa1\xd9\x6f\xd8\x90\x16\x68\x1c\x47\x08\x75\x42\x9e\xc7\xa9\x55\xd0\x53\x9c\xa8\xfd\x2a\x03\xbc\x83\xc9\x4e\x52\xc0\x32\xa1\x74\x6d\x91\xaa\x0b\xce\x6f\x74\x98\xeb\xb5\xd1\xc6\x4e\x2d\x4a\x46\x09\x58\xca\xe0\x6b\x76\xd9\x27\x3d\xa7\xad\x8d\x24\x07\x23\x7b\x95\x89\xda\xe1\x90\x1e\x89\x7d\xe0\xe5\x2a\x11\xce\xe2\xb7\x2c\xbd\x36\xb0\x57\x70\x66\xea\x52\x4e\x9a\x7d\xfa\x61\x9b\x1f\xe8\x1c\x51\xcd\x49\x32\x78\x46\xb1\xf4\xbd\x58\xff\x3c\x39\x81\x78\xd4\xde\x21\xe1\xba\x68\xae\xa3\x47\xac\x04\x1f\xdf\x96\x06\x00\x00")
+
+func dataAuditHtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_dataAuditHtml,
+		"data/audit.html",
+	)
+}
+
+func dataAuditHtml() (*asset, error) {
+	bytes, err := dataAuditHtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "data/audit.html", size: 1686, mode: os.FileMode(420), modTime: time.Unix(1792102961, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dataHistoryHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xad\x56\x51\x4f\xdb\x30\x10\x7e\xdf\xaf\xb8\x79\x93\x68\x1f\x48\xca\x18\x3c\x40\x9a\x09\xa6\x21\xa1\x09\x36\x01\xda\xcb\xb4\x07\x37\xbe\x34\x1e\x89\x1d\x39\x4e\x47\x85\xf8\xef\x3b\xc7\x2d\xcd\xda\x34\x1d\xda\xfc\xd2\xd8\x77\xf7\xdd\xf9\xbe\xf3\x5d\xa3\xcc\x16\x79\xfc\x0a\x68\x45\x19\x72\xe1\x3f\x9b\xad\x95\x36\xc7\xf8\x23\x1a\x2b\x53\x99\x70\x8b\x90\xc9\xca\x6a\x33\x8f\x42\x2f\x5a\xa9\x56\x89\x91\xa5\x05\x3b\x2f\x71\xcc\x2c\x3e\xd8\xf0\x27\x9f\x71\x7f\xca\x56\x7a\x6e\xa5\xb5\x4a\xac\xd4\x0a\x84\xbe\xb4\x37\x83\x19\x9a\x8a\x76\x43\x78\xfc\x43\xcb\x2d\x99\xc2\xe0\x75\xa2\x55\x2a\x4d\x31\x60\x37\xe8\x7c\x23\x58\x0a\x02\x16\x56\x1f\xe0\xd2\xc2\x2f\x99\xe7\x30\x41\xa8\x32\x59\x96\x28\xc0\x6a\xd2\x41\x28\x8d\x7e\x90\x58\x05\x6c\xd8\x85\xed\x96\x41\x5b\x1b\x05\x29\xcf\x2b\x3c\xdd\xd0\x78\xda\x38\x11\x3a\xa9\x0b\x54\x36\x98\xa2\xfd\x94\xa3\xfb\x3c\x9f\x5f\x0a\x0a\x6d\x11\x0e\x1b\x06\x33\x9e\xd7\x08\xe3\x65\x80\xa7\x2f\x00\x49\xc9\xbc\xaa\x27\x85\xb4\x83\xe1\xa6\xdd\xf6\x60\x57\x81\x46\xa1\xcf\xf8\x82\xce\x70\xc5\x67\x34\xd1\x62\xde\xe2\x2b\x3b\xec\xe2\x15\x52\x6d\xe0\xf1\x11\x82\x4c\x57\x16\x9e\x9e\x08\xe1\xb0\x65\x44\xd2\x02\xa4\x18\xbb\x50\xa1\x40\x9b\x69\xfa\xfe\xfa\xe5\xf6\x8e\x01\x6f\x28\x1d\xb3\xb0\x2e\x05\x01\xae\x51\x1e\x49\x55\xd6\xcb\xea\xc8\xa4\x10\xa8\x18\x28\x5e\xd0\xce\x1b\x32\x68\xf2\x36\x66\xc6\x93\xcc\x20\xfc\x6b\x84\x92\xdb\xec\xd9\xde\x05\xef\x0e\x28\xf8\x6d\x18\xcd\x05\x96\x84\x75\x22\x3e\x0b\xd7\x00\x1c\x78\x52\x99\xf4\x42\x62\x2e\xc8\x43\x2b\xef\x2e\x35\xed\x97\xc3\x27\x39\xc2\x44\x1b\x81\x66\xcc\xfc\xef\x7a\x4e\xac\x89\x37\x48\x8e\x6c\x16\xdf\xf2\x19\x0a\x7a\x61\xd9\x16\xb1\xae\x4d\x82\x3d\x72\x34\x92\xe7\xdb\xe5\x97\x55\x55\xa3\xd9\x2e\xbf\xd6\x16\xce\x91\xee\x83\xfd\x3a\x67\xa9\xed\x83\xf9\x8c\x73\xb8\xa3\xdc\x6e\xd7\x38\x6b\x98\xaf\x36\x15\xe8\xc4\x6c\x24\xde\x70\x35\x45\x08\x16\xd4\x54\xed\xec\xf7\x66\xd4\x0b\x44\xec\xb8\x6b\x32\x1b\x5c\x10\x55\xdc\x02\x7b\x37\x1a\x1d\xef\x8f\x0e\xf6\x47\xef\xe0\xe0\xe8\x64\xf4\xfe\x64\x74\x04\x57\xae\x96\x5d\xdd\x5b\xb1\x03\xaa\x61\xa1\x57\x95\xd4\xa8\x81\x05\xee\x99\x75\x45\xbb\x8e\xe8\xf4\x02\x4f\xde\x75\x5d\x4c\xd0\x04\x77\xd4\x46\xe1\xe0\xb8\xd7\xc9\x06\x82\xa7\x37\xb8\xb5\x46\xaa\xe9\xcb\x4c\x89\x55\x4f\xfc\xbf\xa5\xa8\x0b\xb7\x29\x96\xff\x06\xbb\xa8\x05\x2a\x31\x57\x61\xae\x16\x9c\x2f\x67\x3d\x31\xf4\x66\x69\x83\x4a\xec\xe2\x06\xa9\x8f\xee\xe0\x05\x12\x9d\x57\x25\xa7\xb6\x76\xc4\xe2\x81\xd2\x90\xb4\x5a\xa6\x74\x89\x16\xc3\x7e\x1f\x4a\x6c\x73\x11\xf5\xdd\x73\x59\x3a\xb5\x31\x34\x1e\xfa\xa2\x74\x6b\xa1\xd6\x87\xd6\x5c\x96\x20\x39\x05\xe4\x2b\xf2\x6d\x90\x70\x75\x26\x0a\xa9\xfc\xe7\x15\x57\x7c\x8a\xbb\x5c\x7d\x87\x88\x43\x66\x30\x1d\xb3\x37\x0c\xb4\x4a\x72\x99\xdc\xbb\xae\xdd\xcc\x26\x3f\xd0\xf7\x1c\x19\xdf\xfc\x3b\x25\xbc\xbd\xe1\x29\x8b\xbb\x66\x77\x14\xf2\x18\x7e\xf4\x46\xdd\x93\xbe\xce\xb4\x77\x76\x8e\x35\x14\xd2\x71\xcd\xb9\xd5\xac\xcb\xb8\x75\xad\x90\xc5\xe7\x3c\xb9\xf7\xc1\x45\x61\xb9\x1c\xa5\x7e\x7e\xd2\x40\x6c\xfe\x2d\xfd\x06\xe9\x8c\x5e\xdd\x35\x09\x00\x00")
+
+func dataHistoryHtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_dataHistoryHtml,
+		"data/history.html",
+	)
+}
+
+func dataHistoryHtml() (*asset, error) {
+	bytes, err := dataHistoryHtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "data/history.html", size: 2357, mode: os.FileMode(420), modTime: time.Unix(1792102840, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dataImportHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x9d\x56\x4b\x6f\xdb\x30\x0c\xbe\xef\x57\x10\x3a\xb5\x40\x97\xac\x0f\xa0\x45\xea\x18\xd8\x8a\x0e\xdb\x61\x58\x81\xf6\x36\x0c\x83\x62\xd1\x91\x50\xdb\xf2\x24\x39\x6d\x56\xec\xbf\x8f\x92\x9c\xd4\x6e\x9d\x34\x4b\x0e\xb1\x4d\x91\xfc\xf8\xf8\x48\x3b\x91\xae\x2c\xd2\x77\x40\xbf\x44\x22\x17\xf1\x36\x3c\x3a\xe5\x0a\x4c\xbf\x96\xb5\x36\x0e\x32\x34\x4e\xe5\x2a\xe3\x0e\x6d\x32\x8e\x47\xd1\x6a\xfc\x6c\x96\xcc\xb4\x58\x76\x3c\xc8\xd3\x61\x73\x92\xaf\x95\x9e\x9e\xc0\xf0\x6a\x8e\x30\x2a\xd1\x5a\x3e\x47\x0b\x7f\xff\xae\x4f\x83\x9b\x1a\xac\x5b\x16\x38\x65\x35\x17\x42\x55\xf3\xc9\x31\x96\x97\x30\xd3\x46\xa0\x69\xef\x79\x76\x3f\x37\xba\xa9\xc4\x04\xcc\x7c\x76\x70\x72\x7a\x71\x04\xc7\x17\xa7\xf4\x77\x7e\x7e\x78\xc9\x52\x42\x19\x91\xdf\x64\x5c\xf7\x90\xb1\x12\x5d\x34\x92\xa8\x1c\x46\x06\x6d\x53\xb8\xd7\x71\x38\x3e\x2b\xb0\xc5\x9d\xb2\x78\x65\x69\x4f\x27\xea\x99\xd7\xc2\x78\x20\xd3\x2f\xda\x3a\xaa\x9f\xdc\xac\x71\xeb\xb8\x6b\xec\x76\x9d\x6b\x63\xb4\x19\x56\x21\xe9\x00\xfc\x73\x95\x37\x24\xf7\x66\xf0\xf1\x50\x84\x4a\xfa\x24\x2a\x5e\x62\xa8\xa8\x13\x5b\x0d\xda\xa2\xe2\x6f\x18\xc5\xcc\x80\xe5\x5c\x15\x28\x18\x59\xaf\x1a\x9b\xe9\x42\x9b\x89\xf1\xc2\x75\x57\x02\x52\x6b\xb2\x03\x4e\x50\x0f\x75\xd9\xaa\xbd\xb1\x3c\x2f\xa8\xd0\xea\xfa\x8e\x6f\x63\x4c\x52\xb7\x0c\x47\x11\x38\x6e\x81\x1b\x04\x2b\x55\x5d\x93\x84\x5b\x50\xf6\x08\x38\xd9\x78\x71\x85\x0b\x34\x60\xb0\xc2\x07\x14\x23\xb8\x92\xa1\x23\x4e\xa2\x32\x60\x75\x63\x32\x22\x17\xe6\xda\x04\xd9\x12\xf0\xb1\x56\xfe\x5e\x83\xe4\x8b\x20\x2b\x57\xc6\x90\x1b\x5d\x7a\x49\x05\xba\x1a\xf5\x58\x9d\xc8\xb3\xf4\x96\x86\x84\x98\xda\x19\x3a\x9a\xb9\xb3\x8e\x0e\x81\x94\x50\xa2\x93\x5a\x4c\xd9\xcd\xf7\xdb\x3b\x06\x3c\x73\x4a\x57\x53\x36\x56\x21\x9f\x17\xbc\x4e\x54\x55\x37\x0e\xdc\xb2\xa6\x6e\x49\x25\x04\x56\x0c\x3c\x05\xa6\x2c\x1a\x32\x58\xf0\xa2\xa1\x47\x1b\xb0\x19\x8c\x5f\x38\xa8\x03\xf5\x7d\xcc\xdd\xb8\xa8\x3e\x40\xc1\x4c\x7a\x29\xb4\xfa\x3d\x4c\x87\x8f\x6e\x85\x28\xc9\xd1\x1a\x8f\x81\x55\x7f\xe8\x7a\x7e\x42\x39\x34\x4e\xe7\x3a\x6b\x2c\x45\xb5\xba\xf5\x91\x0c\x79\xbf\xea\x04\x71\x70\x73\xfd\xed\x70\x38\x06\x8f\x4b\xbd\xe3\x2d\x74\x27\x74\x06\x46\x3f\x10\xd2\xf1\x07\x06\xc4\x5f\x1b\x42\x20\xa8\x95\xc5\x30\xaa\xe4\xaa\x8a\x78\x47\xa0\x2a\x87\xa6\x44\xa1\xfc\x56\x84\x5c\x19\xeb\x76\x0b\xc2\x3b\xd9\x0b\xfe\xc6\xa8\x85\x4f\xf8\x9e\xe8\xb5\x7b\xd2\x75\xb4\xfa\x45\x56\x7b\xa1\xf6\x1a\x69\x9b\x59\xa9\x9e\xdb\x17\xa7\x67\xb0\x49\x7e\xa0\x33\x6b\xf2\xcf\x0a\x8b\xfe\xd0\x8d\x3d\x7f\xfb\x94\xbf\xe3\x66\xc6\x8b\xe2\xff\x79\x4e\x13\x9d\xc5\xc8\x4a\xda\x8b\xaa\xe6\xc6\x05\xf7\xef\x05\x77\x7c\xef\x29\x70\x31\x9c\xa1\x31\xf8\x08\xed\xa1\x5f\x0f\xab\x4d\x41\x43\x5e\x1b\xfd\xa8\x88\x07\xb3\x25\x2d\x0c\x4d\x73\x62\x88\x20\xd6\x91\x22\xf7\xae\x69\x5f\x84\xfd\xe2\x24\x77\x61\x9b\xf0\x82\xea\x2d\x96\x50\xf2\x8a\xde\x9a\x02\xc8\x00\xe3\xf6\xb9\x0f\x3e\x47\x6f\xb6\x22\x57\x7e\x50\x63\xfc\x9d\x80\xf7\x6b\x21\x6c\xb2\xde\xbd\x8d\x75\xfa\x03\x12\x0e\xd2\x60\x4e\xfd\x61\xe9\x27\x7a\xab\x27\x63\x9e\xc2\xcf\xb5\xd3\x64\x1c\x3f\x31\xa8\xcf\xe1\xbb\xe5\x1f\x65\x14\x02\x0a\xbf\x08\x00\x00")
+
+func dataImportHtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_dataImportHtml,
+		"data/import.html",
+	)
+}
+
+func dataImportHtml() (*asset, error) {
+	bytes, err := dataImportHtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "data/import.html", size: 2239, mode: os.FileMode(420), modTime: time.Unix(1792104191, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dataIndexHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xbd\x59\x4b\x73\xdb\x36\x10\xbe\xf7\x57\xa0\xac\xa7\x96\x67\x1c\xca\x8e\xdb\x26\xa3\x50\xf4\xa8\x4e\x3c\x71\xeb\x28\x19\xc9\x99\x1e\x3a\x3d\x40\xc4\x4a\x44\x4c\x12\x2c\x00\xda\xd1\xa4\xfe\xef\x5d\x00\xd4\x83\x12\x29\x4b\x56\x5d\x1e\x2c\x92\xd8\x07\xf6\xf5\xed\x82\x0e\x62\x9d\x26\xe1\x77\x04\xaf\x20\x06\xca\xdc\xad\x7d\xd4\x5c\x27\x10\x5e\x80\xd4\x44\x0b\x91\x04\x6d\xf7\x62\x41\xa0\x22\xc9\x73\x5c\x9c\xe6\xd0\xf5\x34\x7c\xd5\xed\x2f\xf4\x8e\xba\xb7\xde\x82\xce\x5c\xe3\x22\x8b\x34\x17\x19\x61\xe2\x4a\x7f\x6e\xd1\x48\x1f\x93\x9c\xea\xf8\x88\x7c\xab\xd0\x99\x8b\x89\xa8\x48\x21\xd3\xfe\x04\xf4\xbb\x04\xcc\xed\xaf\xd3\x2b\xd6\xf2\x0c\x83\x77\xe4\xdf\xd1\xa4\x00\xd2\xb5\xfc\x6f\xb6\xe7\xa6\x76\x03\x4b\xfc\xf8\x62\x07\xf6\x31\x72\xaa\x62\x94\x72\xdd\x3a\x5a\x67\x93\xa0\x0b\x99\x91\x31\x4d\x14\x54\x57\x1f\x9a\xfd\xd0\x6f\xed\xec\x82\xfe\x7e\x3e\xe8\x3f\x83\x15\x83\xdd\xad\x18\xec\x67\xc5\xe0\x19\xac\x18\x3e\x31\x27\x87\xfb\x99\x32\xdc\x33\x2b\x87\xfb\xbb\x22\x68\xbb\x8a\x2d\x41\xa0\xbd\x40\x81\x60\x24\xd8\x74\xa9\xde\xf3\xf0\x3d\x27\xdf\xbe\x11\xbf\x50\x20\xfd\x77\x29\xe5\x49\x8f\x31\x09\x4a\x91\x87\x87\x63\x32\x15\x05\xa1\x12\x48\x22\x26\x13\x60\x84\x67\x84\x2a\x4b\x2e\x45\x02\x48\xf1\x3d\xf9\x93\x04\x94\xc4\x12\xc6\x5d\xaf\x8d\x54\xa2\x40\x98\xb8\xb6\xbf\xa4\xa5\x63\xae\x8e\x82\x36\x0d\xc9\x3f\x6b\x54\xe7\xd1\xb8\x7b\x3a\x27\xbd\xb8\xb4\x64\x7f\x05\xed\x7c\xb1\x39\xd4\x23\x69\x36\x01\xe2\xa7\xb8\x1f\x3a\x01\xb3\xa7\x8a\xc5\x41\x4e\x94\x9e\x26\x08\x55\x39\x65\x8c\x67\x93\xce\x29\xa4\x6f\xc8\x48\x48\x06\xb2\xbc\xa7\xd1\xed\x44\x8a\x22\x63\x1d\x22\x27\xa3\xd6\xcb\xb3\xd7\xc7\xe4\xf4\xf5\x19\xfe\x79\xf5\xea\xe8\x8d\x17\x1a\x6b\x50\xee\xaa\x66\xc8\xd8\xb2\xb6\x60\x2c\x64\x4a\x38\xeb\x22\x68\x90\x14\x74\x2c\xf0\xf6\xd3\xc7\xe1\x8d\x47\x5c\xb8\xd1\xb0\x22\x67\x54\xc3\x0a\x4a\x06\x3c\xcb\xd1\x40\xc3\x69\xd3\xaa\x84\xd6\x98\x33\x06\x99\x47\x32\x9a\xc2\x6c\xa5\xdd\xc8\x59\x66\x54\x2d\xef\x6c\x6d\x85\xdb\x98\x15\x29\x39\xbe\xe4\x90\x54\x2d\x69\x1b\x53\xc2\x1a\xcb\x86\xcd\xa6\x29\x51\xc8\x68\x83\x69\xc3\xa7\xdb\x36\xfc\x7f\x8c\xeb\x37\x1b\x67\x54\xa9\x66\xdb\xfa\x3b\xda\xf6\x84\xcd\x0d\x9a\x37\x27\xe1\x4e\xdc\x6e\xf0\xfc\xe0\xd9\x76\x97\xdb\x39\x81\x8f\x79\x84\x59\xad\x48\x4a\x33\x2c\x41\x86\xfc\x7c\x4c\x32\xa1\x89\xaf\x62\x71\xdf\x4b\x12\x14\x42\x46\xd3\x45\xb5\x1e\xf0\x63\x72\xa0\x49\xa7\x4b\x7c\x0d\x34\x35\x45\xeb\x98\x0e\xb8\xc5\x94\x79\x71\xe1\x0d\xd2\xd9\x5f\xf7\xa2\x02\x26\xe7\x34\x49\x0c\x42\x0c\x51\x0b\xc1\x7b\x07\x10\x86\x16\x81\xcf\x88\x9b\x4b\xaf\xb0\x95\x0c\x22\x4b\xa6\x24\x9d\x12\x4b\xb3\x60\xb5\x6a\x3a\x95\x5a\x0f\x34\x1d\x21\x96\x39\xcc\xe8\x7a\xee\x77\xd5\xdf\x5a\x86\x6b\x40\x1c\xe8\x38\xec\xa3\xa3\x71\x88\x8a\xd7\x57\x9d\xc9\xbe\xb8\xcf\x40\xaa\x98\xe7\x06\x62\x90\xee\x06\xf7\x63\x19\xd6\x21\x66\x59\x2e\x62\x30\x37\x09\x40\x13\x62\x54\xa8\x7a\x1d\x86\xf2\x2d\x9d\x2a\x32\x00\x04\xee\x0c\xf1\xaf\x99\xee\x77\x98\x92\x1b\x4c\x92\x66\x8a\xa1\xad\xf1\xe6\xf5\x01\x64\x70\x4f\x93\x66\x82\x8b\x18\xc3\x04\x98\x02\xcd\x24\x3d\x9b\xd6\x35\xe6\xe0\x1b\xb9\x96\xa8\x25\xfa\x47\x98\x86\xaa\xde\x53\x35\x61\x71\x0b\x2c\x9c\xa7\x44\xac\x75\xae\x3a\xed\xb6\xc9\x7c\xe3\x4c\x94\xe4\x50\xbf\x7c\x30\xd9\x81\xea\x59\xbd\xa8\x32\x75\x57\x02\xc9\xac\x00\x13\x4c\x2b\xc0\x3d\x37\xc4\x73\xb6\xa1\xda\x85\xaa\xa5\x17\x12\xd8\xfb\x62\x64\xea\xce\x1f\xf6\xfa\x65\xe9\xd8\xf6\x34\x92\x58\xc9\x9b\x95\x2c\xb6\x4b\x91\xe8\xc0\x8f\x68\xd6\x63\x29\xf6\x6c\xff\x82\x66\x1f\x6c\xf9\x22\xeb\x52\xb1\xfc\xe0\x61\x9d\x44\x09\x8f\x6e\xbb\x5e\x39\x56\xb8\x19\xf6\xd0\x68\xfd\x84\xf0\x81\xf4\x87\xa6\x49\x62\x68\x6d\x5c\x2b\x75\x54\x6f\x69\xa3\x27\xd1\x07\xe5\xf6\x12\x04\x0f\x93\xb7\xf3\xb4\x25\x67\x27\xa6\x8e\xcb\x5e\x1e\x89\x44\xc8\x0e\xba\xc2\x5b\x60\x85\xf5\x77\x95\xa7\x74\x7c\xa3\xbf\x17\x6e\xc5\xdc\x37\xa9\xdf\xec\xce\xb2\x58\x97\xdd\x7f\x31\x1c\x18\x3a\x95\x62\x4e\x87\xad\xb1\x14\x29\xc1\x57\x38\xcd\xb8\x37\x73\xd6\xcd\x7b\xd8\x14\xa7\x6a\xb4\x6d\xf1\x19\x27\xfc\x17\x11\x1c\xb6\x0e\x5d\xc7\x3e\x3c\xde\x23\x94\x4b\x30\x76\x95\xe6\x42\x6a\x60\x73\xd7\x05\x2a\xa7\xd9\x7a\xc0\xc2\x3f\x38\x36\x04\xd3\x1d\x46\x80\x93\x2a\x02\x06\xb0\x63\x12\x59\x95\x44\xc7\x40\xdc\xb6\x70\x15\x9b\x0d\xa2\xb8\x26\xf0\x35\xe7\xd2\x00\x9c\x11\x18\xee\x91\x5b\x1b\x7d\x7d\xcf\xd1\x01\x15\x87\x0f\x6c\x5f\xdd\x68\xbb\x3b\x10\xd7\xdb\x39\x63\x6f\x19\xf7\x0e\x80\x2a\x3c\x73\x3c\x3c\x1c\xd9\xb8\xf6\xb4\x7f\x89\xad\x94\x6a\xe2\xbd\x3c\x39\xf9\xe5\xc5\xc9\xe9\x8b\x93\x97\xe4\xf4\xe7\xce\xc9\x4f\xe4\x83\x69\xee\xa6\x0b\xd2\x7b\x8a\x08\x8f\x69\x2c\x21\x4f\x68\x64\xe7\xff\xd2\x09\xce\xc3\x9b\xcc\x79\x3c\x68\xce\xe0\x12\xb2\x1f\x33\xb2\x0c\xf2\x25\x0e\xff\xae\xb0\x36\x52\x6f\x72\xcb\x4c\x04\x46\xd7\xba\xa2\x7c\xb6\x4d\x0a\x73\x9b\x99\x5f\xeb\xb2\x0b\xec\x02\x10\x15\x9a\xdf\x81\xa1\x29\xa4\xad\x4e\x42\xb5\x86\x34\xd7\xe6\xd4\xb0\x8d\x2b\x66\x57\x1f\xbe\x6a\x73\x30\x92\xd3\x8e\x55\x6b\x9e\x7b\x4e\xd4\xa3\xa1\xd8\x52\x45\x30\xaf\x7b\xff\x9a\x2a\xfd\x4e\x4a\x34\xd1\x94\xbf\x7b\xff\x98\x7f\xed\xd0\xb2\x85\x63\x07\xae\x66\xc8\x4c\xcf\xb0\x88\x22\x3c\xfc\xd4\x58\xe1\x6d\x11\xd5\xc7\xf3\xe4\x59\xaa\x6d\x0d\x4b\x67\x83\xc1\xa3\xd5\x96\x4b\x08\x57\xa1\x71\xce\xed\x5f\x65\x4a\xcb\xc2\x8d\x10\xee\xc8\x26\x21\xdc\x22\xb3\xe7\x60\xfa\x31\x07\x89\xc3\xec\xae\x70\xfa\xb9\x75\x18\x89\x34\x4f\x40\xd7\x02\x6a\xb9\xb4\x03\xa4\x3e\x9b\xd7\xcd\xec\xfb\x16\xcc\x6e\xb6\x35\x8c\x41\x83\x59\x4e\xcc\x8e\x7d\xc2\xe8\xb7\x29\xbc\xad\x7a\x5a\x68\x51\xa3\xdc\xca\x78\x82\x6e\x0c\x6a\x61\xf1\x6e\x2b\xe5\xa9\xa5\xae\x51\xef\xc4\x3c\xc9\x76\xd3\x16\xb6\xd2\x3f\x68\xd5\x58\x6d\xb8\xb7\x55\xbb\x7c\xea\x89\xb9\xd2\x42\x4e\xcf\xcd\x91\xaf\xbb\x24\xd6\x0b\xdf\xbb\x15\x27\x74\x2b\x59\xb4\xc0\xb3\xc7\x79\x2c\x94\xee\x2e\x4d\xc9\x3f\x1a\xec\xee\x9e\x9d\x78\x61\xcf\xac\x6f\x90\x57\x9f\xc1\xb5\x03\xfe\xea\x47\x96\xb6\x3d\x8b\x55\xbe\xc3\x18\xcf\xce\xc7\xa0\x8a\x5f\xdb\x94\x61\xc7\xc1\x93\x92\xdb\x4b\xc5\x06\x6e\xe7\x15\x2f\x74\x73\x4b\x49\x30\x97\x97\x88\x88\x26\x37\xe8\x6a\x0b\x23\xcb\x7c\xda\xbe\x44\xa9\x9f\xae\x88\x23\x58\x89\xc6\xfa\xa6\x97\xd9\xbf\x88\x11\x32\xff\x86\x7f\x6b\xf6\x64\xfd\x5a\xba\x8f\x5c\x8b\xc9\x8a\x0b\xf1\xbc\x7d\x0d\xd4\x7c\xb7\x9a\x6f\xd3\x3e\x96\x43\xeb\xf2\x43\xd9\x4c\x8a\xec\x36\xc3\x63\xc9\xea\x14\xcb\xd5\xf5\x8c\xd6\x7d\x82\x23\x1c\x71\x93\x66\x11\x1c\x2d\x78\x7d\x52\xce\x06\xca\xa2\xa3\x28\xb4\xf9\xa0\xe0\xbe\x60\x29\x6c\xa5\x7f\x17\xa0\xcc\xbc\x17\x03\x8e\x69\xe6\x0b\x20\x8e\x70\x0c\x9f\xc5\x1d\x0a\xd6\x02\x27\x37\x7f\x69\x04\xce\x67\x1f\x1a\xdd\xd7\xc5\xa0\xed\xfe\x03\xf1\x2f\xa6\x88\x3e\xdb\x89\x18\x00\x00")
 
 func dataIndexHtmlBytes() ([]byte, error) {
 	return bindataRead(
@@ -105,7 +173,87 @@ func dataIndexHtml() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "data/index.html", size: 2902, mode: os.FileMode(420), modTime: time.Unix(1551132609, 0)}
+	info := bindataFileInfo{name: "data/index.html", size: 6281, mode: os.FileMode(420), modTime: time.Unix(1792104922, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dataJobsHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8d\x55\xdf\x6f\xda\x30\x10\x7e\xdf\x5f\x71\xf2\xcb\x36\xa9\x24\x01\xc6\x8a\x68\x12\xa9\x5b\x57\x8d\x4e\x9b\xa6\x75\x7b\x9a\xf6\x60\x92\x03\x7b\x4b\xec\xd4\x36\x54\xa8\xea\xff\xbe\x8b\x43\x09\x20\xd2\xc2\x03\x38\xbe\xfb\xee\xfb\x72\xbf\x88\x85\x2b\x8b\xf4\x15\xd0\x27\x16\xc8\xf3\xe6\xe8\x1f\x9d\x74\x05\xa6\x37\x7a\x66\xe3\xb0\x39\x6f\x6d\x0f\x0f\x20\xe7\x10\xf0\xcc\xc9\x15\xc2\xe3\x63\x5c\xa2\xe3\x20\x9c\xab\x7a\x78\xb7\x94\xab\x84\x19\x9c\x1b\xb4\x82\x41\xa6\x95\x43\xe5\x12\x36\x62\x10\xa6\x04\x44\x95\x13\xa2\x61\x0c\x5b\xca\x78\xa6\xf3\xf5\x0e\xbb\x18\x6e\xa8\xe9\xb0\xcb\x6b\xb8\x5a\x20\x04\x25\x5a\xcb\x17\x68\x9f\x42\x6d\x71\x15\x58\xb7\x2e\x30\x61\x15\xcf\x73\xa9\x16\x93\x3e\x96\x17\x30\xd3\x26\x47\xb3\x39\xf3\xec\xdf\xc2\xe8\xa5\xca\x27\x60\x16\xb3\x37\x83\xe1\xf8\x0c\xfa\xe3\x21\x7d\x9d\x9f\xbf\xbd\x60\xb5\xc8\xa0\x7e\xa9\xb0\xda\x63\xde\x11\xde\x30\xa5\x3f\x50\xe1\x3d\x2f\x2c\x70\x32\x65\x82\x17\x05\xd6\xe2\x32\x5d\x56\x05\x3a\xa9\x95\x25\x31\xdc\x38\xcc\x61\x6e\x74\x09\x02\x0d\x82\x36\xe0\x04\xc2\xe5\xf7\x29\x98\xa5\x02\xa9\xfc\x63\x2b\x2a\x80\x6b\xa9\xa4\x15\x04\xfa\x4b\x09\x00\x4e\x18\x2b\xf4\xbd\x82\x39\x41\x39\xe4\x7c\x1d\xc0\x74\x4e\x28\x69\x09\x4d\x04\x2a\x43\x90\x56\xbd\x76\x3e\x52\x41\x29\x45\x73\xd6\x82\x05\xa9\xa3\x60\x7a\x85\xc6\x87\x68\x9d\xc0\xe9\x5a\xc3\x99\xd7\xdf\xf2\x68\x52\xe5\x2c\xd4\xe9\x87\x8a\x92\x1c\xec\x65\x22\x76\x7c\x56\xe0\x26\xa3\x09\x6b\x7e\x59\xba\x5f\x05\x67\xf6\x2f\x9a\x4b\x91\x4e\xaf\xa8\x97\xc4\x71\xdb\x47\x83\x9c\x52\xd5\xed\xf0\xcb\xa2\xe9\xb6\x92\xdc\x6e\xe3\x67\x6d\x5d\xb7\xf5\xd6\x71\xb7\xb4\xcf\xd9\xb1\xea\xb6\x7e\x32\x46\x1f\xd1\x45\x37\x07\x49\x68\xbb\xd7\xd7\xe6\xa0\x73\x3b\xf3\xd6\x18\x72\xdf\x96\xd3\x2b\xdf\x98\x2e\x7f\xde\x6d\x93\xca\xe0\x5a\x9b\x92\x3b\x60\x83\x28\x7a\xdf\x8b\xfa\xbd\x68\x00\xfd\xd1\x24\x7a\x37\x89\x46\xf0\xf5\xf6\x27\x3b\x29\xd8\x65\xe6\xa8\x6b\x4e\xf1\xfc\x22\xfd\x88\xbc\xec\x58\x97\x43\xf1\x12\x5f\x72\xde\x2c\x1a\xbc\x83\xa0\xa9\x11\xb0\x39\x97\x05\xe6\xb5\xf2\xa7\x41\xcf\x74\xa1\xcd\xc4\xd4\x97\xdb\x29\xf5\x2c\x1b\xc8\x29\x82\xea\x0a\x9f\xe4\xe8\x8b\xdd\xe9\x79\xb4\xe6\x58\x58\xec\x2a\x76\xfd\x8e\xa4\xdf\x56\x5c\x25\x6c\xcc\xd2\x6f\x34\x90\x98\xd1\xbe\xf4\xe3\x1b\x78\x96\xe3\x41\x0f\x96\x51\xe8\xa7\x32\xdd\xdd\x4e\xbf\x21\xa6\x9d\x4c\x8b\x38\x61\x21\x4b\x3f\xd0\x82\x89\x43\x9e\xc2\x9f\xed\x34\xc7\x61\xb3\x73\x69\xc7\xfa\x3f\x81\xff\xce\xc7\x95\x91\x0c\x06\x00\x00")
+
+func dataJobsHtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_dataJobsHtml,
+		"data/jobs.html",
+	)
+}
+
+func dataJobsHtml() (*asset, error) {
+	bytes, err := dataJobsHtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "data/jobs.html", size: 1548, mode: os.FileMode(420), modTime: time.Unix(1792104922, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dataNamesHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xcd\x56\x4d\x8b\xdb\x30\x10\xbd\xf7\x57\x0c\xa2\x87\x04\x96\xb8\xed\x1e\x0a\x8b\x63\x08\xa1\x85\xb2\xb4\x1b\x36\x7b\x0f\x8a\x35\x89\x45\x65\xcb\x95\xe4\xa4\x61\xc9\x7f\xef\x48\x72\xbe\x9a\x84\x6c\x03\x5d\xd6\x27\xc9\x9a\x79\x6f\x34\xf3\x34\x52\x5a\xb8\x52\x65\xef\x80\xbe\xb4\x40\x2e\xe2\x30\x4c\x9d\x74\x0a\xb3\x61\xc1\xab\x39\x42\xc5\x4b\xb4\x37\xf0\x13\x57\xe0\x56\x35\x5a\xe0\x95\x00\x83\x15\x2e\xb9\x82\x5a\x2b\x99\xaf\xd2\x24\x7a\x44\xb0\x64\x87\x96\x4e\xb5\x58\xed\x01\x17\xb7\xff\x80\x0a\x33\x6d\xe0\xf9\x19\x7a\x85\xb6\x0e\xd6\x6b\x02\xbe\xdd\xc3\xa2\xd5\x12\x4a\x74\x85\x16\x7d\x36\x7a\x18\x3f\x31\xe0\xb9\x93\xba\xea\xb3\xa4\xa9\x05\x77\xc8\x76\xd6\xc1\x43\x56\x75\xe3\x02\x5d\x9f\x15\x52\x08\xac\x58\x88\xa3\xcf\xa2\x23\x83\x05\x57\x0d\x4d\x43\x70\x0c\x92\x17\xfb\xfb\x08\xb7\xde\x7b\x21\x1f\x63\xd4\xd9\x40\x08\xe9\xd9\x68\x9f\xde\x2a\x70\x41\x67\x3c\xf8\x61\xbb\xe0\x34\xc8\x2a\x57\x8d\xc0\x1b\xd0\x15\x42\x8d\x06\x94\xac\xf0\x2e\x4d\xea\x23\xa0\xd4\xe1\x6f\xc7\x0d\xf2\x36\x08\xcb\x2b\x0a\xda\xe8\xa5\xed\xb3\x8f\x1f\x18\xe4\x5a\xd1\xe8\xf3\x27\x96\xf9\x88\xfc\x6a\x48\xe2\xc6\x2b\x3b\x85\x79\xdf\xd6\xe3\x24\xe1\xc1\x3c\xfc\xb3\xa8\x30\x77\x2d\x3f\xd5\x72\xe2\x7d\xd9\xb1\x61\x30\xd6\xb5\xdf\xf6\x26\x4b\x2c\xeb\x58\xdd\x98\x1c\x41\xe0\x8c\x37\xca\x75\xd3\x24\x5a\x9c\x76\xa7\x3d\xbc\xcf\x1b\x03\x77\x7d\xe8\x11\xd3\x13\x11\xd1\x76\xce\x99\x9a\x20\xb2\x8d\xa1\x3d\x67\xb9\x1f\x17\x79\xc9\x19\xe0\x2f\xe8\x45\xa2\xf5\x3a\xee\x0e\x49\x5e\x9b\x91\xaf\x2d\x92\x50\xd7\xeb\x90\xd3\x90\xcf\x0b\x41\x47\xf3\xe3\xd4\x25\x11\xf3\xaf\x24\x9f\xc8\xfa\x40\x59\x52\x85\xb5\x0d\x02\x07\x8b\xb9\x26\xc0\x1c\x8d\x83\xa5\x74\xc5\xf6\x00\x41\x27\xc6\xc1\x55\xf7\x9a\xda\x45\x5c\x6e\x56\x93\x57\xa9\xe2\x78\x43\x17\xca\xb9\x25\xbf\x7f\x85\xba\xee\xa8\xdf\x4a\x81\x1f\xcc\x8d\x8f\xd0\x15\x74\xde\x8d\x5c\x50\xe3\x0a\x55\x2d\x1b\x6a\x21\x53\x3f\xae\x1d\xa0\xb2\xb8\x2c\xd0\x50\x5f\xe0\x30\x1c\x3f\x42\x67\xf4\xe5\x7b\xec\x17\x41\x19\x5e\x10\xd6\x37\xcc\x1e\x7c\xa3\x41\x6c\x2a\xbe\xa5\x7a\x24\x3a\xed\xd0\x58\x14\xd4\x5b\xac\xa3\xde\x0c\xda\xb3\x69\x4b\x82\x9a\xea\x05\xf6\x60\xa8\x90\x1b\x90\xce\xe3\x15\x7c\x11\xf8\x2d\xcc\xa9\x1b\x1b\x8a\x46\x80\x27\x06\x3e\xe7\xb2\x7a\x51\x23\xca\xad\x39\xdf\x87\x68\xf1\x72\x1b\x7a\x3c\xb8\x08\xae\x11\x74\x7b\x95\x4c\x22\xc2\x7f\x15\xf3\x28\xde\x56\x5e\xc9\x2d\x6b\xfb\xe7\xa2\x8c\xf7\xcd\xe5\x55\x6a\xde\x32\xbd\x0d\x29\x1f\x5c\x91\xb6\x99\x96\x72\x77\x2d\x8e\xdb\x69\x72\x5c\xf1\x56\x17\xb3\xaf\x12\xd5\x41\x04\x69\xe2\x6f\xf9\xcd\xbb\x22\x3e\x26\xe8\x19\x10\x1e\x2e\x7f\x00\x64\x21\x8a\x55\xc0\x08\x00\x00")
+
+func dataNamesHtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_dataNamesHtml,
+		"data/names.html",
+	)
+}
+
+func dataNamesHtml() (*asset, error) {
+	bytes, err := dataNamesHtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "data/names.html", size: 2240, mode: os.FileMode(420), modTime: time.Unix(1792104001, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dataOpenapiJson = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xed\x5d\x5b\x73\xdb\xb8\x15\x7e\xcf\xaf\xc0\xa8\x9d\xd9\x76\x46\x91\x9c\x4b\x1f\x9a\x76\x3a\x93\xf5\x66\x1b\x67\x9a\x26\x8d\x33\xb3\x0f\xbb\x1e\x2f\x44\x42\x22\x62\x0a\xe0\x02\xa0\x15\x25\xe3\xff\xde\x73\x00\x52\x37\xf3\x02\x4a\xa2\x2d\xcb\xcc\x4b\x2c\x12\x97\x03\xe0\xe0\x3b\x17\x1c\x1c\x7e\x7f\x42\xe0\x5f\x4f\x26\x4c\xd0\x84\xf7\x5e\x91\xde\x8b\xc1\xc9\xe0\x45\xaf\xef\x9e\x73\x31\x96\xf0\xf0\xbb\xfd\x65\x9f\x18\x6e\x62\x86\xe5\x62\xf6\x54\x31\x9d\x48\x11\x32\x95\x15\xb7\x05\x42\xa6\x03\xc5\x13\xc3\xa5\xc0\x62\xef\xa9\xa0\x13\x46\x02\xa6\x0c\x1f\xf3\x80\x1a\xa6\x09\xd7\x3a\x65\x21\x19\xcd\xc9\x6a\x23\x03\xf2\x89\xfd\x91\x32\x6d\x34\x99\xa6\xda\x10\xcd\x44\x48\xa8\x20\xbf\xbf\x4e\x4d\x24\x15\xff\x46\xb1\xcd\x57\xe4\x47\x46\x15\x53\xe4\x9f\x46\x5e\x31\xf1\xaf\xdf\x49\xc4\xa8\xad\xfd\x51\xf1\x6b\x68\x9e\x5c\xb1\xb9\x26\x50\x84\x08\x76\x0d\xe5\x14\x33\xa9\x12\x2c\x1c\x90\xb3\x31\x91\x33\xc1\x94\x8e\x78\x02\x34\x90\x40\x8a\x31\x9f\xa4\x8a\x85\x7d\x12\x44\x54\x4c\xb8\x98\x10\xba\x4a\xaa\x2d\x6f\x09\xa5\x42\x9a\x08\x5a\x33\x8c\x4e\xb3\x26\x35\x79\x79\xf2\x62\xb0\x3a\x74\xe8\x4e\x67\xc3\x7e\xd6\xb3\x8f\x6f\xb2\x79\xd4\x4c\xe1\x4b\x78\xf3\xeb\xa2\xf8\x72\x52\x6d\x91\x54\xc5\x58\x71\x08\xcb\x30\xbc\xce\xaa\xdb\x26\xec\x5f\x17\x8b\x86\x82\x54\x71\x33\xaf\x6a\x69\x64\x27\x08\x4b\x5c\x94\xb4\x92\x50\x13\xe9\xf5\x75\x1d\x6a\x99\xaa\x80\xad\x3f\xb5\x6f\x26\xcc\xdc\x7a\xe8\x68\x49\xa7\x53\xaa\x90\x94\xde\x7f\x38\x2c\xd8\x72\x3e\x49\xd6\x18\x4c\x5b\x88\xeb\x41\xcc\x3c\x81\xa6\xfb\xb7\xdb\x00\xc6\x53\x76\x5d\xcf\x42\xcb\x54\xd0\xce\x79\x46\x48\x41\x69\xc7\x2b\xba\x80\xca\x45\x91\xe7\x27\x27\xa5\x2f\x8b\xf8\xb3\xbc\xb7\x45\x0d\x18\x97\x61\xc2\x54\x36\x6b\x0b\xd2\x24\x89\x91\x6f\xa0\xe9\xe1\x17\x6d\xdb\xaf\xae\xe1\xa6\x31\x88\xd8\x94\x7a\x95\xb5\xe5\xff\xac\xd8\x18\x29\xff\xd3\x30\x90\x53\x98\x0e\x20\x4d\x0f\x5d\x23\x7a\x98\x0f\xa7\xb6\xa9\x9b\x27\xdb\xbd\x2d\x7e\x73\x53\x3c\x7d\x30\xd9\x63\x9a\xc6\xd5\x53\x57\x3c\xa0\xc5\x52\x0f\xdf\x28\x25\x55\xf1\x80\x6e\x13\xb3\xfe\x64\xf9\x6b\x85\xc0\xde\x10\x77\xf8\x2e\x8c\x3e\xb5\x98\x16\xae\x81\x9a\x27\x73\x9f\xda\xae\xef\x86\xb5\x4f\x57\xc8\xeb\xc3\x96\x54\xc6\x61\x59\x24\xb5\x11\x74\xca\x1e\x10\xcf\x23\x7e\xe0\x90\xe4\xe8\x0b\x0b\x4c\x05\xe1\x6b\xb5\x12\x85\x2b\x60\x78\xc5\xa4\x16\x0f\xbe\x90\x3f\xbc\x89\xa4\x4a\xd1\xb9\x27\x8d\x8b\xca\xdc\xb0\x69\xf3\x3e\xeb\x01\x01\x99\xa0\xd7\xa8\xcd\x9b\x27\xfb\x2d\x79\xd3\x61\x51\x01\x59\xbd\x04\x76\x61\x2d\xdc\x9c\x1b\xaa\x32\xbc\xb9\xa5\x9c\x14\xa1\xc8\x06\x02\x7c\x8e\xd6\x34\x2f\x54\x7a\x32\xdd\x4b\x0a\x02\xfa\x0c\xa8\x48\x5f\x0d\x81\x3d\xc2\x65\xc8\x03\xa2\x03\x2a\xfa\x44\x2a\x12\xd0\x38\x06\x2d\x47\xb0\x19\x31\xd2\x55\x21\xdc\x10\x21\x67\x03\x0f\x9c\x0b\x14\x83\xce\x2c\xe3\x15\x02\x9d\x55\xf2\x7e\x94\xe1\xbc\x1c\xea\xb0\x10\x07\x45\x02\x4a\x18\x95\xb2\x92\x15\xf5\x01\xa9\xe6\x00\xd5\x04\x9c\x6a\xf6\xde\x72\x22\xf6\xcb\xe1\x4f\x3c\x78\xde\x4f\xa2\x3c\xdb\x69\x3b\x94\x0f\xad\x6c\x13\xbe\xac\x93\x61\x3b\x6c\xc0\xd2\x2e\xff\x7e\xd7\x5d\x1e\xb0\xda\x33\xfc\x9e\x8b\xff\x9b\xdb\x1a\x50\x42\x15\xbc\x30\x9b\x96\x4a\xb1\x9d\x51\x3d\x90\x65\x53\xc3\xb7\xb9\xbe\x51\x43\xf6\x45\xbf\xb9\x3e\xf6\x6f\x66\xea\x51\x71\x03\x9f\xa0\xdd\x72\x70\xda\x5d\x0b\x6b\x61\xcf\xbc\x7c\x6c\x0c\xbc\xc1\x09\x21\x8b\x81\x95\x3c\xa4\xa5\x4c\x1a\x08\xcb\x0d\xb6\x70\x9d\x94\x71\x46\xb1\xd9\x48\x66\xdc\x44\x20\x29\xaf\xe5\x15\xbb\x94\xe2\xd2\xb5\x41\x34\x70\xa5\x7b\x68\xa5\xec\xaa\x08\x1e\x73\xa5\x8d\xf5\x45\xd8\x02\x48\xe8\x98\xf2\x18\x14\x74\x10\xb0\x20\x9f\x85\x34\xc4\xb5\x12\x16\xca\xda\x9a\x2d\x5a\xbe\x4d\x6d\x6d\xbb\x0d\x5f\x21\x9f\x23\x71\x55\x06\x00\xb7\xc3\x04\x51\xad\xaa\x54\xd9\xcd\x69\xf9\x54\x31\xe8\xbe\x9b\x2c\xf7\x4e\x6b\x3b\xf3\x1f\xc6\x1f\xf2\x45\x80\xf9\xa0\x28\x9e\x2b\x3a\xf3\x14\xcc\x0b\x45\x7c\x24\x65\xcc\xa8\xe8\xed\x2c\x5e\x2f\xb6\x85\x8a\x97\x8d\x0c\xb6\x9f\xdc\xba\x37\x45\x87\x17\x77\x2f\x51\x5f\x3e\x02\x21\xfe\xb7\x93\xe7\x9d\xde\x50\xa4\x37\x64\xae\xc2\x43\x57\x1f\x92\xb4\x5e\x7d\x38\x45\xb7\xaf\xc3\x2b\x37\x28\x92\x6a\xb0\x8e\xc6\x60\x03\x8d\x53\x93\x2a\xe6\xac\x20\x1a\xfb\xf8\x77\xac\x0f\x99\x39\xc9\x70\xfc\x96\x4f\x63\x97\xcc\xea\xd0\x7e\xf5\x73\xe0\x64\x8c\x56\x5b\xf8\xc2\xa3\xf7\x2d\x9c\x41\xbd\x12\x46\xf7\x9a\x17\x6d\x14\x08\xf7\xde\x61\xba\x48\xf6\x67\x40\x9e\x3c\x06\x03\xf2\x65\x27\x08\x0a\x05\x41\xa0\xd5\x31\x48\x81\x73\x50\xd7\x51\x04\x9c\x9e\x7f\x82\xff\xa9\xc9\xb1\x7f\xed\xd0\x12\x4f\x14\x33\xe7\x19\x88\x07\xeb\x26\xe3\x63\xc2\xa6\x89\x99\xf7\xc9\x04\xa4\x84\x5a\x1c\x3e\x46\x0c\xca\xd2\x09\xe5\xc2\x5b\x6a\x40\xd7\x9d\xc8\xd8\x87\xc8\x40\x96\xbc\x37\x79\x51\xb4\x1f\x9a\x08\x0b\xff\x33\x83\x4d\xd3\xe1\xe3\x9b\xf7\xc8\xbe\x60\x63\xc1\xee\x64\x04\x77\x8a\x26\x23\x06\x7b\xe9\x96\x35\xf6\x83\xee\x84\x52\x27\x94\x8e\x57\x28\x59\x8d\xfd\xe0\xc5\x92\xcf\xe9\xcf\xff\x52\x96\x82\x20\x21\x5f\xe4\x68\x79\x0e\x43\x09\x1e\xcb\xac\x7a\x57\x84\x9c\x79\xc8\x19\x3b\x2d\x3b\xf9\x3d\x9f\x37\x72\x66\xe0\xd9\x13\x10\x8e\x88\xc4\x83\x88\xa8\x54\x68\xc2\xdd\xa9\xd3\x88\x06\x57\x13\x25\x53\x11\x1e\x4b\xd8\xc5\x3b\x39\x3a\x94\x90\x8b\x47\x01\x0c\xf5\x0e\xda\x8f\x32\x8e\x2d\xb3\xe5\xf1\x5f\x76\x17\xa5\xc2\xf0\x18\x3d\xad\x11\xd5\x44\xa7\x41\xc0\x58\x88\xa7\xa1\xca\x3a\x61\xd1\xe5\xba\x15\xe4\x4c\xa9\x48\x69\x7c\x14\x98\xe3\x4e\x9c\x29\x71\x43\x22\xa1\xd0\x4f\x4f\x9e\x61\x78\x5c\x1c\x33\xd0\x53\x3d\x0f\x9d\x17\x93\xbe\xa8\x68\x67\x9c\x0b\x50\x74\xd2\x00\x4b\x6a\xeb\x64\xc1\x05\xfa\xe9\xbf\xe7\x50\x3e\x90\x2a\xd4\x88\x72\xee\xf4\xb8\x4f\xe8\x18\x06\x9b\x81\x87\x3d\x8f\xc6\xc9\x40\xff\xa8\xcf\x11\xb4\xc6\x51\xbc\xb7\x43\x38\xad\x22\xbd\x3b\xef\xe9\xdc\xab\x87\x67\x55\x67\x8c\x7e\x8c\x3a\x4c\x3e\xb6\x25\xc2\x2c\x11\x02\x83\x45\x9d\x8e\xb3\x61\xb1\xf8\x58\xd1\x59\xbb\xbb\xef\xf6\x4e\xcb\xe9\xb4\x9c\x43\xc1\xc8\x47\xaf\x58\x65\xe7\xd4\xc7\x00\x84\xab\x47\xe3\xa9\x02\x6b\xcc\x34\x0c\xe6\x7b\x0d\x33\x9e\xc4\x34\x60\x53\xac\xeb\x11\xca\x37\x20\x1f\x44\x3c\x27\xaf\x4f\xdf\xbf\xb1\xd8\x1a\xd0\x45\x5c\x3e\xbc\xcd\x02\x14\x06\x5e\xa6\x23\x96\x7c\x1c\x01\x7d\xcd\x7d\x94\xdb\x38\x0a\xb3\x58\x87\x3b\xf2\x15\x32\x91\x4e\xbd\x3d\xa8\x8b\x5a\x20\xca\x12\x16\x00\x7f\xb2\xb0\x69\x30\xf3\x15\x9b\x9f\xc2\x0e\x53\x72\xca\x35\x6b\x5a\x99\x8e\xc7\x3c\xe6\x76\x7d\xdd\xf1\x6c\xe3\xee\x75\x0a\x0b\xa2\x11\x80\x9a\xd6\x2c\x0a\x4c\xf1\x8f\x9e\xbe\x68\xe4\xbf\xcd\xa1\x7d\x6d\xa2\x3b\xcf\x6c\xe7\x99\xed\xcc\xb7\x07\x6f\xbe\x65\xc8\x77\x84\x06\x1c\x20\x72\x18\xe3\xef\xe5\x30\x43\x92\x2c\xaf\x5e\x7a\x3a\x88\xb0\x35\xa7\x56\xe8\x5b\xd1\x82\x8b\x30\xc1\x35\x31\x92\xc5\x07\xf6\x9d\xda\xa3\x81\x82\x55\x75\xc8\xd6\x71\xae\x71\xa8\xd5\xb7\xfa\x4e\x9a\x84\xf6\xe4\x56\xa6\x26\x49\x0d\xa8\xa5\x46\x51\x3e\x89\x0c\xa1\x33\x3a\x1f\x90\x6d\x15\xd9\x7a\x7d\xa9\x4e\xfc\x75\x86\x68\x67\x88\x76\x86\xe8\xee\xd0\xcb\xa1\x17\x65\x0a\x50\xd6\x07\xd7\xce\x6c\xe5\x8d\xfb\xde\x99\x49\xc5\x62\xcd\x66\x18\xc5\xe1\x89\x66\x78\xc5\xd9\xde\x59\x9f\x52\x13\x44\x9b\x80\xd6\x77\x0f\x22\x0a\xfb\xd1\x96\x1a\xa5\x3c\x0e\x11\x4c\x29\x1a\x43\x1a\xaf\x65\x2a\x29\x8d\x43\xad\x4d\x34\x74\x35\xd8\xc2\x5d\x9e\x0b\x9a\x01\x71\x23\xd8\xb8\x87\x0a\xb8\x75\xbd\x16\xd0\xf8\x5b\x8f\x67\xe5\x7e\xeb\xd9\x0e\x56\xaf\xc6\x03\x5e\xb2\xf0\x1f\xee\xf6\xfb\x5a\xad\x11\x83\xfe\xec\x93\x39\x61\x5f\x13\x30\xdd\x7c\x80\xcf\xf5\xd4\xdd\xfc\x1a\x9e\x2d\x27\xa2\xbb\xf9\x75\x8f\x9a\xf8\x8b\x0e\x9b\xef\x15\x9b\x87\x86\xaa\x11\x8d\xe3\xdd\x30\x1a\xc1\x6a\xbe\x8e\xd3\x02\xb1\xd3\xb5\x4d\x50\x51\x8b\x78\x92\x30\x8b\xa9\xa0\x76\x7d\xe5\xc5\xf7\xf1\x2b\xee\xc7\x13\x1a\x83\x7e\x19\xce\x17\x77\xfb\x11\x25\xf5\x95\x6d\x75\x40\xde\x50\x40\x75\xae\x51\xe3\x74\x88\x8b\xd7\x55\xa8\x4b\x08\x02\xd0\x99\x69\x91\x16\xdd\xac\x9a\x68\xaf\x1f\xa1\x8a\x3a\x8b\x64\xdc\x00\x39\x3f\x67\xb3\x75\x60\xe0\x39\xf9\xc6\x93\x76\xbc\x6c\xde\xbe\xac\x1e\x88\x23\x10\xae\xf6\x56\x0f\x17\xc8\x1e\x0f\xd4\xc3\xb1\xc1\x83\x6f\xe5\x8c\x30\xe4\xae\x35\x03\x08\x57\xa7\x4b\xce\x50\xe6\x3a\xd5\x80\x76\xc7\x92\x9e\xc1\x21\xdc\x27\x3b\xa6\x2e\x4d\xc3\x3d\xe9\x09\x87\x29\x41\xc1\x9a\xdf\x25\x49\x8d\x62\x01\x3a\x46\x6c\x2b\x7e\xb9\x69\xde\x95\x14\x6d\x01\xf6\xb0\xab\x3e\x7a\x6b\xac\xc4\x74\x57\x62\x7f\xe6\x82\xeb\xc8\xb9\x61\x5c\xb8\xfd\x15\x4b\x8c\xb5\x7b\x28\x09\xe9\x7c\xd0\x41\x62\x49\xbd\x42\x4e\x79\x98\x78\xe8\xe5\xd9\x79\x0c\x30\x78\xb8\x98\x34\xfc\xce\xc3\xbd\xe5\x8e\xc8\x2f\xa4\xf3\x32\xdf\x64\x76\x11\x1d\x33\xd6\x95\x95\xf0\xd3\x7a\x3d\xb6\xb0\xd7\xdd\xbd\x9b\xd6\xb2\x58\xc0\xe4\xfa\x65\xaf\x78\x57\x5c\xb0\x05\x9c\xce\xbc\xca\x9d\xdf\xf8\x18\xfc\xc6\x87\x03\x29\x6b\xb9\x31\x97\xfd\xac\x67\xa4\xcc\x53\x5d\x9e\xe3\xe2\x15\x25\xa6\x5c\xe4\xb9\x2c\xd8\x5c\xf9\x4e\x8e\x8c\x49\x8a\xf6\x8a\xe5\x08\x97\x24\xc2\xb5\x52\x50\xc6\xbd\xf9\x79\x61\x78\xbe\xfb\xe5\xb3\x4f\x14\xd3\x1a\x10\x6e\x90\xbc\x38\xa9\x2b\x24\x3a\x07\xc3\x8a\x04\x7d\xd5\x70\x58\x0f\x85\x45\x1b\x3c\x51\x1c\x81\x68\xe1\x59\xbe\xe5\x82\xe6\x7a\xe1\x16\x49\x37\xd2\xbd\xfa\xee\xd3\x5a\x68\xf5\x11\x3f\xe5\x00\x67\x7d\x39\xc5\x93\x5a\x9d\x12\xad\x68\x28\x75\x88\xd6\x0c\xc9\xbc\xb3\x96\xec\x90\xc6\xef\x66\x2f\x59\x76\xdc\xde\xf6\x99\x44\x57\xf2\x81\x4c\x5d\x05\x64\xed\x30\x77\x85\x0c\x9a\x75\x79\x9b\x3d\x2b\xa6\xb6\xd6\x54\xf0\x31\x0b\x7a\xac\xb4\x83\xbd\x2a\x37\x9b\x2c\x73\x5e\x92\x34\x78\x8f\x23\xd3\x15\x5d\x34\xb6\x65\x3c\xed\x16\xef\x24\x0e\xcd\x64\xfe\x15\x9b\x5f\xba\x4c\xc8\x47\x30\x98\x2c\x31\xca\x65\x22\x61\x43\xf3\x7b\x1e\x53\xb5\xf5\xea\x1f\x00\xd9\x0b\xe9\x5c\x5f\xba\xc3\x4f\x1f\xaf\x74\xcc\xc7\xcc\xf0\xa9\x57\x59\xaa\x78\xb5\xf2\x79\xb1\xb7\x95\xb9\x96\x0e\x62\x2f\x5d\xcc\xcc\x91\xac\x4d\xb3\x80\xd4\xc6\x41\xa8\xdb\x05\x9e\x36\x0b\x36\xdd\x22\xc0\xf4\xa2\x65\xa9\xbf\x92\xa4\x74\x4b\x14\xaf\x4d\xd2\xd0\xab\xc9\x38\x5d\x9a\xe7\xe7\x62\x4b\x99\x11\x55\xa9\xd9\xcd\x04\x62\x35\xc5\xed\xb5\x4f\xc5\x51\x89\xbc\x46\x33\xe5\x9f\x6f\xf0\x6c\x91\x81\x65\x25\x76\x25\xb3\x73\xd1\x6c\xc1\x6c\x5e\x0d\x27\x9e\x81\x0e\x1b\x82\x39\x74\xd9\x3e\xe9\x9a\x61\xcc\x0f\x71\x5d\x96\x24\x6a\xce\x82\x21\xe1\x41\xfe\x1d\x85\xde\xf6\x62\x7a\xbe\xaf\xb1\x78\x81\x76\xaf\x0e\xfa\xfd\x85\xad\xaf\xa0\xad\x16\xb2\x17\xfe\xab\xf3\x4b\xc4\x44\x91\x1d\x9c\x85\x4c\xbd\x22\x2b\xb4\xc3\x12\x8e\xf9\x57\x58\x2b\x98\x94\x11\x53\x44\x8e\xed\xdb\x3c\x86\xca\x86\x4f\x01\x87\xe6\x43\x20\x52\x04\xb6\x8e\xa2\xf6\x26\x34\x96\xe7\x46\x2f\xdf\x63\xe4\x69\x42\xb5\xc6\xef\x92\xe0\xe1\x87\xe2\x64\x96\x93\x73\xfa\x9a\xe8\x74\x32\xb1\x5f\x49\xf9\x8b\xbd\xd9\x93\x5f\xe9\x91\x22\x9e\xff\xd5\x26\x16\xdd\xf3\x8e\xa8\x4b\x6a\xb3\xfd\x16\x58\x24\xad\xe1\x63\x4b\xed\x4a\x18\x31\xb1\x0c\x9f\x98\x65\x28\xde\x80\x9c\x96\xa7\x63\x82\x09\xec\xc3\xd0\x30\xee\x02\xa7\xd2\xa5\xbf\xc9\x3f\x3e\x82\xb7\xce\x19\x0d\x71\x9e\x11\x56\xf3\xe7\x76\x73\xbb\x98\x3b\x21\x37\xfb\xd6\x46\xaa\xd2\x89\x6a\x28\x61\xdd\x19\x2f\x0c\x56\xb7\x27\x61\xab\x5d\x1a\x99\xe0\xb4\x23\x44\x5c\xdb\x9f\xa4\x5d\xed\xb7\x3d\x26\x59\xed\xa5\x19\xef\x62\xd0\x65\x8b\x84\x71\x61\x98\x9a\xb2\x90\x23\xeb\xac\x7d\x0e\xa4\x11\x99\xab\x4b\xd3\x1e\xb1\xab\x51\xfa\x7b\x64\xec\xfb\x55\x1d\xef\x8b\xf1\xdb\x57\x31\xbb\xad\x75\x4c\x5b\xab\x4e\xdd\xac\x89\xc6\x8b\xe3\x0f\xe3\x5a\x85\x6b\x3f\x51\xcb\x56\x50\x6d\x79\x06\xd6\x40\xc3\xfa\x60\xff\xa2\x71\x91\xfa\x9b\xc7\xba\x6b\x3c\x8d\x70\xc2\xdc\x5d\xf0\xc9\xbe\xc2\x56\xa3\x0a\x6f\x05\x63\x59\x0c\x56\x8b\x9e\xcc\x3b\xb0\x4a\x0d\x35\xa9\xbe\x5b\x25\x3f\xbf\x5d\x50\xa7\x99\x67\xc1\xb4\x75\xc5\xdc\x2d\xab\x0a\x1d\xbe\xd1\x84\xdc\x93\x5b\x7c\x17\x91\x78\x20\x9c\x74\x24\xfe\x87\x2d\xfc\x34\xfe\x10\xb6\x76\xb5\x06\x31\xab\xe8\x2b\x97\x0b\x03\x26\xbf\x9a\x67\xef\xdf\x48\x93\x9b\x92\xcd\xd6\x05\xbf\x3f\xd9\xd2\x68\x3e\xcc\x04\xda\x4f\xd8\x83\xb5\xc8\x4a\xbe\x95\xd9\x6b\xdf\xff\x73\x97\x0e\x9b\x03\x70\xa6\xb4\xee\xd3\x6a\xdd\x82\x77\x59\x93\x2b\xd3\x25\xfb\x98\xf8\xbd\xc6\xc7\x1c\x57\xd6\x74\xf0\x18\x59\x6d\x20\xe6\xad\x0f\xbc\x30\x93\xd3\xbc\xaa\x99\xa0\x6f\x66\xc4\x58\x9e\x3d\x25\xec\x2f\x2f\x0c\xbb\xfb\x21\x78\x23\xb9\xf2\x9e\x6d\x83\x20\xcf\x26\x69\x42\xb6\xb8\x52\xd1\x28\x2d\xc8\x16\xe9\x40\xb6\x4a\x03\xb2\x7d\xfa\x8f\xe6\x69\x3f\xb6\x4c\xf7\x71\x51\xad\x1c\xd7\x79\x28\x4d\xfb\x57\x64\xf0\xde\xfb\x53\xeb\x2e\xdd\x8d\xd4\xc0\x48\xb5\x15\xb5\x77\x12\x42\xe7\xe0\xc5\x6f\xff\x57\x7e\x0a\xa8\xba\x83\xcb\xa3\x3a\xb6\xd7\x4c\xf1\x82\x54\xa5\xfb\x11\x07\x6f\xd9\xd7\x2d\x66\x58\xb5\x27\xb0\x41\xcf\xca\x0f\x18\xf6\x34\x62\xef\x4d\x56\x45\x92\xcd\x6d\x7a\x30\x14\xd9\xa3\x0c\xc5\xa6\x94\xa3\x02\xe8\x47\x16\x7a\x67\x26\x85\x91\x80\x65\xbc\xf1\xf4\x19\xca\x53\x54\x7c\xe7\x28\x5a\xdd\xde\x3d\x3c\x87\x58\x43\x92\x68\x9b\xae\x39\xeb\x61\x6b\xea\x94\xcb\xd2\x5f\xee\x49\x2b\x6a\xa2\xad\xac\x66\xf7\x3d\x5c\xa9\x91\xe9\xf0\x2d\xa9\x8d\x78\x9f\x13\x79\xdb\x5a\x52\x78\x6a\xbb\xa9\x3c\x4e\xa4\xc0\x43\xa7\x91\xb6\x49\xfc\xc6\x79\xa6\x19\x97\x90\xc1\x6a\x96\x99\xd6\x3c\x9a\x93\x98\x3d\x75\x21\xaf\x61\xf5\x4e\x6b\xb2\x48\x31\xd5\x80\x3f\xc6\xa0\x89\xf1\x60\x14\x11\x4b\xb4\x4d\xc6\xa3\xf5\x83\x21\x3a\xc0\x50\xe5\x20\x35\xfc\x9a\x5d\xa2\x57\x0b\x8c\xe7\x86\xc4\xe7\x20\xbb\x13\x19\xd9\x7d\xf4\x4b\xcd\x85\xe7\xa7\xac\x0e\x66\xc5\xeb\xbd\x77\xdb\x21\x49\x4d\xdf\x98\x49\xf3\x3e\xb6\x88\x47\xb5\xcd\x94\xa0\xa9\x91\xd0\x0c\x0f\xf2\xcf\xd4\x91\x99\x14\x3f\xd8\x74\x31\x8a\x01\x2d\x08\x23\x79\x22\x17\xae\xef\x06\x5f\x03\x9a\x7f\x06\x75\x1f\x9a\x79\x43\xc7\x2b\x5e\xf0\x69\xd1\xef\xca\xc3\xf6\x54\xd5\x2b\x2e\xc2\xbb\xf5\xdc\xbb\x0f\xa5\xd4\xa2\x58\x96\x8c\x7c\x4f\x1e\xf9\x87\x1f\x9a\x57\x6f\x15\x3f\xb8\x33\x9c\x3f\x30\xc7\x5f\xed\xd1\x8c\x4a\x85\xf0\x08\xe8\x5d\x24\xcd\xf3\x3c\xea\xa9\x29\x85\x09\x07\xc1\x08\x97\xd7\x55\x82\xb0\xc1\xb9\xe3\x4a\x7b\xce\xc1\xc7\xdd\x77\x29\x28\x46\x65\x71\x8d\xf0\x89\x4e\x3f\xc0\x24\xd0\xba\x5c\x78\x90\x0b\xc6\xb2\x8f\xb3\x2c\x88\xcb\x12\xf8\x0b\x26\x66\x40\xce\x8c\xc6\xdc\x33\x13\x90\xf2\xe8\xfb\xc7\x22\xc2\x06\x65\x11\x1d\xc9\x99\x40\x7d\x6e\x59\x6b\xd0\x94\x27\x58\xd2\x92\xa1\x81\xf7\xa1\x50\xd8\x12\xec\x03\x73\x2c\x06\x11\x7a\x35\xd9\x60\x32\x20\x33\xca\x8d\xfd\x40\x35\x46\xa1\xa5\x26\x92\x8a\x7f\xab\xf0\x91\xb5\x7d\x08\x57\x26\x70\x6c\x00\x73\x78\x30\xe6\xb4\xfd\xf6\xc8\x01\xd1\x33\xce\xf2\x2a\xdc\x3d\x41\x4d\x2f\x84\x3e\xb9\x79\xf2\x7f\x8b\x47\x91\x8b\x90\x8e\x00\x00")
+
+func dataOpenapiJsonBytes() ([]byte, error) {
+	return bindataRead(
+		_dataOpenapiJson,
+		"data/openapi.json",
+	)
+}
+
+func dataOpenapiJson() (*asset, error) {
+	bytes, err := dataOpenapiJsonBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "data/openapi.json", size: 36496, mode: os.FileMode(420), modTime: time.Unix(1792104935, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _dataRevokeHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xad\x55\x4d\x8f\xd3\x30\x10\xbd\xef\xaf\x18\xe5\xbc\x4a\x0e\xcb\x09\xb9\x91\x00\x09\x09\x71\x00\x75\xf7\x86\x38\x4c\xe3\xc9\xda\x34\xb1\x83\x3f\x5a\xaa\xd5\xfe\x77\x26\x76\xb7\x5f\x49\xd1\x82\xc8\x29\xfe\x98\x37\x6f\x66\xde\x4b\x84\x0a\x7d\x57\xdf\x00\x3f\x42\x11\xca\xfc\x9a\x96\x41\x87\x8e\xea\x25\x6d\xec\x9a\xa0\x21\x17\x74\xab\x1b\x0c\x24\xaa\x7c\x92\x83\xaa\x63\x94\x58\x59\xb9\x3b\x01\x50\x77\x33\xd1\xd0\x5a\x07\x4f\x4f\x50\x2a\xeb\x03\x3c\x3f\x33\xc0\xdd\x31\x86\x0f\x74\x0b\x65\x83\xe6\x9d\xec\xb5\xe1\xf3\xc3\x51\x82\xe4\xe0\x1e\x7a\x0a\xca\xca\x45\xf1\xf5\xcb\xfd\x43\x01\xd8\x04\x6d\xcd\xa2\xa8\xe2\x20\x19\xbf\xa8\xcf\x22\x52\x94\x36\x43\x0c\x10\x76\x03\x2d\x0a\xa5\xa5\x24\x53\x80\xc1\x9e\x57\x39\xb8\x80\x0d\x76\x91\x97\x2e\xd1\x2d\xa0\xfa\x2b\x90\xb1\x92\x03\xc4\x49\x69\xf3\x38\xc3\x4b\x53\x82\xe2\xc6\x44\xe7\xc8\x84\xd3\x06\xdd\x02\x1a\x09\x3f\x23\x45\x02\x84\x1f\x76\x05\xc1\x82\xa3\xa1\xc3\x86\x40\x07\xf0\xc1\xa1\x7e\x54\x01\x70\x8b\xbb\x12\x96\x84\xde\x9a\xb7\xa2\x1a\x66\x73\x4d\xf6\xd2\xbe\xa7\x8e\x9a\xb0\xa7\xef\x12\x42\x31\x7f\x75\x3f\x14\x87\xe6\x91\xa0\xcc\x57\xfd\xe5\x58\x26\xf8\x76\x18\xdb\x5a\x8f\xbd\x48\x23\xde\xaf\xff\x94\x81\xb8\xe8\x2b\xb0\xa2\xca\x7c\x67\x0a\xbc\x52\xf5\xd9\xb0\x7c\x5c\xf5\xfa\x38\xa0\xe5\x61\xc6\xb3\xd1\x23\xe7\xc6\xbb\xf6\xa3\xa6\x6e\xc2\x48\x54\xa3\x00\xcf\xe4\x7a\xc1\x5b\xa8\x37\xf5\x67\xda\x41\x63\xfb\xc1\xd9\x5e\x7b\xb6\x0b\x6f\xdd\xfc\xbb\x82\x5f\xaf\xde\x63\xce\x89\xf2\xfe\x87\x7a\x5f\xbc\xe9\xdd\xdc\xa0\xb8\xeb\x0f\x2c\xe8\xc1\xe9\xcd\xe8\xf2\x35\xf7\x40\x7b\x50\x63\x13\xa9\xf3\xb4\x55\xe4\xa8\x84\x07\xc5\x9b\xd9\x65\x3e\x1b\xe0\x54\xf8\x2b\xa6\x88\xf0\xe1\x7e\x99\x3e\x12\x08\x86\xb6\x09\xa8\x8f\xcc\x88\x6f\x1b\x58\x11\xc4\xa1\xb3\x28\x49\xf2\x3b\xdf\x1a\x3d\xb2\x37\x47\x9f\x8c\x84\xe9\x92\xf6\x3e\x92\x2c\x27\x13\x1e\x07\xc6\x6c\xae\x14\xf0\xa9\x4d\x9c\x4e\x6b\xe8\x71\x07\x0a\x37\xc4\xa0\x9c\x9e\x7e\x0d\xd6\x93\xbc\xdd\x97\x30\xad\x20\xe5\xbd\xa0\xb4\xd5\x41\x1d\x8b\xc9\xf6\xce\x93\x06\x1b\x03\x8f\xc5\x5f\x78\x7a\x96\xf5\xd4\x1e\xaf\xd2\x79\xea\xe4\xfa\x4c\x90\xb3\xd2\xbf\x2a\xfb\x4b\xc9\x73\xd6\x6f\x20\x10\x94\xa3\x96\x25\x5b\xd4\xef\xb1\x59\x8b\x0a\x6b\xf8\x7e\x00\x15\x55\xfe\x13\xb0\xf4\xd3\xdf\xe5\x37\xd9\x81\x3e\x02\x65\x06\x00\x00")
+
+func dataRevokeHtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_dataRevokeHtml,
+		"data/revoke.html",
+	)
+}
+
+func dataRevokeHtml() (*asset, error) {
+	bytes, err := dataRevokeHtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "data/revoke.html", size: 1637, mode: os.FileMode(420), modTime: time.Unix(1792107144, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
@@ -130,6 +278,26 @@ func dataSourceHtml() (*asset, error) {
 	return a, nil
 }
 
+var _dataTokensHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x9d\x56\xdd\x4f\xdb\x30\x10\x7f\xdf\x5f\x71\xb2\x26\x3e\x24\x48\x52\x0a\x03\xb5\x69\x26\xca\x84\xc4\xc3\x36\xc4\x78\x99\xa6\x3d\xb8\xf1\x35\xb1\x48\x9c\xc8\x71\xd6\x15\xc4\xff\xbe\x73\x52\x5a\x92\xa6\xa5\xac\x0f\x69\xec\x3b\xdf\xd7\xef\x77\xe7\xf8\xb1\x49\x93\xe0\x03\xd0\xcf\x8f\x91\x8b\xfa\xb5\x5a\x1a\x69\x12\x0c\x2e\x6f\x6f\xc0\x64\x0f\xa8\x0a\xdf\xad\x77\x6a\x65\x77\xa5\xed\x4f\x32\x31\x7f\x75\x30\xee\x37\x4e\xd1\x72\x29\x7b\x7a\x02\xcd\x55\x84\xe0\xa4\x58\x14\x3c\xc2\x02\x9e\x9f\x97\xd2\xea\x74\x0e\x85\x99\x27\x38\x62\x39\x17\x42\xaa\x68\xd0\xc3\x74\x08\x93\x4c\x0b\xd4\x8b\x77\x1e\x3e\x44\x3a\x2b\x95\x18\x80\x8e\x26\x07\x27\xfd\x8b\x23\xe8\x5d\xf4\xe9\x71\x7e\x7e\x38\x64\x01\x79\x71\xc8\xae\xef\xe6\x0d\xcf\xa8\xc4\x6b\x6f\xb4\x23\xa7\xe0\x28\x9c\xdd\xdb\x48\xd7\x03\x09\x7e\x66\xa5\x06\x92\xd7\xa9\x80\x2c\x60\x82\x49\x36\x73\xe0\x2a\xcb\xe7\x20\x0d\xa8\x6c\x76\x04\xbc\xb0\xaf\x21\x57\xfb\x86\xe4\x50\xc4\xd9\x4c\x01\x8f\xb8\x54\x83\x46\x04\xb5\x51\x8d\xdd\xf9\xb5\x73\xf2\xbc\x23\x38\xe9\xdb\x87\xe7\xbd\xe4\xf4\x2a\x54\xb2\xac\x71\x5b\x76\x14\x7e\xa5\x5b\xd8\xc8\x6c\x5c\x65\x81\x82\x12\xa1\x65\x92\x80\x89\x11\x2c\x46\x33\x69\x62\x20\xb9\x1f\x66\x82\xb0\x2e\x4d\x9c\x69\xf9\xc8\x8d\xcc\xd4\x00\xc6\xc8\x35\x6a\xd8\x4b\xcc\xb0\x2a\xc0\x5e\x64\x86\xbe\x5b\x69\x82\x45\x1f\xb5\xd3\x48\xd0\x37\x7c\x92\xe0\x02\xaa\x11\xab\xff\x59\xab\x00\x46\x37\x37\xea\xcd\x38\xf8\xc6\x53\x24\x86\xc5\xdd\xd2\x2b\x8d\xdc\xa0\x78\x53\x01\xc6\xf3\xcd\x3a\x77\x59\xb2\xc5\xc5\x65\x68\xb3\x2e\xd6\x15\x68\xa7\x15\x33\x55\xfb\x63\x58\xe8\xe9\xb5\xc4\x44\xc0\x60\x04\xce\x6a\xd5\x62\xd1\x8a\xf0\x75\x3b\xb4\xe5\x1b\x6b\x52\x0b\x44\x85\xbb\xad\x4d\x85\xb9\x11\xdb\x15\x17\x55\x70\xae\x33\x9d\x72\x03\x8c\xb8\xf3\xe9\xd8\xeb\x1d\x7b\x27\xd0\x3b\x1b\x78\xa7\x03\xef\x0c\xbe\xfe\xb8\x67\xef\x31\x36\x9e\xef\xa4\x6d\x8b\xfb\xa6\x62\xa7\xa0\x12\x4e\x29\x62\x48\x91\xf8\x27\x46\xec\xf6\xbb\x8d\x91\x57\x80\x8c\x98\x5b\x57\x8e\x01\xa1\x53\x4e\x52\x69\x46\x4c\xa3\x29\xb5\x82\x30\x53\x53\xa9\xd3\x83\xfd\x3b\xfc\x43\x3a\xc4\x6a\x6a\xd1\x4a\xfb\xf3\xbe\xed\x99\x8d\xee\x2a\x97\x52\xe5\xa5\x01\x33\xcf\xa9\x17\x63\x29\x04\x2a\x06\x8a\x2a\x3d\x62\xb5\x67\x06\x7f\x78\x52\xa2\xf5\x66\xad\x33\x70\xff\xdb\xa0\x14\x4b\x63\xb6\x56\x37\x5f\xa8\x52\xef\xb3\x57\x67\xbe\xb4\x72\xb7\x5b\x48\x4d\xa2\x76\x50\x6f\xc5\x71\x0b\xc0\x06\xe0\x3a\x21\xed\xec\x8a\xf6\x0c\x72\xab\x89\xd0\xbc\x1a\x6a\x5a\xd5\x30\x35\x2f\x87\x5d\x48\xd0\x6a\xcd\x9d\x31\x0c\x2b\xaf\x6b\x05\xa3\x29\x59\x35\xd7\x01\x3a\x91\x53\x4d\xc5\x5c\xe6\x98\x48\x65\xc9\x44\x1d\x34\x93\x34\x2c\x69\x72\xd2\x84\x3f\xec\x9a\xe6\x41\x23\x02\x83\x7f\xcd\x8b\x7f\xfb\x64\x50\xc8\x47\x7a\x3f\xf5\xac\xe3\xae\xe3\xb6\x6b\x3a\xed\xae\xd7\xbb\xc0\x04\x43\xb3\xb0\xae\xe9\xdc\x06\x7e\xaf\x26\x8e\x55\x2a\xb6\xa2\x9e\xe5\xb6\x48\xab\xfb\x72\xb1\xde\x64\xb7\x05\xef\x0a\xe6\x3a\xb6\xf6\xd8\x7c\xa3\x5a\x2d\x4a\x5f\x2d\x11\x5a\x3b\x69\xe3\xeb\xa4\x71\x9b\xb6\xe4\xe2\x17\xf8\x1c\x62\x8d\x53\xe2\x0c\x0b\xc6\x74\xad\xfa\x2e\x0f\xe0\xf7\xd2\xa8\xef\xd6\x9f\x2b\xc4\xbd\xea\xd3\xe7\x1f\x8c\x43\xab\x16\x02\x09\x00\x00")
+
+func dataTokensHtmlBytes() ([]byte, error) {
+	return bindataRead(
+		_dataTokensHtml,
+		"data/tokens.html",
+	)
+}
+
+func dataTokensHtml() (*asset, error) {
+	bytes, err := dataTokensHtmlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "data/tokens.html", size: 2306, mode: os.FileMode(420), modTime: time.Unix(1792102694, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 // Asset loads and returns the asset for the given name.
 // It returns an error if the asset could not be found or
 // could not be loaded.
@@ -183,8 +351,16 @@ func AssetNames() []string {
 // _bindata is a table, holding each asset generator, mapped to its name.
 var _bindata = map[string]func() (*asset, error){
 	"data/add.html": dataAddHtml,
+	"data/audit.html": dataAuditHtml,
+	"data/history.html": dataHistoryHtml,
+	"data/import.html": dataImportHtml,
 	"data/index.html": dataIndexHtml,
+	"data/jobs.html": dataJobsHtml,
+	"data/names.html": dataNamesHtml,
+	"data/openapi.json": dataOpenapiJson,
+	"data/revoke.html": dataRevokeHtml,
 	"data/source.html": dataSourceHtml,
+	"data/tokens.html": dataTokensHtml,
 }
 
 // AssetDir returns the file names below a certain
@@ -229,8 +405,16 @@ type bintree struct {
 var _bintree = &bintree{nil, map[string]*bintree{
 	"data": &bintree{nil, map[string]*bintree{
 		"add.html": &bintree{dataAddHtml, map[string]*bintree{}},
+		"audit.html": &bintree{dataAuditHtml, map[string]*bintree{}},
+		"history.html": &bintree{dataHistoryHtml, map[string]*bintree{}},
+		"import.html": &bintree{dataImportHtml, map[string]*bintree{}},
 		"index.html": &bintree{dataIndexHtml, map[string]*bintree{}},
+		"jobs.html": &bintree{dataJobsHtml, map[string]*bintree{}},
+		"names.html": &bintree{dataNamesHtml, map[string]*bintree{}},
+		"openapi.json": &bintree{dataOpenapiJson, map[string]*bintree{}},
+		"revoke.html": &bintree{dataRevokeHtml, map[string]*bintree{}},
 		"source.html": &bintree{dataSourceHtml, map[string]*bintree{}},
+		"tokens.html": &bintree{dataTokensHtml, map[string]*bintree{}},
 	}},
 }}
 